@@ -0,0 +1,90 @@
+// backfill 分页下载历史K线写入本地SQLite缓存，供策略预热和回测使用
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"okxauto/internal/api"
+	"okxauto/internal/api/config"
+	"okxauto/internal/candles"
+	"okxauto/internal/database"
+)
+
+func main() {
+	configFile := flag.String("config", "config/config.yaml", "配置文件路径")
+	dbPath := flag.String("db", "data/trades.db", "数据库路径")
+	symbols := flag.String("symbols", "", "交易产品，多个用逗号分隔，为空时使用配置中的交易对")
+	bar := flag.String("bar", "1m", "K线周期，如 1m/5m/1H/1D")
+	from := flag.String("from", "", "开始日期，格式 2006-01-02 (UTC)")
+	to := flag.String("to", "", "结束日期，格式 2006-01-02 (UTC)，为空时为当前时间")
+	flag.Parse()
+
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
+	}
+
+	start, err := time.Parse("2006-01-02", *from)
+	if err != nil {
+		log.Fatalf("开始日期格式错误: %v", err)
+	}
+	end := time.Now()
+	if *to != "" {
+		if end, err = time.Parse("2006-01-02", *to); err != nil {
+			log.Fatalf("结束日期格式错误: %v", err)
+		}
+	}
+
+	db, err := database.New(*dbPath)
+	if err != nil {
+		log.Fatalf("初始化数据库失败: %v", err)
+	}
+	defer db.Close()
+	if err := db.Initialize(); err != nil {
+		log.Fatalf("初始化数据库表结构失败: %v", err)
+	}
+
+	apiClient := api.NewOKXClient(cfg.API.Key, cfg.API.Secret, cfg.API.Passphrase, cfg.API.Mode)
+	if cfg.API.BaseURL != "" {
+		apiClient.SetBaseURL(cfg.API.BaseURL)
+	}
+	proxy, err := api.ParseProxy(cfg.API.Proxy)
+	if err != nil {
+		log.Fatalf("代理配置错误: %v", err)
+	}
+	apiClient.SetProxy(proxy)
+
+	// 默认下载所有账户的交易对
+	var list []string
+	seen := make(map[string]bool)
+	for _, account := range cfg.Accounts {
+		for _, symbol := range account.Trading.Symbols {
+			if !seen[symbol] {
+				seen[symbol] = true
+				list = append(list, symbol)
+			}
+		}
+	}
+	if *symbols != "" {
+		list = strings.Split(*symbols, ",")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	service := candles.NewService(apiClient, db)
+	for _, symbol := range list {
+		symbol = strings.TrimSpace(symbol)
+		n, err := service.Backfill(ctx, symbol, *bar, start, end)
+		if err != nil {
+			log.Fatalf("[%s] 补齐K线失败(已写入 %d 根): %v", symbol, n, err)
+		}
+		log.Printf("[%s] %s K线补齐完成，新写入 %d 根", symbol, *bar, n)
+	}
+}
@@ -0,0 +1,65 @@
+// okxsim 在本地端口运行OKX模拟服务器，将交易程序的 api.base_url 指向该地址即可离线运行
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"okxauto/internal/okxsim"
+)
+
+func main() {
+	addr := flag.String("addr", ":8090", "监听地址")
+	balance := flag.Float64("balance", 10000, "初始USDT余额")
+	funding := flag.Float64("funding", 0, "资金账户初始USDT余额")
+	instruments := flag.String("inst", "IP-USDT-SWAP:1.3:1", "交易产品列表，格式: instId:价格:合约面值，多个用逗号分隔")
+	walk := flag.Duration("walk", time.Second, "价格随机波动间隔，0表示不波动")
+	volatility := flag.Float64("vol", 0.001, "单步最大涨跌幅")
+	key := flag.String("key", "", "API Key，为空时不校验签名")
+	secret := flag.String("secret", "", "API Secret")
+	passphrase := flag.String("passphrase", "", "API Passphrase")
+	skew := flag.Duration("skew", 0, "服务器时间相对本地时间的偏差，用于测试客户端校时")
+	posMode := flag.String("posmode", "long_short_mode", "账户持仓模式: long_short_mode 或 net_mode")
+	flag.Parse()
+
+	sim := okxsim.New(*balance)
+	sim.SetClockSkew(*skew)
+	sim.SetPositionMode(*posMode)
+	sim.SetFundingBalance("USDT", *funding)
+	if *secret != "" {
+		sim.SetCredentials(*key, *secret, *passphrase)
+	}
+
+	for _, item := range strings.Split(*instruments, ",") {
+		parts := strings.Split(strings.TrimSpace(item), ":")
+		if len(parts) < 2 {
+			log.Fatalf("交易产品格式错误: %s", item)
+		}
+		price, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			log.Fatalf("价格格式错误: %s", item)
+		}
+		ctVal := 1.0
+		if len(parts) > 2 {
+			if ctVal, err = strconv.ParseFloat(parts[2], 64); err != nil {
+				log.Fatalf("合约面值格式错误: %s", item)
+			}
+		}
+		sim.AddInstrument(parts[0], price, ctVal)
+		log.Printf("添加交易产品 %s, 价格: %.4f, 合约面值: %g", parts[0], price, ctVal)
+	}
+
+	if *walk > 0 {
+		go sim.RunRandomWalk(*walk, *volatility)
+	}
+	defer sim.Close()
+
+	log.Printf("OKX模拟服务器已启动: http://%s", *addr)
+	if err := http.ListenAndServe(*addr, sim.Handler()); err != nil {
+		log.Fatalf("模拟服务器停止: %v", err)
+	}
+}
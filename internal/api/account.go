@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// 账户持仓模式
+const (
+	PosModeNet       = "net_mode"        // 买卖模式，每个合约只有一个带符号的net持仓
+	PosModeLongShort = "long_short_mode" // 开平仓模式，多空持仓分开，下单需指定posSide
+)
+
+// AccountConfig 账户配置
+type AccountConfig struct {
+	Uid      string
+	AcctLv   string // 账户模式: 1简单 2单币种保证金 3跨币种保证金 4组合保证金
+	PosMode  string // 持仓模式: net_mode/long_short_mode
+	AutoLoan bool
+	Level    string // 手续费等级
+	Label    string // API Key备注
+	Perm     string // API Key权限
+}
+
+// GetAccountConfig 获取账户配置，包括持仓模式
+func (c *OKXClient) GetAccountConfig(ctx context.Context) (*AccountConfig, error) {
+	resp, err := c.sendRequest(ctx, "GET", "/api/v5/account/config", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			Uid      string `json:"uid"`
+			AcctLv   string `json:"acctLv"`
+			PosMode  string `json:"posMode"`
+			AutoLoan bool   `json:"autoLoan"`
+			Level    string `json:"level"`
+			Label    string `json:"label"`
+			Perm     string `json:"perm"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析账户配置失败: %v", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("未获取到账户配置")
+	}
+
+	d := result.Data[0]
+	return &AccountConfig{
+		Uid:      d.Uid,
+		AcctLv:   d.AcctLv,
+		PosMode:  d.PosMode,
+		AutoLoan: d.AutoLoan,
+		Level:    d.Level,
+		Label:    d.Label,
+		Perm:     d.Perm,
+	}, nil
+}
+
+// SetPositionMode 设置持仓模式，账户有持仓或挂单时交易所会拒绝切换
+func (c *OKXClient) SetPositionMode(ctx context.Context, posMode string) error {
+	if posMode != PosModeNet && posMode != PosModeLongShort {
+		return fmt.Errorf("无效的持仓模式: %s", posMode)
+	}
+
+	req := map[string]string{"posMode": posMode}
+	if _, err := c.sendRequest(ctx, "POST", "/api/v5/account/set-position-mode", req); err != nil {
+		return fmt.Errorf("设置持仓模式失败: %w", err)
+	}
+	return nil
+}
+
+// AccountSummary 账户层面的权益和保证金信息，金额均以美元计
+type AccountSummary struct {
+	TotalEq     float64            `json:"totalEq"`     // 美元层面总权益
+	AdjEq       float64            `json:"adjEq"`       // 有效保证金(跨币种/组合保证金模式)
+	IsoEq       float64            `json:"isoEq"`       // 逐仓仓位权益
+	Imr         float64            `json:"imr"`         // 占用的初始保证金
+	Mmr         float64            `json:"mmr"`         // 维持保证金
+	MgnRatio    float64            `json:"mgnRatio"`    // 保证金率，无持仓时为0
+	NotionalUsd float64            `json:"notionalUsd"` // 持仓美元价值
+	OrdFroz     float64            `json:"ordFroz"`     // 挂单冻结保证金
+	UpdateTime  time.Time          `json:"updateTime"`
+	Details     []*CurrencySummary `json:"details"`
+}
+
+// CurrencySummary 单个币种的权益和保证金信息
+type CurrencySummary struct {
+	Ccy           string  `json:"ccy"`
+	Eq            float64 `json:"eq"`            // 币种总权益
+	EqUsd         float64 `json:"eqUsd"`         // 币种权益美元价值
+	CashBal       float64 `json:"cashBal"`       // 现金余额
+	AvailBal      float64 `json:"availBal"`      // 可用余额
+	AvailEq       float64 `json:"availEq"`       // 可用保证金
+	FrozenBal     float64 `json:"frozenBal"`     // 冻结余额
+	OrdFrozen     float64 `json:"ordFrozen"`     // 挂单冻结数量
+	Upl           float64 `json:"upl"`           // 未实现盈亏
+	Liab          float64 `json:"liab"`          // 负债
+	Imr           float64 `json:"imr"`           // 币种维度初始保证金
+	Mmr           float64 `json:"mmr"`           // 币种维度维持保证金
+	MgnRatio      float64 `json:"mgnRatio"`      // 币种维度保证金率，无持仓时为0
+	NotionalLever float64 `json:"notionalLever"` // 币种维度杠杆倍数
+	OpenAvgPx     float64 `json:"openAvgPx"`     // 现货开仓均价
+	SpotUpl       float64 `json:"spotUpl"`       // 现货未实现盈亏(计价货币)
+	SpotUplRatio  float64 `json:"spotUplRatio"`  // 现货未实现收益率
+}
+
+// Currency 返回指定币种的信息，不存在时返回nil
+func (s *AccountSummary) Currency(ccy string) *CurrencySummary {
+	for _, d := range s.Details {
+		if d.Ccy == ccy {
+			return d
+		}
+	}
+	return nil
+}
+
+// Balances 返回权益大于0的币种余额
+func (s *AccountSummary) Balances() []*Balance {
+	balances := make([]*Balance, 0, len(s.Details))
+	for _, d := range s.Details {
+		if d.Eq > 0 {
+			balances = append(balances, d.Balance())
+		}
+	}
+	return balances
+}
+
+// Balance 转换为Balance，总额使用币种权益，可用使用可用保证金
+func (d *CurrencySummary) Balance() *Balance {
+	return &Balance{
+		Currency:  d.Ccy,
+		Balance:   strconv.FormatFloat(d.Eq, 'f', -1, 64),
+		Available: strconv.FormatFloat(d.AvailEq, 'f', -1, 64),
+		Frozen:    strconv.FormatFloat(d.FrozenBal, 'f', -1, 64),
+	}
+}
+
+// accountData /api/v5/account/balance 和 account 频道的原始数据
+type accountData struct {
+	TotalEq     string `json:"totalEq"`
+	AdjEq       string `json:"adjEq"`
+	IsoEq       string `json:"isoEq"`
+	Imr         string `json:"imr"`
+	Mmr         string `json:"mmr"`
+	MgnRatio    string `json:"mgnRatio"`
+	NotionalUsd string `json:"notionalUsd"`
+	OrdFroz     string `json:"ordFroz"`
+	UTime       string `json:"uTime"`
+	Details     []struct {
+		Ccy           string `json:"ccy"`
+		Eq            string `json:"eq"`
+		EqUsd         string `json:"eqUsd"`
+		CashBal       string `json:"cashBal"`
+		AvailBal      string `json:"availBal"`
+		AvailEq       string `json:"availEq"`
+		FrozenBal     string `json:"frozenBal"`
+		OrdFrozen     string `json:"ordFrozen"`
+		Upl           string `json:"upl"`
+		Liab          string `json:"liab"`
+		Imr           string `json:"imr"`
+		Mmr           string `json:"mmr"`
+		MgnRatio      string `json:"mgnRatio"`
+		NotionalLever string `json:"notionalLever"`
+		OpenAvgPx     string `json:"openAvgPx"`
+		SpotUpl       string `json:"spotUpl"`
+		SpotUplRatio  string `json:"spotUplRatio"`
+	} `json:"details"`
+}
+
+// summary 解析为AccountSummary，交易所返回空字符串的字段为0
+func (d *accountData) summary() *AccountSummary {
+	s := &AccountSummary{
+		TotalEq:     parseFloat(d.TotalEq),
+		AdjEq:       parseFloat(d.AdjEq),
+		IsoEq:       parseFloat(d.IsoEq),
+		Imr:         parseFloat(d.Imr),
+		Mmr:         parseFloat(d.Mmr),
+		MgnRatio:    parseFloat(d.MgnRatio),
+		NotionalUsd: parseFloat(d.NotionalUsd),
+		OrdFroz:     parseFloat(d.OrdFroz),
+		UpdateTime:  parseMillis(d.UTime),
+		Details:     make([]*CurrencySummary, 0, len(d.Details)),
+	}
+	for _, detail := range d.Details {
+		s.Details = append(s.Details, &CurrencySummary{
+			Ccy:           detail.Ccy,
+			Eq:            parseFloat(detail.Eq),
+			EqUsd:         parseFloat(detail.EqUsd),
+			CashBal:       parseFloat(detail.CashBal),
+			AvailBal:      parseFloat(detail.AvailBal),
+			AvailEq:       parseFloat(detail.AvailEq),
+			FrozenBal:     parseFloat(detail.FrozenBal),
+			OrdFrozen:     parseFloat(detail.OrdFrozen),
+			Upl:           parseFloat(detail.Upl),
+			Liab:          parseFloat(detail.Liab),
+			Imr:           parseFloat(detail.Imr),
+			Mmr:           parseFloat(detail.Mmr),
+			MgnRatio:      parseFloat(detail.MgnRatio),
+			NotionalLever: parseFloat(detail.NotionalLever),
+			OpenAvgPx:     parseFloat(detail.OpenAvgPx),
+			SpotUpl:       parseFloat(detail.SpotUpl),
+			SpotUplRatio:  parseFloat(detail.SpotUplRatio),
+		})
+	}
+	return s
+}
+
+// GetAccountSummary 获取账户权益、保证金和各币种余额
+func (c *OKXClient) GetAccountSummary(ctx context.Context) (*AccountSummary, error) {
+	resp, err := c.sendRequest(ctx, "GET", "/api/v5/account/balance", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []accountData `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析余额响应失败: %v, 响应内容: %s", err, string(resp))
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("账户余额为空")
+	}
+	return result.Data[0].summary(), nil
+}
+
+// parseFloat 解析交易所返回的数值字符串，为空或格式错误时返回0
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
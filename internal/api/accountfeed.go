@@ -0,0 +1,328 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"okxauto/internal/models"
+)
+
+// AccountFeed 私有频道推送，登录后订阅订单、持仓和账户频道，并维护持仓与余额缓存
+type AccountFeed struct {
+	ws *WSClient
+
+	mu             sync.RWMutex
+	positions      map[string]*models.Position // key: instId|posSide
+	summary        *AccountSummary             // 账户层面数据，Details另存于currencies
+	currencies     map[string]*CurrencySummary // key: ccy
+	positionsReady bool
+	balancesReady  bool
+
+	subMu       sync.RWMutex
+	orderSubs   []chan *Order
+	positionSub map[string][]chan *models.Position
+}
+
+// NewAccountFeed 创建私有频道推送，使用client的API密钥登录
+func NewAccountFeed(url string, client *OKXClient) *AccountFeed {
+	f := &AccountFeed{
+		positions:   make(map[string]*models.Position),
+		currencies:  make(map[string]*CurrencySummary),
+		positionSub: make(map[string][]chan *models.Position),
+	}
+	f.ws = NewWSClient(url, f.handleMessage)
+	f.ws.onConnect = client.wsLogin
+	f.ws.onDisconnect = f.reset
+	return f
+}
+
+// Start 启动连接并订阅私有频道
+func (f *AccountFeed) Start() error {
+	if err := f.ws.Subscribe(
+		WSArg{Channel: "orders", InstType: "ANY"},
+		WSArg{Channel: "positions", InstType: "ANY"},
+		WSArg{Channel: "account"},
+		WSArg{Channel: "balance_and_position"},
+	); err != nil {
+		return err
+	}
+	f.ws.Start()
+	return nil
+}
+
+// Stop 关闭连接
+func (f *AccountFeed) Stop() {
+	f.ws.Stop()
+}
+
+// Positions 返回缓存的持仓，ok为false表示尚未收到持仓快照，调用方应改用REST查询
+func (f *AccountFeed) Positions(instId string) (positions []*models.Position, ok bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if !f.positionsReady {
+		return nil, false
+	}
+
+	positions = make([]*models.Position, 0)
+	for _, pos := range f.positions {
+		if instId == "" || pos.Symbol == instId {
+			p := *pos
+			positions = append(positions, &p)
+		}
+	}
+	return positions, true
+}
+
+// Balances 返回缓存的余额，ok为false表示尚未收到账户快照
+func (f *AccountFeed) Balances() (balances []*Balance, ok bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if !f.balancesReady {
+		return nil, false
+	}
+
+	balances = make([]*Balance, 0, len(f.currencies))
+	for _, detail := range f.currencies {
+		if detail.Eq > 0 {
+			balances = append(balances, detail.Balance())
+		}
+	}
+	return balances, true
+}
+
+// Summary 返回缓存的账户权益和保证金信息，ok为false表示尚未收到账户快照
+func (f *AccountFeed) Summary() (summary *AccountSummary, ok bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if !f.balancesReady || f.summary == nil {
+		return nil, false
+	}
+
+	s := *f.summary
+	s.Details = make([]*CurrencySummary, 0, len(f.currencies))
+	for _, detail := range f.currencies {
+		d := *detail
+		s.Details = append(s.Details, &d)
+	}
+	return &s, true
+}
+
+// Orders 返回订单推送通道
+func (f *AccountFeed) Orders() <-chan *Order {
+	ch := make(chan *Order, 256)
+	f.subMu.Lock()
+	f.orderSubs = append(f.orderSubs, ch)
+	f.subMu.Unlock()
+	return ch
+}
+
+// PositionUpdates 返回指定交易对的持仓变动通道
+func (f *AccountFeed) PositionUpdates(instId string) <-chan *models.Position {
+	ch := make(chan *models.Position, 64)
+	f.subMu.Lock()
+	f.positionSub[instId] = append(f.positionSub[instId], ch)
+	f.subMu.Unlock()
+	return ch
+}
+
+// reset 连接断开后清空缓存，避免使用过期数据
+func (f *AccountFeed) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.positions = make(map[string]*models.Position)
+	f.summary = nil
+	f.currencies = make(map[string]*CurrencySummary)
+	f.positionsReady = false
+	f.balancesReady = false
+}
+
+func (f *AccountFeed) handleMessage(msg *WSMessage) {
+	switch msg.Arg.Channel {
+	case "orders":
+		var orders []*Order
+		if err := json.Unmarshal(msg.Data, &orders); err != nil {
+			log.Printf("[WS] 解析订单推送失败: %v", err)
+			return
+		}
+		f.subMu.RLock()
+		for _, order := range orders {
+			log.Printf("[%s] 订单推送 - OrderID: %s, 状态: %s, 成交: %s@%s",
+				order.InstId, order.OrdId, order.State, order.AccFillSz, order.AvgPx)
+			for _, ch := range f.orderSubs {
+				select {
+				case ch <- order:
+				default:
+					log.Printf("[%s] 订单推送通道已满，丢弃订单 %s", order.InstId, order.OrdId)
+				}
+			}
+		}
+		f.subMu.RUnlock()
+
+	case "positions":
+		var data []struct {
+			InstId   string `json:"instId"`
+			PosSide  string `json:"posSide"`
+			Pos      string `json:"pos"`
+			AvgPx    string `json:"avgPx"`
+			UPL      string `json:"upl"`
+			UplRatio string `json:"uplRatio"`
+			MgnRatio string `json:"mgnRatio"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			log.Printf("[WS] 解析持仓推送失败: %v", err)
+			return
+		}
+
+		updated := make([]*models.Position, 0, len(data))
+		f.mu.Lock()
+		for _, d := range data {
+			pos := &models.Position{Symbol: d.InstId, PosSide: d.PosSide}
+			pos.Position, _ = strconv.ParseFloat(d.Pos, 64)
+			pos.AvgPrice, _ = strconv.ParseFloat(d.AvgPx, 64)
+			pos.UnrealPnL, _ = strconv.ParseFloat(d.UPL, 64)
+			pos.PnLRatio, _ = strconv.ParseFloat(d.UplRatio, 64)
+			pos.MarginRatio, _ = strconv.ParseFloat(d.MgnRatio, 64)
+
+			key := d.InstId + "|" + d.PosSide
+			if pos.Position == 0 {
+				delete(f.positions, key)
+			} else {
+				f.positions[key] = pos
+			}
+			p := *pos
+			updated = append(updated, &p)
+		}
+		f.positionsReady = true
+		f.mu.Unlock()
+
+		f.notifyPositions(updated)
+
+	case "balance_and_position":
+		// 该频道推送最快但字段不全，只用于及时更新持仓数量和移除已平仓位
+		var data []struct {
+			PosData []struct {
+				InstId  string `json:"instId"`
+				PosSide string `json:"posSide"`
+				Pos     string `json:"pos"`
+				AvgPx   string `json:"avgPx"`
+			} `json:"posData"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			log.Printf("[WS] 解析余额持仓推送失败: %v", err)
+			return
+		}
+
+		updated := make([]*models.Position, 0)
+		f.mu.Lock()
+		for _, d := range data {
+			for _, pd := range d.PosData {
+				key := pd.InstId + "|" + pd.PosSide
+				size, _ := strconv.ParseFloat(pd.Pos, 64)
+				if size == 0 {
+					delete(f.positions, key)
+					updated = append(updated, &models.Position{Symbol: pd.InstId, PosSide: pd.PosSide})
+					continue
+				}
+				if pos, ok := f.positions[key]; ok {
+					pos.Position = size
+					pos.AvgPrice, _ = strconv.ParseFloat(pd.AvgPx, 64)
+					p := *pos
+					updated = append(updated, &p)
+				}
+			}
+		}
+		f.mu.Unlock()
+
+		f.notifyPositions(updated)
+
+	case "account":
+		var data []accountData
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			log.Printf("[WS] 解析账户推送失败: %v", err)
+			return
+		}
+
+		// 推送只包含有变动的币种，按币种合并到缓存
+		f.mu.Lock()
+		for _, d := range data {
+			summary := d.summary()
+			for _, detail := range summary.Details {
+				if detail.Eq <= 0 {
+					delete(f.currencies, detail.Ccy)
+					continue
+				}
+				f.currencies[detail.Ccy] = detail
+			}
+			summary.Details = nil
+			f.summary = summary
+		}
+		f.balancesReady = true
+		f.mu.Unlock()
+	}
+}
+
+func (f *AccountFeed) notifyPositions(positions []*models.Position) {
+	f.subMu.RLock()
+	defer f.subMu.RUnlock()
+
+	for _, pos := range positions {
+		for _, ch := range f.positionSub[pos.Symbol] {
+			select {
+			case ch <- pos:
+			default:
+			}
+		}
+	}
+}
+
+// wsLogin 私有频道登录，签名路径固定为 GET /users/self/verify
+func (c *OKXClient) wsLogin(conn *websocket.Conn) error {
+	timestamp := strconv.FormatInt(c.clock.now().Unix(), 10)
+	login := wsRequest{
+		Op: "login",
+		Args: []interface{}{map[string]string{
+			"apiKey":     c.apiKey,
+			"passphrase": c.passphrase,
+			"timestamp":  timestamp,
+			"sign":       c.sign(timestamp, "GET", "/users/self/verify", nil),
+		}},
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := conn.WriteJSON(login); err != nil {
+		return fmt.Errorf("发送登录请求失败: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("等待登录响应失败: %v", err)
+		}
+
+		var msg WSMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		switch msg.Event {
+		case "login":
+			if msg.Code != "" && msg.Code != "0" {
+				return fmt.Errorf("登录失败: %s (错误码: %s)", msg.Msg, msg.Code)
+			}
+			log.Printf("[WS] 私有频道登录成功")
+			return nil
+		case "error":
+			return fmt.Errorf("登录失败: %s (错误码: %s)", msg.Msg, msg.Code)
+		}
+	}
+}
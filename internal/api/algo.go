@@ -0,0 +1,203 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// 策略委托类型
+const (
+	AlgoConditional   = "conditional"     // 单向止盈止损
+	AlgoOCO           = "oco"             // 双向止盈止损
+	AlgoTrigger       = "trigger"         // 计划委托
+	AlgoMoveOrderStop = "move_order_stop" // 移动止盈止损
+)
+
+// AlgoOrderRequest 策略委托下单请求，OrdPx为-1时触发后以市价下单
+type AlgoOrderRequest struct {
+	InstId      string    `json:"instId"`
+	TdMode      string    `json:"tdMode"`
+	Side        OrderSide `json:"side"`
+	PosSide     string    `json:"posSide,omitempty"`
+	OrdType     string    `json:"ordType"`
+	Sz          string    `json:"sz,omitempty"`
+	ReduceOnly  bool      `json:"reduceOnly,omitempty"`
+	AlgoClOrdId string    `json:"algoClOrdId,omitempty"`
+
+	// 止盈止损(conditional/oco)
+	TpTriggerPx     string `json:"tpTriggerPx,omitempty"`
+	TpTriggerPxType string `json:"tpTriggerPxType,omitempty"` // last/index/mark
+	TpOrdPx         string `json:"tpOrdPx,omitempty"`
+	SlTriggerPx     string `json:"slTriggerPx,omitempty"`
+	SlTriggerPxType string `json:"slTriggerPxType,omitempty"`
+	SlOrdPx         string `json:"slOrdPx,omitempty"`
+
+	// 计划委托(trigger)
+	TriggerPx     string `json:"triggerPx,omitempty"`
+	TriggerPxType string `json:"triggerPxType,omitempty"`
+	OrderPx       string `json:"orderPx,omitempty"`
+
+	// 移动止盈止损(move_order_stop)，回调幅度比例和价距二选一
+	CallbackRatio  string `json:"callbackRatio,omitempty"`
+	CallbackSpread string `json:"callbackSpread,omitempty"`
+	ActivePx       string `json:"activePx,omitempty"`
+}
+
+// AlgoOrderResponse 策略委托下单响应
+type AlgoOrderResponse struct {
+	AlgoId      string `json:"algoId"`
+	AlgoClOrdId string `json:"algoClOrdId"`
+	SCode       string `json:"sCode"`
+	SMsg        string `json:"sMsg"`
+}
+
+// AmendAlgoRequest 修改策略委托请求，只支持未触发的止盈止损和计划委托
+type AmendAlgoRequest struct {
+	InstId         string `json:"instId"`
+	AlgoId         string `json:"algoId"`
+	NewSz          string `json:"newSz,omitempty"`
+	NewTpTriggerPx string `json:"newTpTriggerPx,omitempty"`
+	NewTpOrdPx     string `json:"newTpOrdPx,omitempty"`
+	NewSlTriggerPx string `json:"newSlTriggerPx,omitempty"`
+	NewSlOrdPx     string `json:"newSlOrdPx,omitempty"`
+	NewTriggerPx   string `json:"newTriggerPx,omitempty"`
+	NewOrdPx       string `json:"newOrdPx,omitempty"`
+}
+
+// AlgoOrder 策略委托详情
+type AlgoOrder struct {
+	InstId         string `json:"instId"`
+	InstType       string `json:"instType"`
+	AlgoId         string `json:"algoId"`
+	AlgoClOrdId    string `json:"algoClOrdId"`
+	OrdType        string `json:"ordType"`
+	Side           string `json:"side"`
+	PosSide        string `json:"posSide"`
+	TdMode         string `json:"tdMode"`
+	Sz             string `json:"sz"`
+	State          string `json:"state"` // live/effective/canceled/partially_effective/order_failed
+	TpTriggerPx    string `json:"tpTriggerPx"`
+	TpOrdPx        string `json:"tpOrdPx"`
+	SlTriggerPx    string `json:"slTriggerPx"`
+	SlOrdPx        string `json:"slOrdPx"`
+	TriggerPx      string `json:"triggerPx"`
+	OrdPx          string `json:"ordPx"`
+	CallbackRatio  string `json:"callbackRatio"`
+	CallbackSpread string `json:"callbackSpread"`
+	ActivePx       string `json:"activePx"`
+	OrdId          string `json:"ordId"` // 触发后生成的订单ID
+	CTime          string `json:"cTime"`
+}
+
+// Err 返回策略委托的失败原因，成功时返回nil
+func (r *AlgoOrderResponse) Err() error {
+	if r.SCode == "" || r.SCode == "0" {
+		return nil
+	}
+	return &APIError{HTTPStatus: http.StatusOK, SCode: r.SCode, SMsg: r.SMsg}
+}
+
+// PlaceAlgoOrder 策略委托下单
+func (c *OKXClient) PlaceAlgoOrder(ctx context.Context, req *AlgoOrderRequest) (*AlgoOrderResponse, error) {
+	if req.TdMode == "" {
+		req.TdMode = "isolated"
+	}
+
+	resp, err := c.sendRequest(ctx, "POST", "/api/v5/trade/order-algo", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Code string              `json:"code"`
+		Msg  string              `json:"msg"`
+		Data []AlgoOrderResponse `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v, 响应内容: %s", err, string(resp))
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("策略委托响应数据为空")
+	}
+	if err := result.Data[0].Err(); err != nil {
+		return nil, fmt.Errorf("策略委托下单失败: %w", err)
+	}
+
+	return &result.Data[0], nil
+}
+
+// CancelAlgoOrder 撤销策略委托
+func (c *OKXClient) CancelAlgoOrder(ctx context.Context, instId, algoId string) error {
+	req := []map[string]string{{"instId": instId, "algoId": algoId}}
+
+	resp, err := c.sendRequest(ctx, "POST", "/api/v5/trade/cancel-algos", req)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Code string              `json:"code"`
+		Msg  string              `json:"msg"`
+		Data []AlgoOrderResponse `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	if len(result.Data) > 0 {
+		if err := result.Data[0].Err(); err != nil {
+			return fmt.Errorf("撤销策略委托失败: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// AmendAlgoOrder 修改策略委托
+func (c *OKXClient) AmendAlgoOrder(ctx context.Context, req *AmendAlgoRequest) error {
+	resp, err := c.sendRequest(ctx, "POST", "/api/v5/trade/amend-algos", req)
+	if err != nil {
+		return err
+	}
+
+	var result struct {
+		Code string              `json:"code"`
+		Msg  string              `json:"msg"`
+		Data []AlgoOrderResponse `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return fmt.Errorf("解析响应失败: %v", err)
+	}
+	if len(result.Data) > 0 {
+		if err := result.Data[0].Err(); err != nil {
+			return fmt.Errorf("修改策略委托失败: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetAlgoOrders 获取未完成的策略委托，instId为空时返回全部产品
+func (c *OKXClient) GetAlgoOrders(ctx context.Context, ordType, instId string) ([]*AlgoOrder, error) {
+	path := fmt.Sprintf("/api/v5/trade/orders-algo-pending?ordType=%s", ordType)
+	if instId != "" {
+		path += "&instId=" + instId
+	}
+
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Code string       `json:"code"`
+		Msg  string       `json:"msg"`
+		Data []*AlgoOrder `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析策略委托失败: %v", err)
+	}
+
+	return result.Data, nil
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// 资金划转的账户类型
+const (
+	AccountFunding = "6"  // 资金账户
+	AccountTrading = "18" // 交易账户
+)
+
+// AssetBalance 资金账户单个币种的余额
+type AssetBalance struct {
+	Ccy       string
+	Bal       float64 // 余额
+	AvailBal  float64 // 可用余额
+	FrozenBal float64 // 冻结余额
+}
+
+// GetAssetBalances 获取资金账户余额，ccy为空时返回所有币种
+func (c *OKXClient) GetAssetBalances(ctx context.Context, ccy string) ([]*AssetBalance, error) {
+	path := "/api/v5/asset/balances"
+	if ccy != "" {
+		path += "?ccy=" + ccy
+	}
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			Ccy       string `json:"ccy"`
+			Bal       string `json:"bal"`
+			AvailBal  string `json:"availBal"`
+			FrozenBal string `json:"frozenBal"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析资金账户余额失败: %v", err)
+	}
+
+	balances := make([]*AssetBalance, 0, len(result.Data))
+	for _, d := range result.Data {
+		balances = append(balances, &AssetBalance{
+			Ccy:       d.Ccy,
+			Bal:       parseFloat(d.Bal),
+			AvailBal:  parseFloat(d.AvailBal),
+			FrozenBal: parseFloat(d.FrozenBal),
+		})
+	}
+	return balances, nil
+}
+
+// TransferResult 划转结果
+type TransferResult struct {
+	TransId  string
+	ClientId string
+	Ccy      string
+	Amt      float64
+	From     string
+	To       string
+}
+
+// Transfer 在本账户的资金账户和交易账户之间划转，from/to为AccountFunding或AccountTrading。
+// clientId可用于查询划转状态和防止重复划转，可为空
+func (c *OKXClient) Transfer(ctx context.Context, ccy string, amt float64, from, to, clientId string) (*TransferResult, error) {
+	if amt <= 0 {
+		return nil, fmt.Errorf("划转数量必须大于0: %g", amt)
+	}
+	req := struct {
+		Ccy      string `json:"ccy"`
+		Amt      string `json:"amt"`
+		From     string `json:"from"`
+		To       string `json:"to"`
+		Type     string `json:"type"`
+		ClientId string `json:"clientId,omitempty"`
+	}{
+		Ccy:      ccy,
+		Amt:      strconv.FormatFloat(amt, 'f', -1, 64),
+		From:     from,
+		To:       to,
+		Type:     "0", // 母账户内划转
+		ClientId: clientId,
+	}
+
+	resp, err := c.sendRequest(ctx, "POST", "/api/v5/asset/transfer", req)
+	if err != nil {
+		return nil, fmt.Errorf("资金划转失败: %w", err)
+	}
+
+	var result struct {
+		Data []struct {
+			TransId  string `json:"transId"`
+			ClientId string `json:"clientId"`
+			Ccy      string `json:"ccy"`
+			Amt      string `json:"amt"`
+			From     string `json:"from"`
+			To       string `json:"to"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析划转结果失败: %v", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("划转结果为空")
+	}
+
+	d := result.Data[0]
+	return &TransferResult{
+		TransId:  d.TransId,
+		ClientId: d.ClientId,
+		Ccy:      d.Ccy,
+		Amt:      parseFloat(d.Amt),
+		From:     d.From,
+		To:       d.To,
+	}, nil
+}
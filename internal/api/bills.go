@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// 账单类型
+const (
+	BillTypeTransfer       = "1" // 划转
+	BillTypeTrade          = "2" // 交易
+	BillTypeDelivery       = "3" // 交割
+	BillTypeLiquidation    = "5" // 强平
+	BillTypeMarginTransfer = "6" // 保证金划转
+	BillTypeInterest       = "7" // 扣息
+	BillTypeFundingFee     = "8" // 资金费
+	BillTypeADL            = "9" // 自动减仓
+)
+
+// Bill 账户账单，记录每一笔引起余额变动的流水
+type Bill struct {
+	BillId   string
+	InstId   string
+	InstType string
+	Ccy      string
+	Type     string // 账单类型，见BillType常量
+	SubType  string // 账单子类型
+	MgnMode  string
+	BalChg   float64 // 余额变动
+	Bal      float64 // 变动后余额
+	Sz       float64 // 数量，交易类账单为成交数量
+	Px       float64 // 价格，交易类账单为成交价格，资金费为资金费率
+	Pnl      float64 // 收益
+	Fee      float64 // 手续费，负数表示扣除
+	OrdId    string
+	TradeId  string
+	Ts       time.Time
+}
+
+// BillsQuery 账单查询条件，After/Before为账单ID，分别查询更早/更新的记录
+type BillsQuery struct {
+	InstType string
+	Ccy      string
+	Type     string
+	After    string
+	Before   string
+	Limit    int // 最大100，0表示使用默认值100
+}
+
+// GetBills 查询最近7天的账单，按时间倒序
+func (c *OKXClient) GetBills(ctx context.Context, q BillsQuery) ([]*Bill, error) {
+	return c.getBills(ctx, "/api/v5/account/bills", q)
+}
+
+// GetBillsArchive 查询最近3个月的账单，按时间倒序
+func (c *OKXClient) GetBillsArchive(ctx context.Context, q BillsQuery) ([]*Bill, error) {
+	return c.getBills(ctx, "/api/v5/account/bills-archive", q)
+}
+
+func (c *OKXClient) getBills(ctx context.Context, endpoint string, q BillsQuery) ([]*Bill, error) {
+	limit := q.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	path := fmt.Sprintf("%s?limit=%d", endpoint, limit)
+	if q.InstType != "" {
+		path += "&instType=" + q.InstType
+	}
+	if q.Ccy != "" {
+		path += "&ccy=" + q.Ccy
+	}
+	if q.Type != "" {
+		path += "&type=" + q.Type
+	}
+	if q.After != "" {
+		path += "&after=" + q.After
+	}
+	if q.Before != "" {
+		path += "&before=" + q.Before
+	}
+
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			BillId   string `json:"billId"`
+			InstId   string `json:"instId"`
+			InstType string `json:"instType"`
+			Ccy      string `json:"ccy"`
+			Type     string `json:"type"`
+			SubType  string `json:"subType"`
+			MgnMode  string `json:"mgnMode"`
+			BalChg   string `json:"balChg"`
+			Bal      string `json:"bal"`
+			Sz       string `json:"sz"`
+			Px       string `json:"px"`
+			Pnl      string `json:"pnl"`
+			Fee      string `json:"fee"`
+			OrdId    string `json:"ordId"`
+			TradeId  string `json:"tradeId"`
+			Ts       string `json:"ts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析账单失败: %v", err)
+	}
+
+	bills := make([]*Bill, 0, len(result.Data))
+	for _, d := range result.Data {
+		bills = append(bills, &Bill{
+			BillId:   d.BillId,
+			InstId:   d.InstId,
+			InstType: d.InstType,
+			Ccy:      d.Ccy,
+			Type:     d.Type,
+			SubType:  d.SubType,
+			MgnMode:  d.MgnMode,
+			BalChg:   parseFloat(d.BalChg),
+			Bal:      parseFloat(d.Bal),
+			Sz:       parseFloat(d.Sz),
+			Px:       parseFloat(d.Px),
+			Pnl:      parseFloat(d.Pnl),
+			Fee:      parseFloat(d.Fee),
+			OrdId:    d.OrdId,
+			TradeId:  d.TradeId,
+			Ts:       parseMillis(d.Ts),
+		})
+	}
+	return bills, nil
+}
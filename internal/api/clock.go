@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// 默认校时间隔
+	clockSyncInterval = 5 * time.Minute
+	// 本地时钟与服务器偏差超过该值时打印警告，OKX拒绝偏差超过30秒的请求
+	clockSkewWarn = 5 * time.Second
+)
+
+// ClockStatus 本地时钟与OKX服务器的偏差
+type ClockStatus struct {
+	Offset   time.Duration `json:"offset_ns"` // 服务器时间减本地时间
+	RTT      time.Duration `json:"rtt_ns"`    // 最近一次校时的往返耗时
+	LastSync time.Time     `json:"last_sync"`
+	Samples  int64         `json:"samples"`
+	LastErr  string        `json:"last_error,omitempty"`
+}
+
+// clock 维护服务器时间偏移，签名时间戳使用本地时间加偏移
+type clock struct {
+	mu     sync.RWMutex
+	status ClockStatus
+
+	resyncMu sync.Mutex
+	resync   *clockResync // 进行中的重新校时
+}
+
+// clockResync 一次重新校时，done关闭后err为校时结果
+type clockResync struct {
+	done chan struct{}
+	err  error
+}
+
+func (k *clock) now() time.Time {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return time.Now().Add(k.status.Offset)
+}
+
+func (k *clock) snapshot() ClockStatus {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.status
+}
+
+// SyncTime 查询服务器时间并更新时钟偏移，偏移按往返耗时的中点估算
+func (c *OKXClient) SyncTime(ctx context.Context) error {
+	start := time.Now()
+	resp, err := c.doRequest(ctx, "GET", "/api/v5/public/time", nil)
+	rtt := time.Since(start)
+	if err == nil {
+		var serverTs int64
+		serverTs, err = parseServerTime(resp)
+		if err == nil {
+			offset := time.UnixMilli(serverTs).Sub(start.Add(rtt / 2))
+			c.clock.mu.Lock()
+			c.clock.status.Offset = offset
+			c.clock.status.RTT = rtt
+			c.clock.status.LastSync = time.Now()
+			c.clock.status.Samples++
+			c.clock.status.LastErr = ""
+			c.clock.mu.Unlock()
+
+			if offset > clockSkewWarn || offset < -clockSkewWarn {
+				log.Printf("警告: 本地时钟与服务器偏差 %v (往返耗时 %v)，已按偏差修正签名时间戳", offset, rtt)
+			}
+			return nil
+		}
+	}
+
+	c.clock.mu.Lock()
+	c.clock.status.LastErr = err.Error()
+	c.clock.mu.Unlock()
+	return fmt.Errorf("校时失败: %v", err)
+}
+
+func parseServerTime(resp []byte) (int64, error) {
+	var result struct {
+		Data []struct {
+			Ts string `json:"ts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return 0, fmt.Errorf("解析服务器时间失败: %v", err)
+	}
+	if len(result.Data) == 0 {
+		return 0, fmt.Errorf("服务器时间为空")
+	}
+	return strconv.ParseInt(result.Data[0].Ts, 10, 64)
+}
+
+// StartClockSync 立即校时一次，之后每隔interval校时，ctx结束时停止；interval为0时使用默认间隔
+func (c *OKXClient) StartClockSync(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = clockSyncInterval
+	}
+	if err := c.SyncTime(ctx); err != nil {
+		log.Printf("警告: %v", err)
+	} else {
+		status := c.ClockStatus()
+		log.Printf("服务器时间偏差: %v, 往返耗时: %v", status.Offset, status.RTT)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.SyncTime(ctx); err != nil && ctx.Err() == nil {
+					log.Printf("警告: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// ClockStatus 返回最近一次校时的结果
+func (c *OKXClient) ClockStatus() ClockStatus {
+	return c.clock.snapshot()
+}
+
+// resyncClock 收到时间戳过期错误时重新校时并等待结果，同时进行的多个请求共用一次校时。
+// 校时不受调用方ctx影响，ctx结束时只是不再等待
+func (c *OKXClient) resyncClock(ctx context.Context) error {
+	c.clock.resyncMu.Lock()
+	r := c.clock.resync
+	if r == nil {
+		r = &clockResync{done: make(chan struct{})}
+		c.clock.resync = r
+		go func() {
+			syncCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			r.err = c.SyncTime(syncCtx)
+			if r.err == nil {
+				log.Printf("请求时间戳过期，已重新校时，服务器时间偏差: %v", c.ClockStatus().Offset)
+			}
+
+			c.clock.resyncMu.Lock()
+			c.clock.resync = nil
+			c.clock.resyncMu.Unlock()
+			close(r.done)
+		}()
+	}
+	c.clock.resyncMu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.done:
+		return r.err
+	}
+}
+
+// isTimestampExpired 是否为请求时间戳过期错误
+func isTimestampExpired(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == codeTimestampExpired
+}
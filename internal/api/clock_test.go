@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// skewedServer 服务器时间比本地快1小时，签名时间戳偏差超过30秒的请求返回50102
+type skewedServer struct {
+	skew      time.Duration
+	timeCalls int32
+	expired   int32
+}
+
+func (s *skewedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	serverNow := time.Now().Add(s.skew)
+	if r.URL.Path == "/api/v5/public/time" {
+		atomic.AddInt32(&s.timeCalls, 1)
+		// 校时稍慢，让同时过期的请求都等待同一次校时
+		time.Sleep(200 * time.Millisecond)
+		fmt.Fprintf(w, `{"code":"0","msg":"","data":[{"ts":"%d"}]}`, time.Now().Add(s.skew).UnixMilli())
+		return
+	}
+
+	ts, err := time.Parse("2006-01-02T15:04:05.000Z", r.Header.Get("OK-ACCESS-TIMESTAMP"))
+	if err != nil || serverNow.Sub(ts) > 30*time.Second || ts.Sub(serverNow) > 30*time.Second {
+		atomic.AddInt32(&s.expired, 1)
+		w.Write([]byte(`{"code":"50102","msg":"Timestamp request expired","data":[]}`))
+		return
+	}
+	w.Write([]byte(`{"code":"0","msg":"","data":[{"ok":"1"}]}`))
+}
+
+func TestResyncClockRetriesExpiredRequest(t *testing.T) {
+	srv := &skewedServer{skew: time.Hour}
+	c := newTestClient(t, srv)
+
+	if _, err := c.sendRequest(context.Background(), "GET", "/api/v5/account/balance", nil); err != nil {
+		t.Fatalf("重新校时后应重发成功: %v", err)
+	}
+	if srv.timeCalls != 1 || srv.expired != 1 {
+		t.Fatalf("校时次数 = %d, 过期次数 = %d, 期望均为1", srv.timeCalls, srv.expired)
+	}
+	if offset := c.ClockStatus().Offset; offset < 59*time.Minute {
+		t.Fatalf("时钟偏移 = %v", offset)
+	}
+}
+
+func TestResyncClockDeduplicatesConcurrentExpiry(t *testing.T) {
+	srv := &skewedServer{skew: time.Hour}
+	c := newTestClient(t, srv)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.sendRequest(context.Background(), "GET", "/api/v5/account/balance", nil)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&srv.timeCalls); n != 1 {
+		t.Fatalf("同时过期的请求应只校时一次, 实际 %d 次", n)
+	}
+}
+
+func TestResyncClockOnlyOncePerRequest(t *testing.T) {
+	// 服务器一直拒绝时不应反复校时
+	srv := &skewedServer{skew: time.Hour}
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v5/public/time" {
+			srv.ServeHTTP(w, r)
+			return
+		}
+		atomic.AddInt32(&srv.expired, 1)
+		w.Write([]byte(`{"code":"50102","msg":"Timestamp request expired","data":[]}`))
+	}))
+
+	_, err := c.sendRequest(context.Background(), "GET", "/api/v5/account/balance", nil)
+	if !isTimestampExpired(err) {
+		t.Fatalf("应返回时间戳过期错误, 实际: %v", err)
+	}
+	if srv.timeCalls != 1 || srv.expired != 2 {
+		t.Fatalf("校时次数 = %d, 请求次数 = %d, 期望 1 和 2", srv.timeCalls, srv.expired)
+	}
+}
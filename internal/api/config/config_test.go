@@ -0,0 +1,150 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadYAML(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+const baseYAML = `
+api:
+  key: "top-key"
+  secret: "top-secret"
+  passphrase: "top-pass"
+  mode: "simulation"
+  base_url: "http://127.0.0.1:8090"
+  clock_sync_interval: 2m
+  record_file: "data/record.jsonl"
+trading:
+  trade_type: "futures"
+  leverage: 5
+  symbols: ["IP-USDT-SWAP", "BTC-USDT-SWAP"]
+  long_position:
+    enabled: true
+    take_profit: 0.5
+    stop_loss: 0.3
+    position_size: 10
+    symbol_margin_ratios:
+      "IP-USDT-SWAP": 10
+  rsi_strategy:
+    enabled: true
+    period: 14
+`
+
+func TestResolveAccountsDefault(t *testing.T) {
+	cfg, err := loadYAML(t, baseYAML)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if len(cfg.Accounts) != 1 || cfg.Accounts[0].Name != DefaultAccountName {
+		t.Fatalf("未配置accounts时应只有default账户: %+v", cfg.Accounts)
+	}
+	acc := cfg.Accounts[0]
+	if acc.API.Key != "top-key" || acc.API.RecordFile != "data/record.jsonl" {
+		t.Fatalf("default账户应使用顶层api: %+v", acc.API)
+	}
+	if acc.Trading.Leverage != 5 || acc.Trading.LongPosition.PositionSize != 10 {
+		t.Fatalf("default账户应使用顶层trading: %+v", acc.Trading)
+	}
+}
+
+func TestResolveAccountsMerge(t *testing.T) {
+	cfg, err := loadYAML(t, baseYAML+`
+accounts:
+  - name: main
+  - name: sub1
+    api:
+      key: "sub-key"
+      secret: "sub-secret"
+      passphrase: "sub-pass"
+    trading:
+      symbols: ["ETH-USDT-SWAP"]
+      long_position:
+        position_size: 0.5
+        symbol_margin_ratios:
+          "ETH-USDT-SWAP": 8
+      rsi_strategy:
+        enabled: false
+`)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	if len(cfg.Accounts) != 2 {
+		t.Fatalf("账户数量 = %d", len(cfg.Accounts))
+	}
+	mainAcc, sub := cfg.Accounts[0], cfg.Accounts[1]
+
+	if mainAcc.API.Key != "top-key" || mainAcc.Trading.LongPosition.PositionSize != 10 ||
+		len(mainAcc.Trading.Symbols) != 2 || !mainAcc.Trading.RSI.Enabled {
+		t.Fatalf("未填写api和trading的账户应完全使用顶层配置: %+v", mainAcc)
+	}
+
+	// api: 密钥覆盖，其余字段继承，记录文件不继承
+	if sub.API.Key != "sub-key" || sub.API.Secret != "sub-secret" || sub.API.Passphrase != "sub-pass" {
+		t.Fatalf("子账户密钥 = %+v", sub.API)
+	}
+	if sub.API.Mode != "simulation" || sub.API.BaseURL != "http://127.0.0.1:8090" || sub.API.ClockSyncInterval != 2*time.Minute {
+		t.Fatalf("子账户应继承顶层api的其他字段: %+v", sub.API)
+	}
+	if sub.API.RecordFile != "" {
+		t.Fatalf("record_file不应被继承: %q", sub.API.RecordFile)
+	}
+
+	// trading: 只覆盖填写的字段，嵌套结构中未填写的字段保留顶层值
+	tr := sub.Trading
+	if len(tr.Symbols) != 1 || tr.Symbols[0] != "ETH-USDT-SWAP" {
+		t.Fatalf("symbols = %v", tr.Symbols)
+	}
+	if tr.LongPosition.PositionSize != 0.5 || tr.LongPosition.TakeProfit != 0.5 || !tr.LongPosition.Enabled {
+		t.Fatalf("long_position = %+v", tr.LongPosition)
+	}
+	if tr.Leverage != 5 || tr.TradeType != "futures" || tr.RSI.Enabled || tr.RSI.Period != 14 {
+		t.Fatalf("trading = %+v", tr)
+	}
+}
+
+func TestResolveAccountsIndependentMaps(t *testing.T) {
+	cfg, err := loadYAML(t, baseYAML+`
+accounts:
+  - name: a
+  - name: b
+`)
+	if err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	cfg.Accounts[0].Trading.LongPosition.SymbolMarginRatios["IP-USDT-SWAP"] = 1
+
+	if got := cfg.Accounts[1].Trading.LongPosition.SymbolMarginRatios["IP-USDT-SWAP"]; got != 10 {
+		t.Fatalf("账户之间不应共享map, b的保证金率 = %v", got)
+	}
+	if got := cfg.Trading.LongPosition.SymbolMarginRatios["IP-USDT-SWAP"]; got != 10 {
+		t.Fatalf("不应修改顶层配置, 顶层保证金率 = %v", got)
+	}
+}
+
+func TestResolveAccountsInvalidNames(t *testing.T) {
+	tests := []struct {
+		accounts string
+		want     string
+	}{
+		{"\n  - name: a\n  - api:\n      key: x\n", "缺少name"},
+		{"\n  - name: a\n  - name: a\n", "账户名称重复"},
+	}
+	for _, tt := range tests {
+		_, err := loadYAML(t, baseYAML+"accounts:"+tt.accounts)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("accounts%s: 错误 = %v, 期望包含 %q", tt.accounts, err, tt.want)
+		}
+	}
+}
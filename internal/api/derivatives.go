@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FundingRate 永续合约资金费率
+type FundingRate struct {
+	InstId          string
+	FundingRate     float64   // 当期资金费率
+	NextFundingRate float64   // 下一期预测资金费率，交易所未提供时为0
+	FundingTime     time.Time // 当期资金费收取时间
+	NextFundingTime time.Time // 下一期资金费收取时间
+}
+
+// MarkPrice 标记价格
+type MarkPrice struct {
+	InstId string
+	MarkPx float64
+	Ts     time.Time
+}
+
+// IndexPrice 指数价格
+type IndexPrice struct {
+	InstId string // 指数ID，如 BTC-USDT
+	IdxPx  float64
+	Ts     time.Time
+}
+
+// OpenInterest 合约持仓总量
+type OpenInterest struct {
+	InstId string
+	Oi     float64 // 持仓量(张)
+	OiCcy  float64 // 持仓量(币)
+	Ts     time.Time
+}
+
+// IndexId 返回合约对应的指数ID，如 BTC-USDT-SWAP -> BTC-USDT
+func IndexId(instId string) string {
+	parts := strings.Split(instId, "-")
+	if len(parts) < 2 {
+		return instId
+	}
+	return parts[0] + "-" + parts[1]
+}
+
+// GetFundingRate 获取永续合约当期和下一期资金费率
+func (c *OKXClient) GetFundingRate(ctx context.Context, instId string) (*FundingRate, error) {
+	resp, err := c.sendRequest(ctx, "GET", "/api/v5/public/funding-rate?instId="+instId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			InstId          string `json:"instId"`
+			FundingRate     string `json:"fundingRate"`
+			NextFundingRate string `json:"nextFundingRate"`
+			FundingTime     string `json:"fundingTime"`
+			NextFundingTime string `json:"nextFundingTime"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析资金费率失败: %v", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("未获取到资金费率: %s", instId)
+	}
+
+	d := result.Data[0]
+	rate := &FundingRate{
+		InstId:          d.InstId,
+		FundingTime:     parseMillis(d.FundingTime),
+		NextFundingTime: parseMillis(d.NextFundingTime),
+	}
+	rate.FundingRate, _ = strconv.ParseFloat(d.FundingRate, 64)
+	rate.NextFundingRate, _ = strconv.ParseFloat(d.NextFundingRate, 64)
+	return rate, nil
+}
+
+// GetMarkPrice 获取合约标记价格
+func (c *OKXClient) GetMarkPrice(ctx context.Context, instId string) (*MarkPrice, error) {
+	path := fmt.Sprintf("/api/v5/public/mark-price?instType=%s&instId=%s", instTypeOf(instId), instId)
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			InstId string `json:"instId"`
+			MarkPx string `json:"markPx"`
+			Ts     string `json:"ts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析标记价格失败: %v", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("未获取到标记价格: %s", instId)
+	}
+
+	d := result.Data[0]
+	price := &MarkPrice{InstId: d.InstId, Ts: parseMillis(d.Ts)}
+	price.MarkPx, _ = strconv.ParseFloat(d.MarkPx, 64)
+	return price, nil
+}
+
+// GetIndexPrice 获取指数价格，indexId如 BTC-USDT，可用IndexId从合约ID得到
+func (c *OKXClient) GetIndexPrice(ctx context.Context, indexId string) (*IndexPrice, error) {
+	resp, err := c.sendRequest(ctx, "GET", "/api/v5/market/index-tickers?instId="+indexId, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			InstId string `json:"instId"`
+			IdxPx  string `json:"idxPx"`
+			Ts     string `json:"ts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析指数价格失败: %v", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("未获取到指数价格: %s", indexId)
+	}
+
+	d := result.Data[0]
+	price := &IndexPrice{InstId: d.InstId, Ts: parseMillis(d.Ts)}
+	price.IdxPx, _ = strconv.ParseFloat(d.IdxPx, 64)
+	return price, nil
+}
+
+// GetOpenInterest 获取合约持仓总量
+func (c *OKXClient) GetOpenInterest(ctx context.Context, instId string) (*OpenInterest, error) {
+	path := fmt.Sprintf("/api/v5/public/open-interest?instType=%s&instId=%s", instTypeOf(instId), instId)
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			InstId string `json:"instId"`
+			Oi     string `json:"oi"`
+			OiCcy  string `json:"oiCcy"`
+			Ts     string `json:"ts"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析持仓总量失败: %v", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("未获取到持仓总量: %s", instId)
+	}
+
+	d := result.Data[0]
+	oi := &OpenInterest{InstId: d.InstId, Ts: parseMillis(d.Ts)}
+	oi.Oi, _ = strconv.ParseFloat(d.Oi, 64)
+	oi.OiCcy, _ = strconv.ParseFloat(d.OiCcy, 64)
+	return oi, nil
+}
+
+// parseMillis 解析毫秒时间戳，为空或格式错误时返回零值
+func parseMillis(ms string) time.Time {
+	v, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil || v == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(v)
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"time"
+)
+
+// ErrorClass OKX错误分类，决定调用方的处理和重试策略
+type ErrorClass int
+
+const (
+	ClassUnknown          ErrorClass = iota
+	ClassNetwork                     // 网络错误或请求超时
+	ClassRateLimit                   // 请求过于频繁
+	ClassServerBusy                  // 系统繁忙或维护中
+	ClassAuth                        // 密钥、签名或时间戳错误
+	ClassParam                       // 请求参数错误
+	ClassInsufficient                // 余额或保证金不足
+	ClassInstrument                  // 产品不存在、已到期或暂停交易
+	ClassPositionNotExist            // 持仓不存在或可平数量不足
+	ClassOrderNotExist               // 订单不存在或已完成
+	ClassDuplicate                   // 自定义订单ID重复，通常意味着前一次请求已成功
+	ClassRejected                    // 其他业务拒绝
+)
+
+var classNames = map[ErrorClass]string{
+	ClassUnknown:          "unknown",
+	ClassNetwork:          "network",
+	ClassRateLimit:        "rate_limit",
+	ClassServerBusy:       "server_busy",
+	ClassAuth:             "auth",
+	ClassParam:            "param",
+	ClassInsufficient:     "insufficient",
+	ClassInstrument:       "instrument",
+	ClassPositionNotExist: "position_not_exist",
+	ClassOrderNotExist:    "order_not_exist",
+	ClassDuplicate:        "duplicate",
+	ClassRejected:         "rejected",
+}
+
+func (c ErrorClass) String() string {
+	return classNames[c]
+}
+
+// Temporary 该类错误是否可以重试
+func (c ErrorClass) Temporary() bool {
+	_, ok := retryPolicies[c]
+	return ok
+}
+
+// codeTimestampExpired 请求时间戳过期，收到后重新校时
+const codeTimestampExpired = "50102"
+
+// errorCodes 已知的OKX错误码，顶层code和每笔订单的sCode共用
+var errorCodes = map[string]ErrorClass{
+	// 系统
+	"50001": ClassServerBusy, // 服务暂时不可用
+	"50004": ClassServerBusy, // 接口请求超时
+	"50013": ClassServerBusy, // 系统繁忙
+	"50026": ClassServerBusy, // 系统错误
+	"50011": ClassRateLimit,  // 用户请求频率过快
+	"50061": ClassRateLimit,  // 子账户请求频率过快
+
+	// 认证
+	"50100": ClassAuth, // API被冻结
+	"50101": ClassAuth, // APIKey与当前环境不匹配
+	"50102": ClassAuth, // 请求时间戳过期
+	"50103": ClassAuth, // OK-ACCESS-KEY不能为空
+	"50104": ClassAuth, // OK-ACCESS-PASSPHRASE不能为空
+	"50105": ClassAuth, // OK-ACCESS-PASSPHRASE错误
+	"50111": ClassAuth, // 无效的OK-ACCESS-KEY
+	"50112": ClassAuth, // 无效的OK-ACCESS-TIMESTAMP
+	"50113": ClassAuth, // 无效的签名
+
+	// 参数
+	"50014": ClassParam, // 必填参数不能为空
+	"51000": ClassParam, // 参数错误
+	"51006": ClassParam, // 委托价格不在限价范围内
+	"51020": ClassParam, // 委托数量小于最小下单数量
+	"51121": ClassParam, // 委托数量不是下单精度的整数倍
+	"51201": ClassParam, // 市价单下单数量超过最大值
+
+	// 余额和保证金
+	"51004": ClassInsufficient, // 委托数量超过当前杠杆倍数下的最大可开数量
+	"51008": ClassInsufficient, // 可用保证金不足
+	"51119": ClassInsufficient, // 余额不足
+	"51127": ClassInsufficient, // 可用余额为0
+	"51131": ClassInsufficient, // 账户余额不足
+
+	// 产品
+	"51001": ClassInstrument, // 产品不存在
+	"51027": ClassInstrument, // 合约已到期
+	"51028": ClassInstrument, // 合约交割中
+	"51029": ClassInstrument, // 合约结算中
+	"51030": ClassInstrument, // 资金费结算中
+	"51155": ClassInstrument, // 当地合规限制无法交易该产品
+
+	// 持仓
+	"51169": ClassPositionNotExist, // 该方向无持仓可平
+	"51112": ClassPositionNotExist, // 平仓数量超过可平数量
+
+	// 订单
+	"51400": ClassOrderNotExist, // 撤单失败，订单已完成或不存在
+	"51503": ClassOrderNotExist, // 改单失败，订单已完成或不存在
+	"51603": ClassOrderNotExist, // 订单不存在
+	"51016": ClassDuplicate,     // clOrdId重复
+}
+
+// APIError OKX接口错误，包含HTTP状态码、顶层错误码以及单笔订单的sCode
+type APIError struct {
+	HTTPStatus int
+	Path       string
+	Code       string // 顶层code，批量接口为1(全部失败)或2(部分成功)
+	Msg        string
+	SCode      string // 单笔订单错误码
+	SMsg       string
+}
+
+func (e *APIError) Error() string {
+	if e.SCode != "" {
+		return fmt.Sprintf("%s (错误码: %s)", e.SMsg, e.SCode)
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("API错误: %s (code=%s, http=%d)", e.Msg, e.Code, e.HTTPStatus)
+	}
+	return fmt.Sprintf("API错误: HTTP %d %s", e.HTTPStatus, e.Msg)
+}
+
+// Class 按sCode、code和HTTP状态码依次判断错误分类
+func (e *APIError) Class() ErrorClass {
+	if class, ok := errorCodes[e.SCode]; ok {
+		return class
+	}
+	if class, ok := errorCodes[e.Code]; ok {
+		return class
+	}
+	switch {
+	case e.HTTPStatus == http.StatusTooManyRequests:
+		return ClassRateLimit
+	case e.HTTPStatus == http.StatusUnauthorized:
+		return ClassAuth
+	case e.HTTPStatus >= 500:
+		return ClassServerBusy
+	case e.SCode != "" || e.Code != "":
+		return ClassRejected
+	}
+	return ClassUnknown
+}
+
+// ClassOf 返回错误分类，支持被%w包装的APIError和网络错误
+func ClassOf(err error) ErrorClass {
+	if err == nil {
+		return ClassUnknown
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Class()
+	}
+	if errors.Is(err, context.Canceled) {
+		return ClassUnknown
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return ClassNetwork
+	}
+	return ClassUnknown
+}
+
+// IsRateLimit 是否为限频错误
+func IsRateLimit(err error) bool {
+	return ClassOf(err) == ClassRateLimit
+}
+
+// IsInsufficient 是否为余额或保证金不足
+func IsInsufficient(err error) bool {
+	return ClassOf(err) == ClassInsufficient
+}
+
+// IsInstrumentUnavailable 是否为产品不存在或暂停交易
+func IsInstrumentUnavailable(err error) bool {
+	return ClassOf(err) == ClassInstrument
+}
+
+// IsPositionNotExist 是否为持仓不存在
+func IsPositionNotExist(err error) bool {
+	return ClassOf(err) == ClassPositionNotExist
+}
+
+// IsOrderNotExist 是否为订单不存在或已完成
+func IsOrderNotExist(err error) bool {
+	return ClassOf(err) == ClassOrderNotExist
+}
+
+// IsDuplicate 是否为clOrdId重复，网络超时后重发的下单请求会收到该错误
+func IsDuplicate(err error) bool {
+	return ClassOf(err) == ClassDuplicate
+}
+
+// RetryPolicy 指数退避重试策略
+type RetryPolicy struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// retryPolicies 可重试的错误分类及其退避参数，未列出的分类不重试
+var retryPolicies = map[ErrorClass]RetryPolicy{
+	ClassNetwork:    {BaseDelay: 500 * time.Millisecond, MaxDelay: 4 * time.Second},
+	ClassRateLimit:  {BaseDelay: 1 * time.Second, MaxDelay: 8 * time.Second},
+	ClassServerBusy: {BaseDelay: 1 * time.Second, MaxDelay: 10 * time.Second},
+}
+
+// Backoff 返回第attempt次重试(从1开始)前的等待时间，带±20%抖动
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt-1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	jitter := time.Duration((rand.Float64()*0.4 - 0.2) * float64(delay))
+	return delay + jitter
+}
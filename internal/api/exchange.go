@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+
+	"okxauto/internal/models"
+)
+
+// Exchange 交易所接口，交易引擎和策略只依赖该接口，便于替换为模拟盘或回测实现。
+// 所有方法都接受ctx，取消后限速等待、重试退避和进行中的请求都会立即返回
+type Exchange interface {
+	// 交易
+	PlaceOrder(ctx context.Context, req *PlaceOrderRequest) (*OrderResponse, error)
+	CancelOrder(ctx context.Context, symbol, orderId string) error
+	GetOrder(ctx context.Context, instId, ordId string) (*Order, error)
+	GetFills(ctx context.Context, instId, ordId string) ([]*Fill, error)
+	PlaceOrders(ctx context.Context, reqs []*PlaceOrderRequest) ([]OrderResponse, error)
+	CancelOrders(ctx context.Context, reqs []CancelOrderRequest) ([]OrderResponse, error)
+	AmendOrder(ctx context.Context, req *AmendOrderRequest) (*OrderResponse, error)
+	AmendOrders(ctx context.Context, reqs []*AmendOrderRequest) ([]OrderResponse, error)
+
+	// 策略委托
+	PlaceAlgoOrder(ctx context.Context, req *AlgoOrderRequest) (*AlgoOrderResponse, error)
+	CancelAlgoOrder(ctx context.Context, instId, algoId string) error
+	AmendAlgoOrder(ctx context.Context, req *AmendAlgoRequest) error
+	GetAlgoOrders(ctx context.Context, ordType, instId string) ([]*AlgoOrder, error)
+
+	// 账户
+	GetPositions(ctx context.Context, instId string) ([]*models.Position, error)
+	GetBalances(ctx context.Context) ([]*Balance, error)
+	GetBalance(ctx context.Context, currency string) (*Balance, error)
+	GetAccountSummary(ctx context.Context) (*AccountSummary, error)
+	SetLeverage(ctx context.Context, instId string, lever string, mgnMode string, posSide string) error
+	AddMargin(ctx context.Context, params map[string]string) (map[string]interface{}, error)
+	GetAccountConfig(ctx context.Context) (*AccountConfig, error)
+	SetPositionMode(ctx context.Context, posMode string) error
+	GetMaxSize(ctx context.Context, instId, tdMode string, lever int, px float64) (*MaxSize, error)
+	GetMaxAvailSize(ctx context.Context, instId, tdMode string, reduceOnly bool) (*MaxAvailSize, error)
+	GetFeeRate(ctx context.Context, instType string) (*FeeRate, error)
+	GetBills(ctx context.Context, q BillsQuery) ([]*Bill, error)
+	GetBillsArchive(ctx context.Context, q BillsQuery) ([]*Bill, error)
+
+	// 资金
+	GetAssetBalances(ctx context.Context, ccy string) ([]*AssetBalance, error)
+	Transfer(ctx context.Context, ccy string, amt float64, from, to, clientId string) (*TransferResult, error)
+
+	// 行情
+	GetKlines(ctx context.Context, symbol string, period string, limit int) ([]Candle, error)
+	GetHistoryCandles(ctx context.Context, instId, bar string, after, before int64, limit int) ([]Candle, error)
+	GetInstrument(ctx context.Context, instId string) (*Instrument, error)
+
+	// 合约行情
+	GetFundingRate(ctx context.Context, instId string) (*FundingRate, error)
+	GetMarkPrice(ctx context.Context, instId string) (*MarkPrice, error)
+	GetIndexPrice(ctx context.Context, indexId string) (*IndexPrice, error)
+	GetOpenInterest(ctx context.Context, instId string) (*OpenInterest, error)
+}
+
+// 确保OKXClient实现了Exchange接口
+var _ Exchange = (*OKXClient)(nil)
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// FeeRate 账户在某产品类型下的手续费率，Maker和Taker为正数表示收取的费率，负数表示返佣
+type FeeRate struct {
+	InstType string
+	Level    string // 手续费等级，如 Lv1
+	Maker    float64
+	Taker    float64
+}
+
+// GetFeeRate 获取账户在指定产品类型(SPOT/SWAP/FUTURES/OPTION)下的手续费率。
+// 交易所返回的费率负数表示收取，这里取反；合约优先使用USDT保证金合约的费率
+func (c *OKXClient) GetFeeRate(ctx context.Context, instType string) (*FeeRate, error) {
+	resp, err := c.sendRequest(ctx, "GET", "/api/v5/account/trade-fee?instType="+instType, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			InstType string `json:"instType"`
+			Level    string `json:"level"`
+			Maker    string `json:"maker"`
+			Taker    string `json:"taker"`
+			MakerU   string `json:"makerU"` // USDT保证金合约
+			TakerU   string `json:"takerU"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析手续费率失败: %v", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("未获取到手续费率: %s", instType)
+	}
+
+	d := result.Data[0]
+	maker, taker := d.Maker, d.Taker
+	if d.MakerU != "" && d.TakerU != "" {
+		maker, taker = d.MakerU, d.TakerU
+	}
+	return &FeeRate{
+		InstType: instType,
+		Level:    d.Level,
+		Maker:    -parseFloat(maker),
+		Taker:    -parseFloat(taker),
+	}, nil
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Instrument 交易产品基础信息
+type Instrument struct {
+	InstId    string  `json:"instId"`
+	InstType  string  `json:"instType"`  // SPOT/SWAP/FUTURES
+	BaseCcy   string  `json:"baseCcy"`   // 现货交易货币
+	QuoteCcy  string  `json:"quoteCcy"`  // 现货计价货币
+	SettleCcy string  `json:"settleCcy"` // 合约盈亏结算币种
+	CtValCcy  string  `json:"ctValCcy"`  // 合约面值计价币种
+	CtType    string  `json:"ctType"`    // linear正向/inverse反向
+	State     string  `json:"state"`     // live/suspend/preopen
+	CtVal     float64 `json:"ctVal"`     // 合约面值
+	CtMult    float64 `json:"ctMult"`    // 合约乘数
+	LotSz     float64 `json:"lotSz"`     // 下单数量精度
+	TickSz    float64 `json:"tickSz"`    // 下单价格精度
+	MinSz     float64 `json:"minSz"`     // 最小下单数量
+	MaxMktSz  float64 `json:"maxMktSz"`  // 市价单最大下单数量，合约为张数，现货为USDT金额
+
+	lotDecimals  int
+	tickDecimals int
+}
+
+// IsDerivative 是否为合约产品
+func (i *Instrument) IsDerivative() bool {
+	return i.InstType == "SWAP" || i.InstType == "FUTURES"
+}
+
+// IsSpot 是否为现货产品
+func (i *Instrument) IsSpot() bool {
+	return i.InstType == "SPOT"
+}
+
+// SizeFor 将币数量换算为下单数量(合约张数或现货数量)，并按lotSz向下取整
+func (i *Instrument) SizeFor(amount, price float64) float64 {
+	sz := amount
+	if i.IsDerivative() && i.CtVal > 0 {
+		if i.CtType == "inverse" {
+			// 反向合约面值以USD计价
+			sz = amount * price / i.CtVal
+		} else {
+			sz = amount / i.CtVal
+		}
+	}
+	return i.RoundSize(sz)
+}
+
+// Amount 将下单数量换算为币数量，SizeFor的逆运算
+func (i *Instrument) Amount(sz, price float64) float64 {
+	if !i.IsDerivative() || i.CtVal == 0 {
+		return sz
+	}
+	if i.CtType == "inverse" {
+		if price == 0 {
+			return 0
+		}
+		return sz * i.CtVal / price
+	}
+	return sz * i.CtVal
+}
+
+// Notional 计算下单数量对应的名义价值(计价货币)
+func (i *Instrument) Notional(sz, price float64) float64 {
+	if !i.IsDerivative() || i.CtVal == 0 {
+		return sz * price
+	}
+	if i.CtType == "inverse" {
+		return sz * i.CtVal
+	}
+	return sz * i.CtVal * price
+}
+
+// RoundSize 按lotSz向下取整
+func (i *Instrument) RoundSize(sz float64) float64 {
+	if i.LotSz <= 0 {
+		return sz
+	}
+	return roundTo(math.Floor(sz/i.LotSz+1e-9)*i.LotSz, i.lotDecimals)
+}
+
+// RoundPrice 按tickSz四舍五入
+func (i *Instrument) RoundPrice(px float64) float64 {
+	if i.TickSz <= 0 {
+		return px
+	}
+	return roundTo(math.Round(px/i.TickSz)*i.TickSz, i.tickDecimals)
+}
+
+// FormatSize 按数量精度格式化
+func (i *Instrument) FormatSize(sz float64) string {
+	return strconv.FormatFloat(i.RoundSize(sz), 'f', i.lotDecimals, 64)
+}
+
+// FormatPrice 按价格精度格式化
+func (i *Instrument) FormatPrice(px float64) string {
+	return strconv.FormatFloat(i.RoundPrice(px), 'f', i.tickDecimals, 64)
+}
+
+// ValidateSize 检查下单数量是否满足最小下单数量
+func (i *Instrument) ValidateSize(sz float64) error {
+	if sz <= 0 || sz < i.MinSz {
+		return fmt.Errorf("下单数量 %s 低于最小下单数量 %s", i.FormatSize(sz), strconv.FormatFloat(i.MinSz, 'f', -1, 64))
+	}
+	return nil
+}
+
+// FormatQuoteAmount 按计价货币精度向下取整并格式化金额，用于现货按quote_ccy下单。
+// USDT等稳定币保留2位小数，其他计价货币按价格精度
+func (i *Instrument) FormatQuoteAmount(amt float64) string {
+	decimals := i.tickDecimals
+	switch i.QuoteCcy {
+	case "USDT", "USDC", "USD":
+		decimals = 2
+	}
+	p := math.Pow10(decimals)
+	return strconv.FormatFloat(math.Floor(amt*p+1e-9)/p, 'f', decimals, 64)
+}
+
+// MaxMarketSize 返回单笔市价单的最大下单数量(合约张数或现货交易货币数量)，0表示不限制。
+// 现货的maxMktSz为USDT金额，按price换算为交易货币数量
+func (i *Instrument) MaxMarketSize(price float64) float64 {
+	if i.MaxMktSz <= 0 {
+		return 0
+	}
+	if i.IsDerivative() {
+		return i.RoundSize(i.MaxMktSz)
+	}
+	if price <= 0 {
+		return 0
+	}
+	return i.RoundSize(i.MaxMktSz / price)
+}
+
+func roundTo(v float64, decimals int) float64 {
+	p := math.Pow10(decimals)
+	return math.Round(v*p) / p
+}
+
+// decimals 返回数字字符串的小数位数
+func decimals(s string) int {
+	if idx := strings.IndexByte(s, '.'); idx >= 0 {
+		return len(strings.TrimRight(s[idx+1:], "0"))
+	}
+	return 0
+}
+
+// instTypeOf 根据产品ID推断产品类型，只用于查询尚未缓存的产品信息；
+// 已加载的产品应使用Instrument.InstType判断。
+// 永续 BTC-USDT-SWAP，交割 BTC-USDT-250328，期权 BTC-USD-250328-100000-C，现货 BTC-USDT
+func instTypeOf(instId string) string {
+	parts := strings.Split(instId, "-")
+	switch {
+	case strings.HasSuffix(instId, "-SWAP"):
+		return "SWAP"
+	case len(parts) == 5:
+		return "OPTION"
+	case len(parts) == 3:
+		return "FUTURES"
+	default:
+		return "SPOT"
+	}
+}
+
+// LoadInstruments 加载并缓存指定类型的全部产品信息
+func (c *OKXClient) LoadInstruments(ctx context.Context, instTypes ...string) error {
+	for _, instType := range instTypes {
+		instruments, err := c.fetchInstruments(ctx, instType, "")
+		if err != nil {
+			return fmt.Errorf("加载%s产品信息失败: %v", instType, err)
+		}
+		c.instMu.Lock()
+		for _, inst := range instruments {
+			c.instruments[inst.InstId] = inst
+		}
+		c.instMu.Unlock()
+		log.Printf("已加载%s产品信息 %d 个", instType, len(instruments))
+	}
+	return nil
+}
+
+// GetInstrument 获取产品信息，优先使用缓存
+func (c *OKXClient) GetInstrument(ctx context.Context, instId string) (*Instrument, error) {
+	c.instMu.RLock()
+	inst, ok := c.instruments[instId]
+	c.instMu.RUnlock()
+	if ok {
+		return inst, nil
+	}
+
+	instruments, err := c.fetchInstruments(ctx, instTypeOf(instId), instId)
+	if err != nil {
+		return nil, err
+	}
+	if len(instruments) == 0 {
+		return nil, fmt.Errorf("未找到产品: %s", instId)
+	}
+
+	c.instMu.Lock()
+	c.instruments[instId] = instruments[0]
+	c.instMu.Unlock()
+	return instruments[0], nil
+}
+
+func (c *OKXClient) fetchInstruments(ctx context.Context, instType, instId string) ([]*Instrument, error) {
+	path := fmt.Sprintf("/api/v5/public/instruments?instType=%s", instType)
+	if instId != "" {
+		path += "&instId=" + instId
+	}
+
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Code string `json:"code"`
+		Msg  string `json:"msg"`
+		Data []struct {
+			InstId    string `json:"instId"`
+			InstType  string `json:"instType"`
+			BaseCcy   string `json:"baseCcy"`
+			QuoteCcy  string `json:"quoteCcy"`
+			SettleCcy string `json:"settleCcy"`
+			CtValCcy  string `json:"ctValCcy"`
+			CtType    string `json:"ctType"`
+			State     string `json:"state"`
+			CtVal     string `json:"ctVal"`
+			CtMult    string `json:"ctMult"`
+			LotSz     string `json:"lotSz"`
+			TickSz    string `json:"tickSz"`
+			MinSz     string `json:"minSz"`
+			MaxMktSz  string `json:"maxMktSz"`
+		} `json:"data"`
+	}
+
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析产品信息失败: %v", err)
+	}
+
+	instruments := make([]*Instrument, 0, len(result.Data))
+	for _, d := range result.Data {
+		inst := &Instrument{
+			InstId:       d.InstId,
+			InstType:     d.InstType,
+			BaseCcy:      d.BaseCcy,
+			QuoteCcy:     d.QuoteCcy,
+			SettleCcy:    d.SettleCcy,
+			CtValCcy:     d.CtValCcy,
+			CtType:       d.CtType,
+			State:        d.State,
+			lotDecimals:  decimals(d.LotSz),
+			tickDecimals: decimals(d.TickSz),
+		}
+		inst.CtVal, _ = strconv.ParseFloat(d.CtVal, 64)
+		inst.CtMult, _ = strconv.ParseFloat(d.CtMult, 64)
+		inst.LotSz, _ = strconv.ParseFloat(d.LotSz, 64)
+		inst.TickSz, _ = strconv.ParseFloat(d.TickSz, 64)
+		inst.MinSz, _ = strconv.ParseFloat(d.MinSz, 64)
+		inst.MaxMktSz, _ = strconv.ParseFloat(d.MaxMktSz, 64)
+		instruments = append(instruments, inst)
+	}
+
+	return instruments, nil
+}
@@ -0,0 +1,53 @@
+package api
+
+import "testing"
+
+func TestFormatQuoteAmount(t *testing.T) {
+	tests := []struct {
+		quote string
+		amt   float64
+		want  string
+	}{
+		{"USDT", 12.345678901, "12.34"},
+		{"USDT", 10, "10.00"},
+		{"USDC", 0.999, "0.99"},
+		{"BTC", 0.0123456789, "0.01234"}, // 按价格精度0.00001
+	}
+	for _, tt := range tests {
+		inst := &Instrument{InstType: "SPOT", QuoteCcy: tt.quote, TickSz: 0.00001, tickDecimals: 5}
+		if got := inst.FormatQuoteAmount(tt.amt); got != tt.want {
+			t.Errorf("%s %v: got %s, want %s", tt.quote, tt.amt, got, tt.want)
+		}
+	}
+}
+
+func TestMaxMarketSize(t *testing.T) {
+	swap := &Instrument{InstType: "SWAP", LotSz: 1, MaxMktSz: 5000}
+	if got := swap.MaxMarketSize(30000); got != 5000 {
+		t.Errorf("合约maxMktSz为张数: got %v", got)
+	}
+
+	// 现货maxMktSz为USDT金额，按价格换算为交易货币数量并按lotSz向下取整
+	spot := &Instrument{InstType: "SPOT", LotSz: 0.0001, lotDecimals: 4, MaxMktSz: 1000000}
+	if got := spot.MaxMarketSize(30000); got != 33.3333 {
+		t.Errorf("现货: got %v", got)
+	}
+	if got := (&Instrument{InstType: "SWAP"}).MaxMarketSize(1); got != 0 {
+		t.Errorf("未设置maxMktSz时应不限制: got %v", got)
+	}
+}
+
+func TestInstTypeOf(t *testing.T) {
+	tests := map[string]string{
+		"BTC-USDT-SWAP":           "SWAP",
+		"BTC-USD-250328":          "FUTURES",
+		"BTC-USD-250328-100000-C": "OPTION",
+		"ETH-USD-250328-3000-P":   "OPTION",
+		"BTC-USDT":                "SPOT",
+	}
+	for instId, want := range tests {
+		if got := instTypeOf(instId); got != want {
+			t.Errorf("%s: got %s, want %s", instId, got, want)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"okxauto/internal/types"
+)
+
+// MarketFeed 公共行情推送，订阅tickers/trades/candle频道并将数据分发为types.Tick
+type MarketFeed struct {
+	public   *WSClient
+	business *WSClient // K线频道位于business地址
+
+	mu          sync.RWMutex
+	subscribers map[string][]chan *types.Tick
+
+	booksMu sync.RWMutex
+	books   map[string]*OrderBook
+}
+
+// NewMarketFeed 创建行情推送，publicURL用于tickers/trades，businessURL用于K线
+func NewMarketFeed(publicURL, businessURL string) *MarketFeed {
+	f := &MarketFeed{
+		subscribers: make(map[string][]chan *types.Tick),
+		books:       make(map[string]*OrderBook),
+	}
+	f.public = NewWSClient(publicURL, f.handleMessage)
+	f.public.onDisconnect = f.resetBooks
+	if businessURL != "" {
+		f.business = NewWSClient(businessURL, f.handleMessage)
+	}
+	return f
+}
+
+// Start 启动WebSocket连接
+func (f *MarketFeed) Start() {
+	f.public.Start()
+	if f.business != nil {
+		f.business.Start()
+	}
+}
+
+// Stop 关闭WebSocket连接
+func (f *MarketFeed) Stop() {
+	f.public.Stop()
+	if f.business != nil {
+		f.business.Stop()
+	}
+}
+
+// SubscribeTickers 订阅行情频道
+func (f *MarketFeed) SubscribeTickers(symbol string) error {
+	return f.public.Subscribe(WSArg{Channel: "tickers", InstId: symbol})
+}
+
+// SubscribeTrades 订阅逐笔成交频道
+func (f *MarketFeed) SubscribeTrades(symbol string) error {
+	return f.public.Subscribe(WSArg{Channel: "trades", InstId: symbol})
+}
+
+// SubscribeCandles 订阅K线频道，bar如1m/5m/1H
+func (f *MarketFeed) SubscribeCandles(symbol, bar string) error {
+	client := f.business
+	if client == nil {
+		client = f.public
+	}
+	return client.Subscribe(WSArg{Channel: "candle" + bar, InstId: symbol})
+}
+
+// SubscribeBooks 订阅400档深度频道，由快照初始化后按增量维护本地深度
+func (f *MarketFeed) SubscribeBooks(symbol string) error {
+	f.booksMu.Lock()
+	if _, ok := f.books[symbol]; !ok {
+		f.books[symbol] = newOrderBook(symbol)
+	}
+	f.booksMu.Unlock()
+	return f.public.Subscribe(WSArg{Channel: "books", InstId: symbol})
+}
+
+// OrderBook 返回指定交易对的本地深度，未订阅或尚未同步完成时返回false
+func (f *MarketFeed) OrderBook(symbol string) (*OrderBook, bool) {
+	f.booksMu.RLock()
+	book, ok := f.books[symbol]
+	f.booksMu.RUnlock()
+	if !ok || !book.Ready() {
+		return nil, false
+	}
+	return book, true
+}
+
+// resetBooks 连接断开后清空深度，重连恢复订阅时会重新收到快照
+func (f *MarketFeed) resetBooks() {
+	f.booksMu.RLock()
+	defer f.booksMu.RUnlock()
+	for _, book := range f.books {
+		book.reset()
+	}
+}
+
+// resyncBook 深度校验失败时重新订阅以获取新的快照
+func (f *MarketFeed) resyncBook(symbol string) {
+	arg := WSArg{Channel: "books", InstId: symbol}
+	if err := f.public.Unsubscribe(arg); err != nil {
+		log.Printf("[%s] 取消深度订阅失败: %v", symbol, err)
+	}
+	if err := f.public.Subscribe(arg); err != nil {
+		log.Printf("[%s] 重新订阅深度失败: %v", symbol, err)
+	}
+}
+
+// Ticks 返回指定交易对的行情通道，每次调用都会注册一个新的接收者
+func (f *MarketFeed) Ticks(symbol string) <-chan *types.Tick {
+	ch := make(chan *types.Tick, 256)
+	f.mu.Lock()
+	f.subscribers[symbol] = append(f.subscribers[symbol], ch)
+	f.mu.Unlock()
+	return ch
+}
+
+// StopTicks 注销Ticks返回的行情通道，引擎停止后不再向其分发
+func (f *MarketFeed) StopTicks(symbol string, ch <-chan *types.Tick) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	subs := f.subscribers[symbol]
+	for i, c := range subs {
+		if c == ch {
+			f.subscribers[symbol] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(f.subscribers[symbol]) == 0 {
+		delete(f.subscribers, symbol)
+	}
+}
+
+// publish 非阻塞地分发行情，接收者处理不及时则丢弃
+func (f *MarketFeed) publish(tick *types.Tick) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, ch := range f.subscribers[tick.Symbol] {
+		select {
+		case ch <- tick:
+		default:
+			log.Printf("[%s] 行情通道已满，丢弃 %s 数据", tick.Symbol, tick.Source)
+		}
+	}
+}
+
+func (f *MarketFeed) handleMessage(msg *WSMessage) {
+	switch {
+	case msg.Arg.Channel == "tickers":
+		var data []struct {
+			InstId string `json:"instId"`
+			Last   string `json:"last"`
+			LastSz string `json:"lastSz"`
+			Ts     string `json:"ts"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			log.Printf("[WS] 解析tickers数据失败: %v", err)
+			return
+		}
+		for _, d := range data {
+			f.publish(newTick(d.InstId, d.Last, d.LastSz, d.Ts, msg.Arg.Channel))
+		}
+
+	case msg.Arg.Channel == "trades":
+		var data []struct {
+			InstId string `json:"instId"`
+			Px     string `json:"px"`
+			Sz     string `json:"sz"`
+			Ts     string `json:"ts"`
+		}
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			log.Printf("[WS] 解析trades数据失败: %v", err)
+			return
+		}
+		for _, d := range data {
+			f.publish(newTick(d.InstId, d.Px, d.Sz, d.Ts, msg.Arg.Channel))
+		}
+
+	case msg.Arg.Channel == "books":
+		f.handleBooks(msg)
+
+	case strings.HasPrefix(msg.Arg.Channel, "candle"):
+		var data [][]string
+		if err := json.Unmarshal(msg.Data, &data); err != nil {
+			log.Printf("[WS] 解析K线数据失败: %v", err)
+			return
+		}
+		for _, d := range data {
+			if len(d) >= 6 {
+				f.publish(newTick(msg.Arg.InstId, d[4], d[5], d[0], msg.Arg.Channel))
+			}
+		}
+	}
+}
+
+func (f *MarketFeed) handleBooks(msg *WSMessage) {
+	f.booksMu.RLock()
+	book, ok := f.books[msg.Arg.InstId]
+	f.booksMu.RUnlock()
+	if !ok {
+		return
+	}
+
+	var data []struct {
+		Asks      [][]string `json:"asks"`
+		Bids      [][]string `json:"bids"`
+		Checksum  int32      `json:"checksum"`
+		SeqId     int64      `json:"seqId"`
+		PrevSeqId int64      `json:"prevSeqId"`
+	}
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		log.Printf("[WS] 解析深度数据失败: %v", err)
+		return
+	}
+
+	for _, d := range data {
+		var err error
+		if msg.Action == "snapshot" {
+			err = book.applySnapshot(d.Bids, d.Asks, d.SeqId, d.Checksum)
+		} else {
+			err = book.applyUpdate(d.Bids, d.Asks, d.SeqId, d.PrevSeqId, d.Checksum)
+		}
+		if err != nil {
+			log.Printf("[%s] %v，重新订阅深度", msg.Arg.InstId, err)
+			go f.resyncBook(msg.Arg.InstId)
+			return
+		}
+	}
+}
+
+// newTick 将推送中的字符串字段转换为Tick，ts为毫秒时间戳
+func newTick(symbol, price, volume, ts, source string) *types.Tick {
+	px, _ := strconv.ParseFloat(price, 64)
+	vol, _ := strconv.ParseFloat(volume, 64)
+	ms, _ := strconv.ParseInt(ts, 10, 64)
+	return &types.Tick{
+		Symbol:    symbol,
+		Price:     px,
+		Volume:    vol,
+		Timestamp: ms / 1000,
+		Source:    source,
+	}
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"okxauto/internal/types"
+)
+
+// wsStandIn 本地WebSocket服务，记录客户端的订阅请求，可主动推送消息或断开连接
+type wsStandIn struct {
+	srv      *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu    sync.Mutex
+	conn  *websocket.Conn
+	conns int
+
+	subs chan WSArg // 收到的订阅参数
+}
+
+func newWSStandIn(t *testing.T) *wsStandIn {
+	s := &wsStandIn{subs: make(chan WSArg, 16)}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.srv.Close)
+	return s
+}
+
+func (s *wsStandIn) url() string {
+	return "ws" + strings.TrimPrefix(s.srv.URL, "http")
+}
+
+func (s *wsStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	s.conn = conn
+	s.conns++
+	s.mu.Unlock()
+
+	for {
+		var req struct {
+			Op   string  `json:"op"`
+			Args []WSArg `json:"args"`
+		}
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		if req.Op == "subscribe" {
+			for _, arg := range req.Args {
+				s.subs <- arg
+			}
+		}
+	}
+}
+
+// push 向当前连接发送一条文本消息
+func (s *wsStandIn) push(t *testing.T, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+		t.Fatalf("推送消息失败: %v", err)
+	}
+}
+
+// drop 断开当前连接
+func (s *wsStandIn) drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conn.Close()
+}
+
+func (s *wsStandIn) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns
+}
+
+func waitSub(t *testing.T, s *wsStandIn) WSArg {
+	t.Helper()
+	select {
+	case arg := <-s.subs:
+		return arg
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待订阅请求超时")
+	}
+	return WSArg{}
+}
+
+func waitTick(t *testing.T, ticks <-chan *types.Tick) *types.Tick {
+	t.Helper()
+	select {
+	case tick := <-ticks:
+		return tick
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待行情推送超时")
+	}
+	return nil
+}
+
+// newTestFeed 启动连接到本地服务的行情推送，等待连接建立后返回
+func newTestFeed(t *testing.T, s *wsStandIn) *MarketFeed {
+	f := NewMarketFeed(s.url(), "")
+	f.public.dialer.Proxy = nil
+	f.Start()
+	t.Cleanup(f.Stop)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for !f.public.Connected() {
+		if time.Now().After(deadline) {
+			t.Fatal("等待连接超时")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return f
+}
+
+const tickerPush = `{"arg":{"channel":"tickers","instId":"BTC-USDT-SWAP"},"data":[{"instId":"BTC-USDT-SWAP","last":"%s","lastSz":"0.5","ts":"1700000000123"}]}`
+
+func TestMarketFeedTickers(t *testing.T) {
+	s := newWSStandIn(t)
+	f := newTestFeed(t, s)
+	ticks := f.Ticks("BTC-USDT-SWAP")
+	if err := f.SubscribeTickers("BTC-USDT-SWAP"); err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+
+	if arg := waitSub(t, s); arg.Channel != "tickers" || arg.InstId != "BTC-USDT-SWAP" {
+		t.Fatalf("订阅参数 = %+v", arg)
+	}
+	s.push(t, `{"event":"subscribe","arg":{"channel":"tickers","instId":"BTC-USDT-SWAP"}}`)
+	s.push(t, strings.Replace(tickerPush, "%s", "43210.5", 1))
+
+	tick := waitTick(t, ticks)
+	if tick.Symbol != "BTC-USDT-SWAP" || tick.Price != 43210.5 || tick.Volume != 0.5 ||
+		tick.Timestamp != 1700000000 || tick.Source != "tickers" {
+		t.Fatalf("解析结果 = %+v", tick)
+	}
+}
+
+func TestWSClientReconnectResubscribes(t *testing.T) {
+	s := newWSStandIn(t)
+	f := newTestFeed(t, s)
+	ticks := f.Ticks("BTC-USDT-SWAP")
+	f.SubscribeTickers("BTC-USDT-SWAP")
+	f.SubscribeTrades("BTC-USDT-SWAP")
+
+	waitSub(t, s)
+	waitSub(t, s)
+
+	s.drop()
+
+	resubscribed := map[string]bool{}
+	for len(resubscribed) < 2 {
+		arg := waitSub(t, s)
+		if arg.InstId != "BTC-USDT-SWAP" {
+			t.Fatalf("恢复订阅参数 = %+v", arg)
+		}
+		resubscribed[arg.Channel] = true
+	}
+	if !resubscribed["tickers"] || !resubscribed["trades"] {
+		t.Fatalf("重连后恢复的订阅 = %v", resubscribed)
+	}
+	if n := s.connections(); n != 2 {
+		t.Fatalf("连接次数 = %d, 期望 2", n)
+	}
+
+	s.push(t, strings.Replace(tickerPush, "%s", "43000", 1))
+	if tick := waitTick(t, ticks); tick.Price != 43000 {
+		t.Fatalf("重连后行情价格 = %v", tick.Price)
+	}
+}
+
+func TestMarketFeedStopTicks(t *testing.T) {
+	f := NewMarketFeed("ws://127.0.0.1:0", "")
+	a := f.Ticks("BTC-USDT-SWAP")
+	b := f.Ticks("BTC-USDT-SWAP")
+	f.StopTicks("BTC-USDT-SWAP", a)
+
+	f.publish(&types.Tick{Symbol: "BTC-USDT-SWAP", Price: 1})
+	select {
+	case <-a:
+		t.Fatal("已注销的通道不应再收到行情")
+	default:
+	}
+	if tick := waitTick(t, b); tick.Price != 1 {
+		t.Fatalf("行情价格 = %v", tick.Price)
+	}
+
+	f.StopTicks("BTC-USDT-SWAP", b)
+	if _, ok := f.subscribers["BTC-USDT-SWAP"]; ok {
+		t.Fatal("全部注销后应删除该交易对的订阅者")
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// MaxSize 当前账户和杠杆下最大可下单数量，合约为张数，现货为币的数量
+type MaxSize struct {
+	InstId  string
+	Ccy     string
+	MaxBuy  float64
+	MaxSell float64
+}
+
+// Max 返回指定方向的最大可下单数量
+func (m *MaxSize) Max(side OrderSide) float64 {
+	if side == Sell {
+		return m.MaxSell
+	}
+	return m.MaxBuy
+}
+
+// MaxAvailSize 最大可用余额/保证金，合约为保证金币种数量，现货买入为计价币、卖出为交易币
+type MaxAvailSize struct {
+	InstId    string
+	AvailBuy  float64
+	AvailSell float64
+}
+
+// Avail 返回指定方向的最大可用数量
+func (m *MaxAvailSize) Avail(side OrderSide) float64 {
+	if side == Sell {
+		return m.AvailSell
+	}
+	return m.AvailBuy
+}
+
+// GetMaxSize 查询最大可下单数量，已考虑手续费、档位和已有持仓；lever为0时使用当前杠杆，px为0时按最新价计算
+func (c *OKXClient) GetMaxSize(ctx context.Context, instId, tdMode string, lever int, px float64) (*MaxSize, error) {
+	path := fmt.Sprintf("/api/v5/account/max-size?instId=%s&tdMode=%s", instId, tdMode)
+	if lever > 0 {
+		path += fmt.Sprintf("&leverage=%d", lever)
+	}
+	if px > 0 {
+		path += "&px=" + strconv.FormatFloat(px, 'f', -1, 64)
+	}
+
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			InstId  string `json:"instId"`
+			Ccy     string `json:"ccy"`
+			MaxBuy  string `json:"maxBuy"`
+			MaxSell string `json:"maxSell"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析最大可下单数量失败: %v", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("未获取到最大可下单数量: %s", instId)
+	}
+
+	d := result.Data[0]
+	return &MaxSize{
+		InstId:  d.InstId,
+		Ccy:     d.Ccy,
+		MaxBuy:  parseFloat(d.MaxBuy),
+		MaxSell: parseFloat(d.MaxSell),
+	}, nil
+}
+
+// GetMaxAvailSize 查询最大可用余额/保证金，reduceOnly为true时返回只减仓方向的可用数量
+func (c *OKXClient) GetMaxAvailSize(ctx context.Context, instId, tdMode string, reduceOnly bool) (*MaxAvailSize, error) {
+	path := fmt.Sprintf("/api/v5/account/max-avail-size?instId=%s&tdMode=%s", instId, tdMode)
+	if reduceOnly {
+		path += "&reduceOnly=true"
+	}
+
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []struct {
+			InstId    string `json:"instId"`
+			AvailBuy  string `json:"availBuy"`
+			AvailSell string `json:"availSell"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析最大可用数量失败: %v", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("未获取到最大可用数量: %s", instId)
+	}
+
+	d := result.Data[0]
+	return &MaxAvailSize{
+		InstId:    d.InstId,
+		AvailBuy:  parseFloat(d.AvailBuy),
+		AvailSell: parseFloat(d.AvailSell),
+	}, nil
+}
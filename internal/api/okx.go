@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
@@ -11,11 +12,11 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 	
 	"okxauto/internal/models"
-	"okxauto/internal/utils"
 )
 
 type OKXClient struct {
@@ -25,8 +26,11 @@ type OKXClient struct {
 	baseURL     string
 	client      *http.Client
 	isSimulated bool // 新增字段，标记是否是模拟盘
-	lastRequest time.Time    // 添加上次请求时间
-	mu          sync.Mutex   // 添加互斥锁以保证并发安全
+	limiter     *rateLimiter // 按接口分组限速
+	clock       clock        // 服务器时间偏移，用于签名时间戳
+
+	instruments map[string]*Instrument // 产品信息缓存
+	instMu      sync.RWMutex
 }
 
 func NewOKXClient(apiKey, secretKey, passphrase string, mode string) *OKXClient {
@@ -49,12 +53,19 @@ func NewOKXClient(apiKey, secretKey, passphrase string, mode string) *OKXClient
 		baseURL:    baseURL,
 		client:     &http.Client{Timeout: 10 * time.Second},
 		isSimulated: isSimulated,
-		lastRequest: time.Now(),  // 初始化上次请求时间
+		limiter:     newRateLimiter(systemClock{}),
+		instruments: make(map[string]*Instrument),
 	}
 
 	return client
 }
 
+// SetBaseURL 修改REST接口地址，用于地区站点或本地模拟服务器
+func (c *OKXClient) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+	log.Printf("REST接口地址: %s", c.baseURL)
+}
+
 // 生成签名
 func (c *OKXClient) sign(timestamp, method, requestPath string, body []byte) string {
 	message := timestamp + method + requestPath
@@ -67,16 +78,29 @@ func (c *OKXClient) sign(timestamp, method, requestPath string, body []byte) str
 	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
 }
 
+// requestRetryConfig 单次请求的重试次数，等待时间由错误分类的退避策略决定
+var requestRetryConfig = RetryConfig{MaxRetries: 3}
+
 // 发送请求
-func (c *OKXClient) sendRequest(method, path string, body interface{}) ([]byte, error) {
-	return c.sendRequestWithRetry(method, path, body, utils.DefaultRetryConfig)
+func (c *OKXClient) sendRequest(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	return c.sendRequestWithRetry(ctx, method, path, body, requestRetryConfig)
 }
 
-func (c *OKXClient) sendRequestWithRetry(method, path string, body interface{}, retryConfig utils.RetryConfig) ([]byte, error) {
+func (c *OKXClient) sendRequestWithRetry(ctx context.Context, method, path string, body interface{}, retryConfig RetryConfig) ([]byte, error) {
 	var resp []byte
-	err := utils.RetryOperation(func() error {
+	resynced := false
+	err := RetryOperation(ctx, func() error {
 		var sendErr error
-		resp, sendErr = c.doRequest(method, path, body)
+		resp, sendErr = c.doRequest(ctx, method, path, body)
+		// 时间戳过期时重新校时后重发一次，时间戳在重发时重新生成
+		if isTimestampExpired(sendErr) && !resynced {
+			resynced = true
+			if syncErr := c.resyncClock(ctx); syncErr != nil {
+				log.Printf("重新校时失败: %v", syncErr)
+				return sendErr
+			}
+			resp, sendErr = c.doRequest(ctx, method, path, body)
+		}
 		return sendErr
 	}, retryConfig)
 	
@@ -84,14 +108,10 @@ func (c *OKXClient) sendRequestWithRetry(method, path string, body interface{},
 }
 
 // doRequest 执行实际的HTTP请求
-func (c *OKXClient) doRequest(method, path string, body interface{}) ([]byte, error) {
-	c.mu.Lock()
-	now := time.Now()
-	if diff := now.Sub(c.lastRequest); diff < time.Second/6 {  // 限制为6次/秒
-		time.Sleep(time.Second/6 - diff)
+func (c *OKXClient) doRequest(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	if err := c.limiter.wait(ctx, method, path); err != nil {
+		return nil, err
 	}
-	c.lastRequest = now
-	c.mu.Unlock()
 
 	var bodyJSON []byte
 	var err error
@@ -103,11 +123,11 @@ func (c *OKXClient) doRequest(method, path string, body interface{}) ([]byte, er
 		}
 	}
 
-	timestamp := time.Now().UTC().Format("2006-01-02T15:04:05.000Z")
+	timestamp := c.clock.now().UTC().Format("2006-01-02T15:04:05.000Z")
 	sign := c.sign(timestamp, method, path, bodyJSON)
 
 	url := c.baseURL + path
-	req, err := http.NewRequest(method, url, bytes.NewReader(bodyJSON))
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(bodyJSON))
 	if err != nil {
 		return nil, err
 	}
@@ -133,13 +153,23 @@ func (c *OKXClient) doRequest(method, path string, body interface{}) ([]byte, er
 		return nil, err
 	}
 
-	// 检查API响应
+	// 检查API响应，交易类接口返回1(全部失败)或2(部分成功)时由调用方按每笔的sCode处理
 	var result struct {
-		Code string `json:"code"`
-		Msg  string `json:"msg"`
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, &APIError{HTTPStatus: resp.StatusCode, Path: path, Msg: string(respBody)}
+		}
+		return respBody, nil
 	}
-	if err := json.Unmarshal(respBody, &result); err == nil && result.Code != "0" {
-		return nil, fmt.Errorf("API错误: %s", string(respBody))
+	if result.Code != "0" {
+		if (result.Code == "1" || result.Code == "2") && len(result.Data) > 2 {
+			return respBody, nil
+		}
+		return nil, &APIError{HTTPStatus: resp.StatusCode, Path: path, Code: result.Code, Msg: result.Msg}
 	}
 
 	return respBody, nil
@@ -150,16 +180,18 @@ type PlaceOrderRequest struct {
 	InstId  string    `json:"instId"`          // 产品ID
 	TdMode  string    `json:"tdMode"`          // 交易模式：cash/cross/isolated
 	Side    OrderSide `json:"side"`            // 订单方向
-	PosSide string    `json:"posSide"`         // 持仓方向：long/short
+	PosSide string    `json:"posSide,omitempty"` // 持仓方向：long/short，买卖模式下不填
 	OrdType OrderType `json:"ordType"`         // 订单类型：market/limit
 	Sz      string    `json:"sz"`              // 委托数量
 	Px      string    `json:"px,omitempty"`    // 委托价格，市价单不需要
 	Lever   string    `json:"lever,omitempty"` // 杠杆倍数
 	ClOrdId string    `json:"clOrdId,omitempty"` // 客户自定义订单ID
+	ReduceOnly bool   `json:"reduceOnly,omitempty"` // 只减仓，买卖模式下平仓使用
+	TgtCcy  string    `json:"tgtCcy,omitempty"` // 现货市价单数量单位：base_ccy/quote_ccy
 }
 
 // 修改下单方法
-func (c *OKXClient) PlaceOrder(req *PlaceOrderRequest) (*OrderResponse, error) {
+func (c *OKXClient) PlaceOrder(ctx context.Context, req *PlaceOrderRequest) (*OrderResponse, error) {
 	// 生成一个简单的数字ID
 	if req.ClOrdId == "" {
 		// 使用Unix时间戳的后12位作为ID
@@ -172,16 +204,11 @@ func (c *OKXClient) PlaceOrder(req *PlaceOrderRequest) (*OrderResponse, error) {
 		req.TdMode = "isolated"
 	}
 
-	// 修改合约张数格式
-	if sz, err := strconv.ParseFloat(req.Sz, 64); err == nil {
-		req.Sz = fmt.Sprintf("%.0f", sz) // 确保是整数
-	}
-
 	// 打印完整请求内容用于调试
 	reqJSON, _ := json.MarshalIndent(req, "", "  ")
 	log.Printf("发送下单请求: %s", string(reqJSON))
 
-	resp, err := c.sendRequest("POST", "/api/v5/trade/order", req)
+	resp, err := c.sendRequest(ctx, "POST", "/api/v5/trade/order", req)
 	if err != nil {
 		return nil, err
 	}
@@ -201,7 +228,21 @@ func (c *OKXClient) PlaceOrder(req *PlaceOrderRequest) (*OrderResponse, error) {
 
 	if result.Code != "0" {
 		if len(result.Data) > 0 && result.Data[0].SCode != "" {
-			return nil, fmt.Errorf("下单失败: %s (错误码: %s)", result.Data[0].SMsg, result.Data[0].SCode)
+			apiErr := &APIError{
+				HTTPStatus: http.StatusOK,
+				Path:       "/api/v5/trade/order",
+				Code:       result.Code,
+				Msg:        result.Msg,
+				SCode:      result.Data[0].SCode,
+				SMsg:       result.Data[0].SMsg,
+			}
+			// 超时重发的请求可能已经下单成功
+			if apiErr.Class() == ClassDuplicate {
+				if resp := c.recoverDuplicate(ctx, req); resp != nil {
+					return resp, nil
+				}
+			}
+			return nil, fmt.Errorf("下单失败: %w", apiErr)
 		}
 		return nil, fmt.Errorf("下单失败: %s (code=%s)", result.Msg, result.Code)
 	}
@@ -222,98 +263,30 @@ type Balance struct {
 }
 
 // GetBalances 获取账户所有货币余额
-func (c *OKXClient) GetBalances() ([]*Balance, error) {
-	// 使用正确的API路径获取账户余额
-	resp, err := c.sendRequest("GET", "/api/v5/account/balance", nil)
+func (c *OKXClient) GetBalances(ctx context.Context) ([]*Balance, error) {
+	summary, err := c.GetAccountSummary(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// 打印原始响应用于调试
-	log.Printf("获取余额响应: %s", string(resp))
-
-	var result struct {
-		Code string `json:"code"`
-		Msg  string `json:"msg"`
-		Data []struct {
-			AdjEq       string `json:"adjEq"`       // 调整后权益
-			Details     []struct {
-				AvailBal    string `json:"availBal"`    // 可用余额
-				AvailEq     string `json:"availEq"`     // 可用权益
-				CashBal     string `json:"cashBal"`     // 现金余额
-				Ccy         string `json:"ccy"`         // 币种
-				CrossLiab   string `json:"crossLiab"`   // 全仓负债
-				DisEq       string `json:"disEq"`       // 美金层面币种折算权益
-				Eq          string `json:"eq"`          // 币种总权益
-				FrozenBal   string `json:"frozenBal"`   // 冻结余额
-				Interest    string `json:"interest"`    // 计息
-				IsoEq       string `json:"isoEq"`       // 逐仓权益
-				IsoLiab     string `json:"isoLiab"`     // 逐仓负债
-				IsoUpl      string `json:"isoUpl"`      // 逐仓未实现盈亏
-				Liab        string `json:"liab"`        // 负债
-				MaxLoan     string `json:"maxLoan"`     // 最大可借
-				MgnRatio    string `json:"mgnRatio"`    // 保证金率
-				NotionalLever string `json:"notionalLever"` // 杠杆倍数
-				OrdFrozen   string `json:"ordFrozen"`   // 委托冻结数量
-				Twap        string `json:"twap"`        // 当前负债币种触发系统自动换币的风险
-				Upl         string `json:"upl"`         // 未实现盈亏
-				UplLiab     string `json:"uplLiab"`     // 未实现负债
-				StgyEq      string `json:"stgyEq"`      // 策略权益
-			} `json:"details"`
-			Imr         string `json:"imr"`         // 初始保证金
-			IsoEq       string `json:"isoEq"`       // 逐仓仓位权益
-			MgnRatio    string `json:"mgnRatio"`    // 保证金率
-			Mmr         string `json:"mmr"`         // 维持保证金
-			NotionalUsd string `json:"notionalUsd"` // 以美金价值为单位的持仓数量
-			OrdFroz     string `json:"ordFroz"`     // 订单冻结保证金
-			TotalEq     string `json:"totalEq"`     // 美金层面权益
-			UplLiab     string `json:"uplLiab"`     // 未实现亏损
-		} `json:"data"`
-	}
-
-	if err := json.Unmarshal(resp, &result); err != nil {
-		return nil, fmt.Errorf("解析余额响应失败: %v, 响应内容: %s", err, string(resp))
-	}
-
-	if result.Code != "0" {
-		return nil, fmt.Errorf("获取余额失败: %s", result.Msg)
-	}
-
-	if len(result.Data) == 0 {
-		return nil, fmt.Errorf("账户余额为空")
-	}
-
 	// 打印账户总权益
-	if len(result.Data) > 0 {
-		log.Printf("账户总权益: %s USDT", result.Data[0].TotalEq)
-	}
+	log.Printf("账户总权益: %.2f USDT, 占用保证金: %.2f, 维持保证金: %.2f",
+		summary.TotalEq, summary.Imr, summary.Mmr)
 
 	// 只返回有余额的币种
-	balances := make([]*Balance, 0)
-	for _, data := range result.Data {
-		for _, detail := range data.Details {
-			// 使用eq(总权益)来判断是否有余额
-			eq, _ := strconv.ParseFloat(detail.Eq, 64)
-			if eq > 0 {
-				balances = append(balances, &Balance{
-					Currency:  detail.Ccy,
-					Balance:   detail.Eq,         // 使用总权益作为余额
-					Available: detail.AvailEq,    // 使用可用权益作为可用余额
-					Frozen:    detail.FrozenBal,  // 冻结余额
-				})
-				// 打印详细的余额信息
-				log.Printf("币种详情 - %s: 总权益=%s, 可用=%s, 冻结=%s, 现金=%s", 
-					detail.Ccy, detail.Eq, detail.AvailEq, detail.FrozenBal, detail.CashBal)
-			}
+	for _, detail := range summary.Details {
+		if detail.Eq > 0 {
+			// 打印详细的余额信息
+			log.Printf("币种详情 - %s: 总权益=%g, 可用=%g, 冻结=%g, 现金=%g",
+				detail.Ccy, detail.Eq, detail.AvailEq, detail.FrozenBal, detail.CashBal)
 		}
 	}
-
-	return balances, nil
+	return summary.Balances(), nil
 }
 
 // GetBalance 获取指定货币余额
-func (c *OKXClient) GetBalance(currency string) (*Balance, error) {
-	balances, err := c.GetBalances()
+func (c *OKXClient) GetBalance(ctx context.Context, currency string) (*Balance, error) {
+	balances, err := c.GetBalances(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -328,14 +301,37 @@ func (c *OKXClient) GetBalance(currency string) (*Balance, error) {
 }
 
 // 获取K线数据
-func (c *OKXClient) GetKlines(symbol string, period string, limit int) ([]Candle, error) {
+func (c *OKXClient) GetKlines(ctx context.Context, symbol string, period string, limit int) ([]Candle, error) {
 	path := fmt.Sprintf("/api/v5/market/candles?instId=%s&bar=%s&limit=%d", symbol, period, limit)
 
-	resp, err := c.sendRequest("GET", path, nil)
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCandles(resp)
+}
+
+// GetHistoryCandles 分页获取历史K线，按时间倒序返回。after和before为毫秒时间戳，
+// 分别返回早于after、晚于before的K线，为0时不限制；limit最大100
+func (c *OKXClient) GetHistoryCandles(ctx context.Context, instId, bar string, after, before int64, limit int) ([]Candle, error) {
+	path := fmt.Sprintf("/api/v5/market/history-candles?instId=%s&bar=%s&limit=%d", instId, bar, limit)
+	if after > 0 {
+		path += fmt.Sprintf("&after=%d", after)
+	}
+	if before > 0 {
+		path += fmt.Sprintf("&before=%d", before)
+	}
+
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
+	return parseCandles(resp)
+}
 
+// parseCandles 解析K线数组响应 [ts,o,h,l,c,vol,volCcy,volCcyQuote,confirm]
+func parseCandles(resp []byte) ([]Candle, error) {
 	var result struct {
 		Code string     `json:"code"`
 		Data [][]string `json:"data"`
@@ -356,6 +352,9 @@ func (c *OKXClient) GetKlines(symbol string, period string, limit int) ([]Candle
 				Close:     item[4],
 				Volume:    item[5],
 			}
+			if len(item) >= 9 {
+				candle.Confirm = item[8]
+			}
 			candles = append(candles, candle)
 		}
 	}
@@ -364,7 +363,7 @@ func (c *OKXClient) GetKlines(symbol string, period string, limit int) ([]Candle
 }
 
 // 取消订单
-func (c *OKXClient) CancelOrder(symbol, orderId string) error {
+func (c *OKXClient) CancelOrder(ctx context.Context, symbol, orderId string) error {
 	req := struct {
 		InstId  string `json:"instId"`
 		OrderId string `json:"ordId"`
@@ -373,20 +372,26 @@ func (c *OKXClient) CancelOrder(symbol, orderId string) error {
 		OrderId: orderId,
 	}
 
-	resp, err := c.sendRequest("POST", "/api/v5/trade/cancel-order", req)
+	resp, err := c.sendRequest(ctx, "POST", "/api/v5/trade/cancel-order", req)
 	if err != nil {
 		return err
 	}
 
 	var result struct {
-		Code string `json:"code"`
-		Msg  string `json:"msg"`
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data []OrderResponse `json:"data"`
 	}
 
 	if err := json.Unmarshal(resp, &result); err != nil {
 		return err
 	}
 
+	if len(result.Data) > 0 {
+		if err := result.Data[0].Err(); err != nil {
+			return fmt.Errorf("取消订单失败: %w", err)
+		}
+	}
 	if result.Code != "0" {
 		return fmt.Errorf("取消订单失败: %s", result.Msg)
 	}
@@ -395,7 +400,7 @@ func (c *OKXClient) CancelOrder(symbol, orderId string) error {
 }
 
 // SetLeverage 设置杠杆倍数
-func (c *OKXClient) SetLeverage(instId string, lever string, mgnMode string, posSide string) error {
+func (c *OKXClient) SetLeverage(ctx context.Context, instId string, lever string, mgnMode string, posSide string) error {
 	req := struct {
 		InstId  string `json:"instId"`
 		Lever   string `json:"lever"`
@@ -408,7 +413,7 @@ func (c *OKXClient) SetLeverage(instId string, lever string, mgnMode string, pos
 		PosSide: posSide,
 	}
 
-	resp, err := c.sendRequest("POST", "/api/v5/account/set-leverage", req)
+	resp, err := c.sendRequest(ctx, "POST", "/api/v5/account/set-leverage", req)
 	if err != nil {
 		return err
 	}
@@ -430,9 +435,9 @@ func (c *OKXClient) SetLeverage(instId string, lever string, mgnMode string, pos
 }
 
 // GetPositions 方法返回 models.Position
-func (c *OKXClient) GetPositions(instId string) ([]*models.Position, error) {
+func (c *OKXClient) GetPositions(ctx context.Context, instId string) ([]*models.Position, error) {
 	path := fmt.Sprintf("/api/v5/account/positions?instId=%s", instId)
-	resp, err := c.sendRequest("GET", path, nil)
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -485,8 +490,8 @@ func (c *OKXClient) GetPositions(instId string) ([]*models.Position, error) {
 }
 
 // AddMargin 追加或减少保证金
-func (c *OKXClient) AddMargin(params map[string]string) (map[string]interface{}, error) {
-	resp, err := c.sendRequest("POST", "/api/v5/account/position/margin-balance", params)
+func (c *OKXClient) AddMargin(ctx context.Context, params map[string]string) (map[string]interface{}, error) {
+	resp, err := c.sendRequest(ctx, "POST", "/api/v5/account/position/margin-balance", params)
 	if err != nil {
 		return nil, err
 	}
@@ -495,6 +500,9 @@ func (c *OKXClient) AddMargin(params map[string]string) (map[string]interface{},
 	if err := json.Unmarshal(resp, &result); err != nil {
 		return nil, fmt.Errorf("解析响应失败: %v", err)
 	}
+	if code, _ := result["code"].(string); code != "0" {
+		return nil, fmt.Errorf("调整保证金失败: %s", string(resp))
+	}
 
 	return result, nil
 }
@@ -0,0 +1,243 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 校验和使用的档位数
+const checksumLevels = 25
+
+// errBookOutOfSync 增量数据无法应用，需要重新订阅获取快照
+var errBookOutOfSync = errors.New("深度数据不同步")
+
+// BookLevel 深度档位，Size为下单数量单位(合约为张)
+type BookLevel struct {
+	Price float64
+	Size  float64
+
+	px string // 原始字符串，计算校验和使用
+	sz string
+}
+
+// FillEstimate 按当前深度估算市价单的成交结果
+type FillEstimate struct {
+	AvgPrice   float64 // 成交均价
+	WorstPrice float64 // 吃到的最差价格
+	BestPrice  float64 // 下单时的最优价格
+	Filled     float64 // 深度可满足的数量
+	Slippage   float64 // 成交均价相对最优价格的偏离比例，始终为非负数
+}
+
+// OrderBook 本地维护的深度，由快照初始化并按增量更新，每次更新后用CRC32校验和验证
+type OrderBook struct {
+	InstId string
+
+	mu      sync.RWMutex
+	bids    []BookLevel // 价格从高到低
+	asks    []BookLevel // 价格从低到高
+	seqId   int64
+	updated time.Time
+	ready   bool
+}
+
+func newOrderBook(instId string) *OrderBook {
+	return &OrderBook{InstId: instId}
+}
+
+// Ready 是否已收到快照且校验通过
+func (b *OrderBook) Ready() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.ready
+}
+
+// UpdatedAt 最近一次更新的时间
+func (b *OrderBook) UpdatedAt() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.updated
+}
+
+// BestBid 返回买一档
+func (b *OrderBook) BestBid() (BookLevel, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if !b.ready || len(b.bids) == 0 {
+		return BookLevel{}, false
+	}
+	return b.bids[0], true
+}
+
+// BestAsk 返回卖一档
+func (b *OrderBook) BestAsk() (BookLevel, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if !b.ready || len(b.asks) == 0 {
+		return BookLevel{}, false
+	}
+	return b.asks[0], true
+}
+
+// Depth 返回买卖各前n档的副本
+func (b *OrderBook) Depth(n int) (bids, asks []BookLevel) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if !b.ready {
+		return nil, nil
+	}
+	bids = append([]BookLevel(nil), b.bids[:min(n, len(b.bids))]...)
+	asks = append([]BookLevel(nil), b.asks[:min(n, len(b.asks))]...)
+	return bids, asks
+}
+
+// EstimateFill 估算市价买入(吃卖盘)或卖出(吃买盘)size数量的成交均价和滑点，深度不足时返回错误和已估算的部分
+func (b *OrderBook) EstimateFill(side OrderSide, size float64) (*FillEstimate, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if !b.ready {
+		return nil, fmt.Errorf("[%s] 深度尚未就绪", b.InstId)
+	}
+	levels := b.asks
+	if side == Sell {
+		levels = b.bids
+	}
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("[%s] 深度为空", b.InstId)
+	}
+
+	est := &FillEstimate{BestPrice: levels[0].Price}
+	var value float64
+	for _, level := range levels {
+		take := min(level.Size, size-est.Filled)
+		value += take * level.Price
+		est.Filled += take
+		est.WorstPrice = level.Price
+		if est.Filled >= size {
+			break
+		}
+	}
+	est.AvgPrice = value / est.Filled
+	est.Slippage = (est.AvgPrice - est.BestPrice) / est.BestPrice
+	if side == Sell {
+		est.Slippage = -est.Slippage
+	}
+
+	if est.Filled < size {
+		return est, fmt.Errorf("[%s] 深度不足: 需要 %g, 可成交 %g", b.InstId, size, est.Filled)
+	}
+	return est, nil
+}
+
+// reset 标记为未就绪，等待新的快照
+func (b *OrderBook) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ready = false
+	b.bids, b.asks = nil, nil
+}
+
+// applySnapshot 用快照替换全部深度
+func (b *OrderBook) applySnapshot(bids, asks [][]string, seqId int64, checksum int32) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bids = b.bids[:0]
+	b.asks = b.asks[:0]
+	for _, l := range bids {
+		b.bids = upsertLevel(b.bids, l, true)
+	}
+	for _, l := range asks {
+		b.asks = upsertLevel(b.asks, l, false)
+	}
+	return b.validate(seqId, checksum)
+}
+
+// applyUpdate 应用增量数据，序号不连续或校验和不一致时返回errBookOutOfSync
+func (b *OrderBook) applyUpdate(bids, asks [][]string, seqId, prevSeqId int64, checksum int32) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.ready {
+		return nil // 等待快照
+	}
+	if seqId != 0 && seqId <= b.seqId {
+		return nil // 快照之前的旧数据
+	}
+	if prevSeqId != 0 && prevSeqId != b.seqId {
+		b.ready = false
+		return fmt.Errorf("%w: prevSeqId=%d, 本地seqId=%d", errBookOutOfSync, prevSeqId, b.seqId)
+	}
+
+	for _, l := range bids {
+		b.bids = upsertLevel(b.bids, l, true)
+	}
+	for _, l := range asks {
+		b.asks = upsertLevel(b.asks, l, false)
+	}
+	return b.validate(seqId, checksum)
+}
+
+// validate 校验并记录序号，调用方需持有写锁
+func (b *OrderBook) validate(seqId int64, checksum int32) error {
+	if got := b.checksum(); got != checksum {
+		b.ready = false
+		return fmt.Errorf("%w: 校验和 %d, 本地 %d", errBookOutOfSync, checksum, got)
+	}
+	b.seqId = seqId
+	b.updated = time.Now()
+	b.ready = true
+	return nil
+}
+
+// checksum 按OKX规则计算前25档的CRC32: bidPx:bidSz:askPx:askSz:...，一侧不足时只拼接另一侧
+func (b *OrderBook) checksum() int32 {
+	parts := make([]string, 0, checksumLevels*4)
+	for i := 0; i < checksumLevels; i++ {
+		if i < len(b.bids) {
+			parts = append(parts, b.bids[i].px, b.bids[i].sz)
+		}
+		if i < len(b.asks) {
+			parts = append(parts, b.asks[i].px, b.asks[i].sz)
+		}
+	}
+	return int32(crc32.ChecksumIEEE([]byte(strings.Join(parts, ":"))))
+}
+
+// upsertLevel 插入、更新或删除(数量为0)档位，desc为true时按价格从高到低排列
+func upsertLevel(levels []BookLevel, raw []string, desc bool) []BookLevel {
+	if len(raw) < 2 {
+		return levels
+	}
+	px, _ := strconv.ParseFloat(raw[0], 64)
+	sz, _ := strconv.ParseFloat(raw[1], 64)
+
+	i := sort.Search(len(levels), func(i int) bool {
+		if desc {
+			return levels[i].Price <= px
+		}
+		return levels[i].Price >= px
+	})
+	exists := i < len(levels) && levels[i].Price == px
+
+	switch {
+	case sz == 0 && exists:
+		return append(levels[:i], levels[i+1:]...)
+	case sz == 0:
+		return levels
+	case exists:
+		levels[i] = BookLevel{Price: px, Size: sz, px: raw[0], sz: raw[1]}
+		return levels
+	}
+	levels = append(levels, BookLevel{})
+	copy(levels[i+1:], levels[i:])
+	levels[i] = BookLevel{Price: px, Size: sz, px: raw[0], sz: raw[1]}
+	return levels
+}
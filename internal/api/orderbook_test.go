@@ -0,0 +1,136 @@
+package api
+
+import (
+	"errors"
+	"hash/crc32"
+	"math"
+	"testing"
+)
+
+func crc(s string) int32 {
+	return int32(crc32.ChecksumIEEE([]byte(s)))
+}
+
+// snapshotBook 返回按快照初始化的深度：买 100/99，卖 101/102
+func snapshotBook(t *testing.T) *OrderBook {
+	t.Helper()
+	b := newOrderBook("BTC-USDT-SWAP")
+	bids := [][]string{{"100", "2", "0", "1"}, {"99", "5", "0", "2"}}
+	asks := [][]string{{"101", "3", "0", "1"}, {"102", "4", "0", "1"}}
+	if err := b.applySnapshot(bids, asks, 10, crc("100:2:101:3:99:5:102:4")); err != nil {
+		t.Fatalf("应用快照失败: %v", err)
+	}
+	return b
+}
+
+func TestOrderBookChecksumInterleavesLevels(t *testing.T) {
+	b := snapshotBook(t)
+	if !b.Ready() {
+		t.Fatal("校验通过后应就绪")
+	}
+
+	// 一侧档位不足时只拼接另一侧
+	err := b.applyUpdate(nil, [][]string{{"103", "1", "0", "1"}}, 11, 10, crc("100:2:101:3:99:5:102:4:103:1"))
+	if err != nil {
+		t.Fatalf("应用增量失败: %v", err)
+	}
+	if bids, asks := b.Depth(5); len(bids) != 2 || len(asks) != 3 {
+		t.Fatalf("深度档位数 = %d/%d", len(bids), len(asks))
+	}
+}
+
+func TestOrderBookChecksumUsesRawStrings(t *testing.T) {
+	b := newOrderBook("BTC-USDT-SWAP")
+	// 校验和使用推送中的原始字符串，"100.50"不能被格式化为"100.5"
+	err := b.applySnapshot([][]string{{"100.50", "1.0"}}, [][]string{{"101.00", "2"}}, 1, crc("100.50:1.0:101.00:2"))
+	if err != nil {
+		t.Fatalf("应用快照失败: %v", err)
+	}
+}
+
+func TestOrderBookChecksumMismatch(t *testing.T) {
+	b := snapshotBook(t)
+	err := b.applyUpdate([][]string{{"100", "1"}}, nil, 11, 10, 12345)
+	if !errors.Is(err, errBookOutOfSync) {
+		t.Fatalf("校验和不一致应返回不同步错误, 实际: %v", err)
+	}
+	if b.Ready() {
+		t.Fatal("校验失败后应标记为未就绪")
+	}
+}
+
+func TestOrderBookUpdateAndDelete(t *testing.T) {
+	b := snapshotBook(t)
+	// 更新买一数量、删除卖一、插入新买二
+	bids := [][]string{{"100", "7"}, {"99.5", "1"}}
+	asks := [][]string{{"101", "0"}}
+	if err := b.applyUpdate(bids, asks, 11, 10, crc("100:7:102:4:99.5:1:99:5")); err != nil {
+		t.Fatalf("应用增量失败: %v", err)
+	}
+	bid, _ := b.BestBid()
+	ask, _ := b.BestAsk()
+	if bid.Price != 100 || bid.Size != 7 || ask.Price != 102 {
+		t.Fatalf("买一 %+v, 卖一 %+v", bid, ask)
+	}
+}
+
+func TestOrderBookSeqId(t *testing.T) {
+	b := snapshotBook(t)
+
+	// 快照之前的旧数据直接忽略
+	if err := b.applyUpdate([][]string{{"100", "9"}}, nil, 9, 8, 0); err != nil {
+		t.Fatalf("旧数据应被忽略: %v", err)
+	}
+	if bid, _ := b.BestBid(); bid.Size != 2 {
+		t.Fatalf("旧数据不应修改深度, 买一数量 = %v", bid.Size)
+	}
+
+	// prevSeqId与本地序号不连续
+	err := b.applyUpdate([][]string{{"100", "9"}}, nil, 13, 12, 0)
+	if !errors.Is(err, errBookOutOfSync) {
+		t.Fatalf("序号不连续应返回不同步错误, 实际: %v", err)
+	}
+	if b.Ready() {
+		t.Fatal("序号不连续后应标记为未就绪")
+	}
+
+	// 未就绪时等待快照，不应用增量
+	if err := b.applyUpdate([][]string{{"100", "9"}}, nil, 14, 13, 0); err != nil {
+		t.Fatalf("未就绪时应忽略增量: %v", err)
+	}
+}
+
+func TestOrderBookSeqIdHeartbeat(t *testing.T) {
+	b := snapshotBook(t)
+	// 无变化时推送的seqId与prevSeqId相同，校验和不变
+	if err := b.applyUpdate(nil, nil, 11, 10, crc("100:2:101:3:99:5:102:4")); err != nil {
+		t.Fatalf("应用无变化的增量失败: %v", err)
+	}
+	if err := b.applyUpdate([][]string{{"100", "3"}}, nil, 12, 11, crc("100:3:101:3:99:5:102:4")); err != nil {
+		t.Fatalf("后续增量应基于新序号: %v", err)
+	}
+}
+
+func TestOrderBookEstimateFill(t *testing.T) {
+	b := snapshotBook(t)
+
+	est, err := b.EstimateFill(Buy, 5)
+	if err != nil {
+		t.Fatalf("估算失败: %v", err)
+	}
+	// 3@101 + 2@102
+	if avg := (3*101.0 + 2*102.0) / 5; math.Abs(est.AvgPrice-avg) > 1e-9 || est.WorstPrice != 102 {
+		t.Fatalf("成交均价 %v, 最差价 %v", est.AvgPrice, est.WorstPrice)
+	}
+	if math.Abs(est.Slippage-(est.AvgPrice-101)/101) > 1e-12 {
+		t.Fatalf("滑点 = %v", est.Slippage)
+	}
+
+	est, err = b.EstimateFill(Sell, 10)
+	if err == nil || est.Filled != 7 {
+		t.Fatalf("深度不足时应返回错误和可成交数量, filled=%v err=%v", est.Filled, err)
+	}
+	if est.Slippage < 0 {
+		t.Fatalf("卖出滑点应为非负数: %v", est.Slippage)
+	}
+}
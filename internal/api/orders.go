@@ -0,0 +1,217 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// GetOrder 查询订单详情
+func (c *OKXClient) GetOrder(ctx context.Context, instId, ordId string) (*Order, error) {
+	path := fmt.Sprintf("/api/v5/trade/order?instId=%s&ordId=%s", instId, ordId)
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Code string   `json:"code"`
+		Msg  string   `json:"msg"`
+		Data []*Order `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析订单信息失败: %v", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("未找到订单: %s", ordId)
+	}
+
+	return result.Data[0], nil
+}
+
+// GetOrderByClOrdId 按客户自定义订单ID查询订单详情
+func (c *OKXClient) GetOrderByClOrdId(ctx context.Context, instId, clOrdId string) (*Order, error) {
+	path := fmt.Sprintf("/api/v5/trade/order?instId=%s&clOrdId=%s", instId, clOrdId)
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Data []*Order `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析订单信息失败: %v", err)
+	}
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("未找到订单: %s", clOrdId)
+	}
+
+	return result.Data[0], nil
+}
+
+// recoverDuplicate 下单返回clOrdId重复时按clOrdId查询已有订单。网络超时后重试的下单请求，
+// 前一次可能已经被交易所接受，产品、方向、类型和数量一致时视为下单成功；
+// 不一致说明与其他订单的clOrdId冲突，返回nil
+func (c *OKXClient) recoverDuplicate(ctx context.Context, req *PlaceOrderRequest) *OrderResponse {
+	o, err := c.GetOrderByClOrdId(ctx, req.InstId, req.ClOrdId)
+	if err != nil {
+		log.Printf("[%s] clOrdId %s 重复，查询已有订单失败: %v", req.InstId, req.ClOrdId, err)
+		return nil
+	}
+	// 现货按计价货币下单时交易所返回的数量单位可能不同，不比较数量
+	sameSz := req.TgtCcy == TgtCcyQuote || parseFloat(o.Sz) == parseFloat(req.Sz)
+	if o.Side != string(req.Side) || o.OrdType != string(req.OrdType) || !sameSz {
+		log.Printf("[%s] clOrdId %s 已被订单 %s 使用 (%s %s %s)，与本次请求不一致",
+			req.InstId, req.ClOrdId, o.OrdId, o.Side, o.OrdType, o.Sz)
+		return nil
+	}
+
+	log.Printf("[%s] clOrdId %s 重复，订单 %s 已由之前的请求提交 (状态: %s)", req.InstId, req.ClOrdId, o.OrdId, o.State)
+	return &OrderResponse{OrderId: o.OrdId, ClOrdId: o.ClOrdId, SCode: "0"}
+}
+
+// GetFills 查询订单的成交明细(近3天)
+func (c *OKXClient) GetFills(ctx context.Context, instId, ordId string) ([]*Fill, error) {
+	path := fmt.Sprintf("/api/v5/trade/fills?instId=%s&ordId=%s", instId, ordId)
+	resp, err := c.sendRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Code string  `json:"code"`
+		Msg  string  `json:"msg"`
+		Data []*Fill `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析成交明细失败: %v", err)
+	}
+
+	return result.Data, nil
+}
+
+// 批量接口单次最多处理的订单数量
+const maxBatchOrders = 20
+
+// CancelOrderRequest 撤单请求，ordId和clOrdId二选一
+type CancelOrderRequest struct {
+	InstId  string `json:"instId"`
+	OrdId   string `json:"ordId,omitempty"`
+	ClOrdId string `json:"clOrdId,omitempty"`
+}
+
+// AmendOrderRequest 改单请求，ordId和clOrdId二选一，newSz和newPx至少填一个
+type AmendOrderRequest struct {
+	InstId    string `json:"instId"`
+	OrdId     string `json:"ordId,omitempty"`
+	ClOrdId   string `json:"clOrdId,omitempty"`
+	ReqId     string `json:"reqId,omitempty"`
+	NewSz     string `json:"newSz,omitempty"`
+	NewPx     string `json:"newPx,omitempty"`
+	CxlOnFail bool   `json:"cxlOnFail,omitempty"` // 改单失败时是否自动撤单
+}
+
+// PlaceOrders 批量下单，超过20笔时分批提交。
+// 返回结果与请求一一对应，部分失败时error为nil，调用方需通过OrderResponse.Err检查每笔结果
+func (c *OKXClient) PlaceOrders(ctx context.Context, reqs []*PlaceOrderRequest) ([]OrderResponse, error) {
+	for i, req := range reqs {
+		if req.ClOrdId == "" {
+			req.ClOrdId = fmt.Sprintf("%012d%02d", time.Now().UnixNano()%1000000000000, i%100)
+		}
+		if req.TdMode == "" {
+			req.TdMode = "isolated"
+		}
+	}
+
+	results := make([]OrderResponse, 0, len(reqs))
+	for start := 0; start < len(reqs); start += maxBatchOrders {
+		end := start + maxBatchOrders
+		if end > len(reqs) {
+			end = len(reqs)
+		}
+		batch, err := c.sendBatch(ctx, "/api/v5/trade/batch-orders", reqs[start:end], end-start)
+		if err != nil {
+			return results, fmt.Errorf("批量下单失败: %w", err)
+		}
+		for i := range batch {
+			if IsDuplicate(batch[i].Err()) {
+				if resp := c.recoverDuplicate(ctx, reqs[start+i]); resp != nil {
+					batch[i] = *resp
+				}
+			}
+		}
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+// CancelOrders 批量撤单，返回结果与请求一一对应
+func (c *OKXClient) CancelOrders(ctx context.Context, reqs []CancelOrderRequest) ([]OrderResponse, error) {
+	results := make([]OrderResponse, 0, len(reqs))
+	for start := 0; start < len(reqs); start += maxBatchOrders {
+		end := start + maxBatchOrders
+		if end > len(reqs) {
+			end = len(reqs)
+		}
+		batch, err := c.sendBatch(ctx, "/api/v5/trade/cancel-batch-orders", reqs[start:end], end-start)
+		if err != nil {
+			return results, fmt.Errorf("批量撤单失败: %w", err)
+		}
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+// AmendOrder 修改未成交订单的数量或价格
+func (c *OKXClient) AmendOrder(ctx context.Context, req *AmendOrderRequest) (*OrderResponse, error) {
+	results, err := c.sendBatch(ctx, "/api/v5/trade/amend-order", req, 1)
+	if err != nil {
+		return nil, fmt.Errorf("改单失败: %w", err)
+	}
+	if err := results[0].Err(); err != nil {
+		return &results[0], fmt.Errorf("改单失败: %w", err)
+	}
+	return &results[0], nil
+}
+
+// AmendOrders 批量改单，返回结果与请求一一对应
+func (c *OKXClient) AmendOrders(ctx context.Context, reqs []*AmendOrderRequest) ([]OrderResponse, error) {
+	results := make([]OrderResponse, 0, len(reqs))
+	for start := 0; start < len(reqs); start += maxBatchOrders {
+		end := start + maxBatchOrders
+		if end > len(reqs) {
+			end = len(reqs)
+		}
+		batch, err := c.sendBatch(ctx, "/api/v5/trade/amend-batch-orders", reqs[start:end], end-start)
+		if err != nil {
+			return results, fmt.Errorf("批量改单失败: %w", err)
+		}
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+// sendBatch 提交交易类请求并解析每笔订单的结果，n为请求中的订单数量
+func (c *OKXClient) sendBatch(ctx context.Context, path string, body interface{}, n int) ([]OrderResponse, error) {
+	resp, err := c.sendRequest(ctx, "POST", path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Code string          `json:"code"`
+		Msg  string          `json:"msg"`
+		Data []OrderResponse `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %v, 响应内容: %s", err, string(resp))
+	}
+	if len(result.Data) != n {
+		return nil, fmt.Errorf("响应订单数量 %d 与请求数量 %d 不一致: %s", len(result.Data), n, string(resp))
+	}
+
+	return result.Data, nil
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// dupOrderServer 模拟下单超时后重发：第一次下单请求被交易所接受但连接被断开，
+// 之后同一clOrdId的下单返回51016
+type dupOrderServer struct {
+	mu      sync.Mutex
+	orders  map[string]map[string]string // clOrdId -> 订单
+	posts   int
+	dropped bool
+}
+
+func (s *dupOrderServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Method == http.MethodGet {
+		o, ok := s.orders[r.URL.Query().Get("clOrdId")]
+		if !ok {
+			io.WriteString(w, `{"code":"51603","msg":"Order does not exist","data":[]}`)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"code": "0", "data": []map[string]string{o}})
+		return
+	}
+
+	s.posts++
+	var reqs []PlaceOrderRequest
+	body, _ := io.ReadAll(r.Body)
+	if r.URL.Path == "/api/v5/trade/order" {
+		var req PlaceOrderRequest
+		json.Unmarshal(body, &req)
+		reqs = append(reqs, req)
+	} else {
+		json.Unmarshal(body, &reqs)
+	}
+
+	data := make([]map[string]string, 0, len(reqs))
+	failed := 0
+	for _, req := range reqs {
+		if _, ok := s.orders[req.ClOrdId]; ok {
+			failed++
+			data = append(data, map[string]string{"clOrdId": req.ClOrdId, "sCode": "51016", "sMsg": "Duplicated clOrdId"})
+			continue
+		}
+		ordId := "ord" + req.ClOrdId
+		s.orders[req.ClOrdId] = map[string]string{
+			"instId": req.InstId, "ordId": ordId, "clOrdId": req.ClOrdId, "side": string(req.Side),
+			"ordType": string(req.OrdType), "sz": req.Sz, "state": "filled",
+		}
+		data = append(data, map[string]string{"ordId": ordId, "clOrdId": req.ClOrdId, "sCode": "0"})
+	}
+
+	if !s.dropped {
+		// 订单已受理，但响应丢失
+		s.dropped = true
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+		return
+	}
+	code := "0"
+	if failed == len(data) {
+		code = "1"
+	} else if failed > 0 {
+		code = "2"
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"code": code, "msg": "", "data": data})
+}
+
+func newTestClient(t *testing.T, h http.Handler) *OKXClient {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c := NewOKXClient("key", "secret", "pass", "live")
+	c.SetBaseURL(srv.URL)
+	return c
+}
+
+func TestPlaceOrderRecoversDuplicateAfterRetry(t *testing.T) {
+	srv := &dupOrderServer{orders: make(map[string]map[string]string)}
+	c := newTestClient(t, srv)
+
+	resp, err := c.PlaceOrder(context.Background(), &PlaceOrderRequest{
+		InstId: "BTC-USDT-SWAP", TdMode: "cross", Side: Buy, OrdType: Market, Sz: "1", ClOrdId: "c1",
+	})
+	if err != nil {
+		t.Fatalf("重发后应按已有订单返回成功: %v", err)
+	}
+	if resp.OrderId != "ordc1" {
+		t.Fatalf("ordId = %q, 期望 ordc1", resp.OrderId)
+	}
+	if srv.posts != 2 {
+		t.Fatalf("下单请求次数 = %d, 期望 2", srv.posts)
+	}
+}
+
+func TestPlaceOrderDuplicateMismatch(t *testing.T) {
+	srv := &dupOrderServer{orders: map[string]map[string]string{
+		"c1": {"instId": "BTC-USDT-SWAP", "ordId": "other", "clOrdId": "c1", "side": "sell", "ordType": "market", "sz": "1"},
+	}, dropped: true}
+	c := newTestClient(t, srv)
+
+	_, err := c.PlaceOrder(context.Background(), &PlaceOrderRequest{
+		InstId: "BTC-USDT-SWAP", TdMode: "cross", Side: Buy, OrdType: Market, Sz: "1", ClOrdId: "c1",
+	})
+	if !IsDuplicate(err) {
+		t.Fatalf("clOrdId与其他订单冲突时应返回重复错误, 实际: %v", err)
+	}
+}
+
+func TestPlaceOrdersRecoversDuplicate(t *testing.T) {
+	srv := &dupOrderServer{orders: make(map[string]map[string]string)}
+	c := newTestClient(t, srv)
+
+	results, err := c.PlaceOrders(context.Background(), []*PlaceOrderRequest{
+		{InstId: "BTC-USDT-SWAP", Side: Buy, OrdType: Market, Sz: "1", ClOrdId: "b1"},
+		{InstId: "BTC-USDT-SWAP", Side: Sell, OrdType: Market, Sz: "2", ClOrdId: "b2"},
+	})
+	if err != nil {
+		t.Fatalf("批量下单失败: %v", err)
+	}
+	for i, r := range results {
+		if r.Err() != nil || r.OrderId == "" {
+			t.Fatalf("第%d笔应恢复为成功, 实际: %+v", i, r)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 等待超过该时间时打印日志
+const slowWaitThreshold = time.Second
+
+// endpointLimit 接口限速，OKX文档以2秒内的请求次数给出
+type endpointLimit struct {
+	group string
+	per2s float64
+}
+
+// endpointLimits 按"方法 路径"配置的接口限速
+var endpointLimits = map[string]endpointLimit{
+	"POST /api/v5/trade/order":               {"trade", 60},
+	"GET /api/v5/trade/order":                {"trade", 60},
+	"POST /api/v5/trade/batch-orders":        {"trade", 30},
+	"POST /api/v5/trade/cancel-order":        {"trade", 60},
+	"POST /api/v5/trade/cancel-batch-orders": {"trade", 30},
+	"POST /api/v5/trade/amend-order":         {"trade", 60},
+	"POST /api/v5/trade/amend-batch-orders":  {"trade", 30},
+	"GET /api/v5/trade/fills":                {"trade", 60},
+	"POST /api/v5/trade/order-algo":          {"trade", 20},
+	"POST /api/v5/trade/cancel-algos":        {"trade", 20},
+	"POST /api/v5/trade/amend-algos":         {"trade", 20},
+	"GET /api/v5/trade/orders-algo-pending":  {"trade", 20},
+
+	"GET /api/v5/account/balance":                  {"account", 10},
+	"GET /api/v5/account/positions":                {"account", 10},
+	"POST /api/v5/account/set-leverage":            {"account", 20},
+	"POST /api/v5/account/position/margin-balance": {"account", 20},
+	"GET /api/v5/account/config":                   {"account", 5},
+	"POST /api/v5/account/set-position-mode":       {"account", 5},
+	"GET /api/v5/account/max-size":                 {"account", 20},
+	"GET /api/v5/account/max-avail-size":           {"account", 20},
+	"GET /api/v5/account/trade-fee":                {"account", 5},
+	"GET /api/v5/account/bills":                    {"account", 10},
+	"GET /api/v5/account/bills-archive":            {"account", 5},
+	"GET /api/v5/asset/balances":                   {"account", 12},
+	"POST /api/v5/asset/transfer":                  {"account", 2},
+
+	"GET /api/v5/market/candles":         {"market", 40},
+	"GET /api/v5/market/history-candles": {"market", 20},
+	"GET /api/v5/public/instruments":     {"market", 20},
+	"GET /api/v5/public/time":            {"market", 10},
+	"GET /api/v5/public/funding-rate":    {"market", 20},
+	"GET /api/v5/public/mark-price":      {"market", 10},
+	"GET /api/v5/market/index-tickers":   {"market", 20},
+	"GET /api/v5/public/open-interest":   {"market", 20},
+}
+
+// groupLimits 每个分组的总体限速，未配置的接口使用分组默认值
+var groupLimits = map[string]float64{
+	"trade":   120,
+	"account": 40,
+	"market":  80,
+	"other":   20,
+}
+
+// highPriorityEndpoints 下单、撤单和改单请求优先获取分组令牌
+var highPriorityEndpoints = map[string]bool{
+	"POST /api/v5/trade/order":               true,
+	"POST /api/v5/trade/batch-orders":        true,
+	"POST /api/v5/trade/cancel-order":        true,
+	"POST /api/v5/trade/cancel-batch-orders": true,
+	"POST /api/v5/trade/amend-order":         true,
+	"POST /api/v5/trade/amend-batch-orders":  true,
+	"POST /api/v5/trade/order-algo":          true,
+	"POST /api/v5/trade/cancel-algos":        true,
+}
+
+// groupOf 根据路径推断接口分组
+func groupOf(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/api/v5/trade/"):
+		return "trade"
+	case strings.HasPrefix(path, "/api/v5/account/"), strings.HasPrefix(path, "/api/v5/asset/"):
+		return "account"
+	case strings.HasPrefix(path, "/api/v5/market/"), strings.HasPrefix(path, "/api/v5/public/"):
+		return "market"
+	default:
+		return "other"
+	}
+}
+
+// limiterClock 限速使用的时钟，测试中替换为手动推进的时钟
+type limiterClock interface {
+	now() time.Time
+	// newTimer 返回d之后触发的通道和停止函数
+	newTimer(d time.Duration) (<-chan time.Time, func() bool)
+}
+
+// systemClock 使用系统时间
+type systemClock struct{}
+
+func (systemClock) now() time.Time { return time.Now() }
+
+func (systemClock) newTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	timer := time.NewTimer(d)
+	return timer.C, timer.Stop
+}
+
+// tokenBucket 令牌桶，高优先级请求等待时低优先级请求让出令牌
+type tokenBucket struct {
+	mu          sync.Mutex
+	clock       limiterClock
+	rate        float64 // 每秒补充的令牌数
+	burst       float64
+	tokens      float64
+	last        time.Time
+	highWaiting int
+}
+
+func newTokenBucket(per2s float64, clock limiterClock) *tokenBucket {
+	return &tokenBucket{
+		clock:  clock,
+		rate:   per2s / 2,
+		burst:  per2s,
+		tokens: per2s,
+		last:   clock.now(),
+	}
+}
+
+// take 尝试获取令牌，失败时返回建议的等待时间
+func (b *tokenBucket) take(high bool) (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if !high && b.highWaiting > 0 {
+		return false, 5 * time.Millisecond
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// wait 阻塞直到获取令牌或ctx结束
+func (b *tokenBucket) wait(ctx context.Context, high bool) error {
+	if high {
+		b.mu.Lock()
+		b.highWaiting++
+		b.mu.Unlock()
+		defer func() {
+			b.mu.Lock()
+			b.highWaiting--
+			b.mu.Unlock()
+		}()
+	}
+	for {
+		ok, delay := b.take(high)
+		if ok {
+			return nil
+		}
+		fired, stop := b.clock.newTimer(delay)
+		select {
+		case <-ctx.Done():
+			stop()
+			return ctx.Err()
+		case <-fired:
+		}
+	}
+}
+
+// RateLimitStat 分组的限速等待统计
+type RateLimitStat struct {
+	Requests  int64         `json:"requests"`
+	Waited    int64         `json:"waited"` // 需要等待的请求数
+	TotalWait time.Duration `json:"total_wait_ns"`
+	MaxWait   time.Duration `json:"max_wait_ns"`
+}
+
+// rateLimiter 按接口和分组两级限速
+type rateLimiter struct {
+	mu        sync.Mutex
+	clock     limiterClock
+	endpoints map[string]*tokenBucket
+	groups    map[string]*tokenBucket
+	stats     map[string]*RateLimitStat
+}
+
+func newRateLimiter(clock limiterClock) *rateLimiter {
+	l := &rateLimiter{
+		clock:     clock,
+		endpoints: make(map[string]*tokenBucket),
+		groups:    make(map[string]*tokenBucket),
+		stats:     make(map[string]*RateLimitStat),
+	}
+	for group, per2s := range groupLimits {
+		l.groups[group] = newTokenBucket(per2s, clock)
+		l.stats[group] = &RateLimitStat{}
+	}
+	return l
+}
+
+// wait 等待接口和分组的令牌，path可以带查询参数，ctx结束时返回ctx.Err()
+func (l *rateLimiter) wait(ctx context.Context, method, path string) error {
+	if idx := strings.IndexByte(path, '?'); idx >= 0 {
+		path = path[:idx]
+	}
+	key := method + " " + path
+
+	limit, ok := endpointLimits[key]
+	if !ok {
+		limit = endpointLimit{group: groupOf(path), per2s: 10}
+	}
+
+	l.mu.Lock()
+	endpoint, ok := l.endpoints[key]
+	if !ok {
+		endpoint = newTokenBucket(limit.per2s, l.clock)
+		l.endpoints[key] = endpoint
+	}
+	group := l.groups[limit.group]
+	l.mu.Unlock()
+
+	high := highPriorityEndpoints[key]
+	start := l.clock.now()
+	if err := endpoint.wait(ctx, high); err != nil {
+		return err
+	}
+	if err := group.wait(ctx, high); err != nil {
+		return err
+	}
+	waited := l.clock.now().Sub(start)
+
+	l.mu.Lock()
+	stat := l.stats[limit.group]
+	stat.Requests++
+	if waited > time.Millisecond {
+		stat.Waited++
+		stat.TotalWait += waited
+		if waited > stat.MaxWait {
+			stat.MaxWait = waited
+		}
+	}
+	l.mu.Unlock()
+
+	if waited > slowWaitThreshold {
+		log.Printf("[限速] %s 等待 %v", key, waited)
+	}
+	return nil
+}
+
+// snapshot 返回各分组的统计快照
+func (l *rateLimiter) snapshot() map[string]RateLimitStat {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	result := make(map[string]RateLimitStat, len(l.stats))
+	for group, stat := range l.stats {
+		result[group] = *stat
+	}
+	return result
+}
+
+// RateLimitStats 返回各接口分组的限速等待统计
+func (c *OKXClient) RateLimitStats() map[string]RateLimitStat {
+	return c.limiter.snapshot()
+}
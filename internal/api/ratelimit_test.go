@@ -0,0 +1,252 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 手动推进的时钟，advance时触发到期的定时器
+type fakeClock struct {
+	mu     sync.Mutex
+	t      time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) newTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer := &fakeTimer{at: c.t.Add(d), ch: make(chan time.Time, 1)}
+	c.timers = append(c.timers, timer)
+	stop := func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, t := range c.timers {
+			if t == timer {
+				c.timers = append(c.timers[:i], c.timers[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+	return timer.ch, stop
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(c.t) {
+			pending = append(pending, t)
+			continue
+		}
+		t.ch <- c.t
+	}
+	c.timers = pending
+}
+
+// blockUntilWaiting 等待直到恰好有n个定时器在等待
+func (c *fakeClock) blockUntilWaiting(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		c.mu.Lock()
+		waiting := len(c.timers)
+		c.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("等待中的定时器 %d 个, 期望 %d 个", waiting, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// takeAll 连续获取令牌直到失败，返回成功次数和建议的等待时间
+func takeAll(b *tokenBucket) (int, time.Duration) {
+	for n := 0; ; n++ {
+		if ok, delay := b.take(false); !ok {
+			return n, delay
+		}
+	}
+}
+
+func TestTokenBucketBurstAndRefill(t *testing.T) {
+	tests := []struct {
+		name      string
+		per2s     float64
+		elapsed   time.Duration // 用完突发令牌后经过的时间
+		wantBurst int
+		wantTaken int
+		wantDelay time.Duration
+	}{
+		{"突发后立即请求", 10, 0, 10, 0, 200 * time.Millisecond},
+		{"补充不足一个令牌", 10, 100 * time.Millisecond, 10, 0, 100 * time.Millisecond},
+		{"补充一个令牌", 10, 200 * time.Millisecond, 10, 1, 200 * time.Millisecond},
+		{"补充多个令牌", 10, time.Second, 10, 5, 200 * time.Millisecond},
+		{"补充不超过突发上限", 10, time.Minute, 10, 10, 200 * time.Millisecond},
+		{"低速接口", 2, 0, 2, 0, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			b := newTokenBucket(tt.per2s, clock)
+
+			if n, _ := takeAll(b); n != tt.wantBurst {
+				t.Fatalf("突发请求成功 %d 次, 期望 %d 次", n, tt.wantBurst)
+			}
+			clock.advance(tt.elapsed)
+			n, delay := takeAll(b)
+			if n != tt.wantTaken || delay != tt.wantDelay {
+				t.Fatalf("补充后成功 %d 次, 建议等待 %v; 期望 %d 次, %v", n, delay, tt.wantTaken, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestTokenBucketWait(t *testing.T) {
+	clock := newFakeClock()
+	b := newTokenBucket(10, clock)
+	takeAll(b)
+
+	done := make(chan error, 1)
+	go func() { done <- b.wait(context.Background(), false) }()
+	clock.blockUntilWaiting(t, 1)
+
+	// 未到补充时间前不应返回
+	clock.advance(100 * time.Millisecond)
+	clock.blockUntilWaiting(t, 1)
+	select {
+	case err := <-done:
+		t.Fatalf("令牌补充前返回: %v", err)
+	default:
+	}
+
+	clock.advance(100 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("补充令牌后应获取成功: %v", err)
+	}
+}
+
+func TestTokenBucketWaitCanceled(t *testing.T) {
+	clock := newFakeClock()
+	b := newTokenBucket(10, clock)
+	takeAll(b)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- b.wait(ctx, true) }()
+	clock.blockUntilWaiting(t, 1)
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("取消后应返回context.Canceled, 实际: %v", err)
+	}
+	clock.blockUntilWaiting(t, 0)
+	b.mu.Lock()
+	highWaiting := b.highWaiting
+	b.mu.Unlock()
+	if highWaiting != 0 {
+		t.Fatalf("取消后高优先级等待数 = %d", highWaiting)
+	}
+
+	// 取消的等待不消耗令牌
+	clock.advance(200 * time.Millisecond)
+	if n, _ := takeAll(b); n != 1 {
+		t.Fatalf("补充后成功 %d 次, 期望 1 次", n)
+	}
+}
+
+func TestTokenBucketHighPriority(t *testing.T) {
+	b := newTokenBucket(10, newFakeClock())
+	// 模拟一个正在等待的高优先级请求
+	b.highWaiting++
+
+	if ok, delay := b.take(false); ok || delay != 5*time.Millisecond {
+		t.Fatalf("高优先级请求等待时低优先级请求应让出令牌: ok=%v, delay=%v", ok, delay)
+	}
+	if ok, _ := b.take(true); !ok {
+		t.Fatal("高优先级请求应获取令牌")
+	}
+	b.highWaiting--
+	if ok, _ := b.take(false); !ok {
+		t.Fatal("没有高优先级请求等待时低优先级请求应获取令牌")
+	}
+}
+
+func TestRateLimiterSharesEndpointBucket(t *testing.T) {
+	clock := newFakeClock()
+	l := newRateLimiter(clock)
+	ctx := context.Background()
+
+	// 同一接口不同查询参数共用令牌桶，account/config限速为2秒5次
+	for i := 0; i < 5; i++ {
+		if err := l.wait(ctx, "GET", fmt.Sprintf("/api/v5/account/config?n=%d", i)); err != nil {
+			t.Fatalf("第%d次请求失败: %v", i+1, err)
+		}
+	}
+	// 同一分组的其他接口不受影响
+	if err := l.wait(ctx, "GET", "/api/v5/account/balance"); err != nil {
+		t.Fatalf("其他接口请求失败: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- l.wait(ctx, "GET", "/api/v5/account/config") }()
+	clock.blockUntilWaiting(t, 1)
+	clock.advance(400 * time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("补充令牌后请求失败: %v", err)
+	}
+
+	stat := l.snapshot()["account"]
+	if stat.Requests != 7 || stat.Waited != 1 || stat.MaxWait != 400*time.Millisecond {
+		t.Fatalf("限速统计 = %+v", stat)
+	}
+}
+
+func TestRateLimiterSharesGroupBucket(t *testing.T) {
+	clock := newFakeClock()
+	l := newRateLimiter(clock)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 未配置的接口各自限速2秒10次，共用account分组的2秒40次
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 10; j++ {
+			if err := l.wait(ctx, "GET", fmt.Sprintf("/api/v5/account/test%d", i)); err != nil {
+				t.Fatalf("请求失败: %v", err)
+			}
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- l.wait(ctx, "GET", "/api/v5/account/test4") }()
+	clock.blockUntilWaiting(t, 1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("分组令牌用完后应等待至取消, 实际: %v", err)
+	}
+	if stat := l.snapshot()["account"]; stat.Requests != 40 {
+		t.Fatalf("取消的请求不应计入统计: %+v", stat)
+	}
+}
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveHeaders 记录时需要脱敏的请求头
+var sensitiveHeaders = map[string]bool{
+	"Ok-Access-Key":        true,
+	"Ok-Access-Sign":       true,
+	"Ok-Access-Passphrase": true,
+	"Authorization":        true,
+	"Cookie":               true,
+}
+
+// sensitiveFields 记录时需要脱敏的JSON字段
+var sensitiveFields = map[string]bool{
+	"apiKey":     true,
+	"secretKey":  true,
+	"passphrase": true,
+	"sign":       true,
+	"pwd":        true,
+}
+
+// Recording 一次REST请求及其响应
+type Recording struct {
+	Time     time.Time         `json:"time"`
+	Method   string            `json:"method"`
+	Path     string            `json:"path"` // 包含查询参数
+	Header   map[string]string `json:"header,omitempty"`
+	Body     string            `json:"body,omitempty"`
+	Status   int               `json:"status,omitempty"`
+	Response string            `json:"response,omitempty"`
+	Error    string            `json:"error,omitempty"` // 网络错误，回放时原样返回
+	Duration int64             `json:"duration_ms"`
+}
+
+// RecordingTransport 将每个请求和响应(脱敏后)以JSON Lines格式追加写入文件
+type RecordingTransport struct {
+	base http.RoundTripper
+
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewRecordingTransport 创建记录传输层，base为空时使用http.DefaultTransport
+func NewRecordingTransport(base http.RoundTripper, path string) (*RecordingTransport, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("打开记录文件失败: %v", err)
+	}
+	return &RecordingTransport{base: base, file: f, enc: json.NewEncoder(f)}, nil
+}
+
+// RoundTrip 转发请求并记录请求和响应
+func (t *RecordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := Recording{
+		Time:   time.Now(),
+		Method: req.Method,
+		Path:   req.URL.RequestURI(),
+		Header: redactHeader(req.Header),
+	}
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			rec.Body = redactJSON(data)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	rec.Duration = time.Since(rec.Time).Milliseconds()
+	if err != nil {
+		rec.Error = err.Error()
+		t.write(&rec)
+		return nil, err
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	rec.Status = resp.StatusCode
+	rec.Response = redactJSON(data)
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	t.write(&rec)
+	return resp, err
+}
+
+func (t *RecordingTransport) write(rec *Recording) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.enc.Encode(rec); err != nil {
+		log.Printf("写入请求记录失败: %v", err)
+	}
+}
+
+// Close 关闭记录文件
+func (t *RecordingTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.file.Close()
+}
+
+func redactHeader(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, v := range h {
+		if sensitiveHeaders[http.CanonicalHeaderKey(k)] {
+			out[k] = redacted
+			continue
+		}
+		out[k] = strings.Join(v, ",")
+	}
+	return out
+}
+
+// redactJSON 将JSON中的敏感字段替换为[REDACTED]，非JSON内容原样返回
+func redactJSON(data []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return string(data)
+	}
+	if !redactValue(v) {
+		return string(data)
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return string(data)
+	}
+	return string(out)
+}
+
+// redactValue 递归脱敏，返回是否有字段被替换
+func redactValue(v interface{}) bool {
+	changed := false
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, child := range val {
+			if sensitiveFields[k] {
+				val[k] = redacted
+				changed = true
+				continue
+			}
+			if redactValue(child) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, child := range val {
+			if redactValue(child) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// ReplayTransport 按记录文件回放响应，不访问网络。相同请求按记录顺序依次返回，
+// 优先匹配查询参数和请求体完全一致的记录；查询参数含时间戳等无法一致时按路径顺序匹配，
+// 记录用完后重复返回最后一条
+type ReplayTransport struct {
+	mu      sync.Mutex
+	pending map[string][]*Recording // key: 方法 路径(不含查询参数)
+	last    map[string]*Recording   // key: 方法 路径(含查询参数)，以及不含查询参数的路径
+}
+
+// NewReplayTransport 读取RecordingTransport生成的记录文件
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开回放文件失败: %v", err)
+	}
+	defer f.Close()
+
+	t := &ReplayTransport{
+		pending: make(map[string][]*Recording),
+		last:    make(map[string]*Recording),
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	n := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		rec := &Recording{}
+		if err := json.Unmarshal(line, rec); err != nil {
+			return nil, fmt.Errorf("解析回放记录第%d条失败: %v", n+1, err)
+		}
+		key := rec.Method + " " + strings.SplitN(rec.Path, "?", 2)[0]
+		t.pending[key] = append(t.pending[key], rec)
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取回放文件失败: %v", err)
+	}
+	log.Printf("已加载 %d 条回放记录: %s", n, path)
+	return t, nil
+}
+
+// RoundTrip 返回与请求匹配的记录
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+	rec := t.next(req.Method, req.URL.Path, req.URL.RequestURI(), redactJSON(body))
+	if rec == nil {
+		return nil, fmt.Errorf("回放记录中没有匹配的请求: %s %s", req.Method, req.URL.RequestURI())
+	}
+	if rec.Error != "" {
+		return nil, errors.New(rec.Error)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(rec.Response)),
+		ContentLength: int64(len(rec.Response)),
+		Request:       req,
+	}, nil
+}
+
+// next 按匹配优先级取出记录: 路径和请求体一致 > 路径一致 > 上次相同路径的记录 > 同一接口的下一条
+func (t *ReplayTransport) next(method, path, uri, body string) *Recording {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := method + " " + path
+	exact := method + " " + uri
+	queue := t.pending[key]
+
+	i := -1
+	for j, rec := range queue {
+		if rec.Path == uri && rec.Body == body {
+			i = j
+			break
+		}
+		if rec.Path == uri && i < 0 {
+			i = j
+		}
+	}
+	if i < 0 {
+		if rec, ok := t.last[exact]; ok {
+			return rec
+		}
+		if len(queue) == 0 {
+			return t.last[key]
+		}
+		i = 0
+	}
+
+	rec := queue[i]
+	t.pending[key] = append(queue[:i:i], queue[i+1:]...)
+	t.last[exact] = rec
+	t.last[key] = rec
+	return rec
+}
+
+// EnableRecording 记录之后的所有REST请求和响应，需在SetProxy之后调用，返回的Closer用于关闭文件
+func (c *OKXClient) EnableRecording(path string) (io.Closer, error) {
+	base := c.client.Transport
+	recorder, err := NewRecordingTransport(base, path)
+	if err != nil {
+		return nil, err
+	}
+	c.client = &http.Client{Timeout: c.client.Timeout, Transport: recorder}
+	log.Printf("REST请求记录到: %s", path)
+	return recorder, nil
+}
+
+// EnableReplay 使用记录文件回放REST响应，不再访问交易所
+func (c *OKXClient) EnableReplay(path string) error {
+	replay, err := NewReplayTransport(path)
+	if err != nil {
+		return err
+	}
+	c.client = &http.Client{Timeout: c.client.Timeout, Transport: replay}
+	log.Printf("REST请求从记录回放: %s", path)
+	return nil
+}
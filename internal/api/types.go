@@ -10,6 +10,8 @@
  */
 package api
 
+import "net/http"
+
 // OrderSide 订单方向
 type OrderSide string
 
@@ -26,15 +28,33 @@ const (
 	Limit  OrderType = "limit"
 )
 
-// OrderResponse 下单响应
+// TdModeCash 现货非保证金交易模式
+const TdModeCash = "cash"
+
+// 现货市价单的数量单位
+const (
+	TgtCcyBase  = "base_ccy"  // 交易货币，如BTC-USDT中的BTC
+	TgtCcyQuote = "quote_ccy" // 计价货币，如BTC-USDT中的USDT
+)
+
+// OrderResponse 下单、撤单和改单响应，批量接口中每笔订单的结果通过SCode区分
 type OrderResponse struct {
 	OrderId string `json:"ordId"`
 	ClOrdId string `json:"clOrdId"`
+	ReqId   string `json:"reqId"` // 改单请求ID
 	Tag     string `json:"tag"`
 	SCode   string `json:"sCode"`
 	SMsg    string `json:"sMsg"`
 }
 
+// Err 返回单笔订单的失败原因，成功时返回nil
+func (r *OrderResponse) Err() error {
+	if r.SCode == "" || r.SCode == "0" {
+		return nil
+	}
+	return &APIError{HTTPStatus: http.StatusOK, SCode: r.SCode, SMsg: r.SMsg}
+}
+
 // Candle K线数据
 type Candle struct {
 	Timestamp string `json:"ts"`
@@ -43,4 +63,51 @@ type Candle struct {
 	Low       string `json:"l"`
 	Close     string `json:"c"`
 	Volume    string `json:"vol"`
+	Confirm   string `json:"confirm"` // 0表示K线未完结，1表示已完结
+}
+
+// Order 订单详情，REST查询与私有频道推送共用
+type Order struct {
+	InstId    string `json:"instId"`
+	InstType  string `json:"instType"`
+	OrdId     string `json:"ordId"`
+	ClOrdId   string `json:"clOrdId"`
+	Side      string `json:"side"`
+	PosSide   string `json:"posSide"`
+	OrdType   string `json:"ordType"`
+	TdMode    string `json:"tdMode"`
+	State     string `json:"state"`     // live/partially_filled/filled/canceled
+	Px        string `json:"px"`        // 委托价格
+	Sz        string `json:"sz"`        // 委托数量
+	AvgPx     string `json:"avgPx"`     // 成交均价
+	AccFillSz string `json:"accFillSz"` // 累计成交数量
+	FillPx    string `json:"fillPx"`    // 最新成交价格
+	FillSz    string `json:"fillSz"`    // 最新成交数量
+	Fee       string `json:"fee"`       // 手续费，负数表示扣除
+	FeeCcy    string `json:"feeCcy"`
+	CTime     string `json:"cTime"`
+	UTime     string `json:"uTime"`
+}
+
+// IsFinal 订单是否已处于终态(完全成交或已撤销)
+func (o *Order) IsFinal() bool {
+	return o.State == "filled" || o.State == "canceled" || o.State == "mmp_canceled"
+}
+
+// Fill 成交明细
+type Fill struct {
+	InstId   string `json:"instId"`
+	InstType string `json:"instType"`
+	TradeId  string `json:"tradeId"`
+	OrdId    string `json:"ordId"`
+	ClOrdId  string `json:"clOrdId"`
+	BillId   string `json:"billId"`
+	Side     string `json:"side"`
+	PosSide  string `json:"posSide"`
+	FillPx   string `json:"fillPx"`   // 成交价格
+	FillSz   string `json:"fillSz"`   // 成交数量
+	Fee      string `json:"fee"`      // 手续费，负数表示扣除
+	FeeCcy   string `json:"feeCcy"`
+	ExecType string `json:"execType"` // T:taker M:maker
+	Ts       string `json:"ts"`
 }
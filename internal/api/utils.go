@@ -1,6 +1,7 @@
 package api
 
 import (
+    "context"
     "fmt"
     "log"
     "time"
@@ -19,11 +20,14 @@ var DefaultRetryConfig = RetryConfig{
     DelayMillis: 1000, // 1秒
 }
 
-// IsTemporaryError 判断是否为临时性错误
+// IsTemporaryError 判断是否为临时性错误，优先按错误分类判断，无法分类时按错误信息匹配
 func IsTemporaryError(err error) bool {
     if err == nil {
         return false
     }
+    if class := ClassOf(err); class != ClassUnknown {
+        return class.Temporary()
+    }
     
     errMsg := strings.ToLower(err.Error())
     return strings.Contains(errMsg, "upgrading") ||
@@ -32,14 +36,27 @@ func IsTemporaryError(err error) bool {
            strings.Contains(errMsg, "too many requests")
 }
 
-// RetryOperation 重试执行操作
-func RetryOperation(operation func() error, config RetryConfig) error {
+// RetryOperation 重试执行操作，只重试临时性错误，等待时间按错误分类的退避策略计算且不小于DelayMillis，
+// ctx取消或超时后立即返回
+func RetryOperation(ctx context.Context, operation func() error, config RetryConfig) error {
     var lastErr error
     
     for i := 0; i < config.MaxRetries; i++ {
         if i > 0 {
-            log.Printf("重试操作 (第%d次)...", i+1)
-            time.Sleep(time.Duration(config.DelayMillis) * time.Millisecond)
+            delay := time.Duration(config.DelayMillis) * time.Millisecond
+            if policy, ok := retryPolicies[ClassOf(lastErr)]; ok {
+                if backoff := policy.Backoff(i); backoff > delay {
+                    delay = backoff
+                }
+            }
+            log.Printf("重试操作 (第%d次)，等待 %v...", i+1, delay)
+            timer := time.NewTimer(delay)
+            select {
+            case <-ctx.Done():
+                timer.Stop()
+                return fmt.Errorf("重试被取消: %w (上次错误: %v)", ctx.Err(), lastErr)
+            case <-timer.C:
+            }
         }
         
         err := operation()
@@ -48,6 +65,9 @@ func RetryOperation(operation func() error, config RetryConfig) error {
         }
         
         lastErr = err
+        if ctx.Err() != nil {
+            return err // 请求因ctx结束失败，不再重试
+        }
         if !IsTemporaryError(err) {
             return err // 如果不是临时性错误，直接返回
         }
@@ -55,5 +75,5 @@ func RetryOperation(operation func() error, config RetryConfig) error {
         log.Printf("操作失败 (尝试 %d/%d): %v", i+1, config.MaxRetries, err)
     }
     
-    return fmt.Errorf("达到最大重试次数 (%d): %v", config.MaxRetries, lastErr)
+    return fmt.Errorf("达到最大重试次数 (%d): %w", config.MaxRetries, lastErr)
 } 
\ No newline at end of file
@@ -0,0 +1,331 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// OKX WebSocket 地址
+const (
+	wsPublicURL       = "wss://ws.okx.com:8443/ws/v5/public"
+	wsPrivateURL      = "wss://ws.okx.com:8443/ws/v5/private"
+	wsBusinessURL     = "wss://ws.okx.com:8443/ws/v5/business"
+	wsDemoPublicURL   = "wss://wspap.okx.com:8443/ws/v5/public"
+	wsDemoPrivateURL  = "wss://wspap.okx.com:8443/ws/v5/private"
+	wsDemoBusinessURL = "wss://wspap.okx.com:8443/ws/v5/business"
+)
+
+const (
+	wsPingInterval   = 20 * time.Second // 无数据时发送ping的间隔，OKX 30秒无数据会断开
+	wsReadTimeout    = 30 * time.Second // 超过该时间未收到任何消息视为连接失效
+	wsWriteTimeout   = 5 * time.Second
+	wsMaxReconnDelay = 30 * time.Second
+)
+
+// WSURLs 根据交易模式返回公共、私有和业务频道的WebSocket地址
+func WSURLs(mode string) (public, private, business string) {
+	return WSURLsFor("", mode)
+}
+
+// WSURLsFor 根据WebSocket根地址(如 wss://wsaws.okx.com:8443 或本地模拟服务器
+// ws://127.0.0.1:8090)生成各频道地址，根地址为空时按交易模式使用默认地址
+func WSURLsFor(base, mode string) (public, private, business string) {
+	if base != "" {
+		base = strings.TrimRight(base, "/")
+		return base + "/ws/v5/public", base + "/ws/v5/private", base + "/ws/v5/business"
+	}
+	if mode == "simulation" {
+		return wsDemoPublicURL, wsDemoPrivateURL, wsDemoBusinessURL
+	}
+	return wsPublicURL, wsPrivateURL, wsBusinessURL
+}
+
+// WSArg 订阅频道参数
+type WSArg struct {
+	Channel  string `json:"channel"`
+	InstId   string `json:"instId,omitempty"`
+	InstType string `json:"instType,omitempty"`
+	Ccy      string `json:"ccy,omitempty"`
+}
+
+// key 频道唯一标识，用于记录订阅
+func (a WSArg) key() string {
+	return a.Channel + "|" + a.InstType + "|" + a.InstId + "|" + a.Ccy
+}
+
+// WSMessage 服务端推送消息
+type WSMessage struct {
+	Event  string          `json:"event"`
+	Code   string          `json:"code"`
+	Msg    string          `json:"msg"`
+	Arg    WSArg           `json:"arg"`
+	Action string          `json:"action"` // 深度频道: snapshot/update
+	Data   json.RawMessage `json:"data"`
+}
+
+type wsRequest struct {
+	Op   string        `json:"op"`
+	Args []interface{} `json:"args"`
+}
+
+// WSClient OKX WebSocket客户端，负责心跳、断线重连和重连后恢复订阅
+type WSClient struct {
+	url     string
+	dialer  *websocket.Dialer
+	handler func(msg *WSMessage)
+	// onConnect 在连接建立后、恢复订阅前执行(如私有频道登录)
+	onConnect func(conn *websocket.Conn) error
+	// onDisconnect 在连接断开后执行(如清理推送缓存)
+	onDisconnect func()
+
+	connMu  sync.Mutex
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+
+	subsMu sync.Mutex
+	subs   map[string]WSArg
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewWSClient 创建WebSocket客户端，handler在读协程中被调用，不应阻塞
+func NewWSClient(url string, handler func(msg *WSMessage)) *WSClient {
+	return &WSClient{
+		url:      url,
+		dialer:   &websocket.Dialer{HandshakeTimeout: 10 * time.Second, Proxy: http.ProxyFromEnvironment},
+		handler:  handler,
+		subs:     make(map[string]WSArg),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start 启动连接维护协程
+func (c *WSClient) Start() {
+	c.wg.Add(1)
+	go c.run()
+}
+
+// Stop 关闭连接并停止重连
+func (c *WSClient) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.stopChan)
+		c.connMu.Lock()
+		if c.conn != nil {
+			c.conn.Close()
+		}
+		c.connMu.Unlock()
+	})
+	c.wg.Wait()
+}
+
+// Subscribe 订阅频道，未连接时仅记录，连接建立后自动订阅
+func (c *WSClient) Subscribe(args ...WSArg) error {
+	c.subsMu.Lock()
+	for _, arg := range args {
+		c.subs[arg.key()] = arg
+	}
+	c.subsMu.Unlock()
+
+	return c.sendOp("subscribe", args)
+}
+
+// Unsubscribe 取消订阅频道
+func (c *WSClient) Unsubscribe(args ...WSArg) error {
+	c.subsMu.Lock()
+	for _, arg := range args {
+		delete(c.subs, arg.key())
+	}
+	c.subsMu.Unlock()
+
+	return c.sendOp("unsubscribe", args)
+}
+
+// Connected 返回当前是否已建立连接
+func (c *WSClient) Connected() bool {
+	c.connMu.Lock()
+	defer c.connMu.Unlock()
+	return c.conn != nil
+}
+
+func (c *WSClient) sendOp(op string, args []WSArg) error {
+	c.connMu.Lock()
+	conn := c.conn
+	c.connMu.Unlock()
+	if conn == nil {
+		return nil
+	}
+
+	req := wsRequest{Op: op}
+	for _, arg := range args {
+		req.Args = append(req.Args, arg)
+	}
+	return c.writeJSON(conn, req)
+}
+
+func (c *WSClient) writeJSON(conn *websocket.Conn, v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteJSON(v)
+}
+
+func (c *WSClient) writeText(conn *websocket.Conn, text string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteMessage(websocket.TextMessage, []byte(text))
+}
+
+// run 连接循环，断开后按指数退避重连
+func (c *WSClient) run() {
+	defer c.wg.Done()
+
+	delay := time.Second
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		default:
+		}
+
+		conn, err := c.connect()
+		if err != nil {
+			log.Printf("[WS] 连接 %s 失败: %v, %v后重试", c.url, err, delay)
+			select {
+			case <-c.stopChan:
+				return
+			case <-time.After(delay):
+			}
+			delay *= 2
+			if delay > wsMaxReconnDelay {
+				delay = wsMaxReconnDelay
+			}
+			continue
+		}
+		delay = time.Second
+
+		c.readLoop(conn)
+
+		c.connMu.Lock()
+		c.conn = nil
+		c.connMu.Unlock()
+		conn.Close()
+		log.Printf("[WS] 连接 %s 已断开", c.url)
+		if c.onDisconnect != nil {
+			c.onDisconnect()
+		}
+	}
+}
+
+// connect 建立连接，执行登录并恢复订阅
+func (c *WSClient) connect() (*websocket.Conn, error) {
+	conn, _, err := c.dialer.Dial(c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.onConnect != nil {
+		if err := c.onConnect(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	c.connMu.Lock()
+	select {
+	case <-c.stopChan:
+		c.connMu.Unlock()
+		conn.Close()
+		return nil, fmt.Errorf("客户端已停止")
+	default:
+	}
+	c.conn = conn
+	c.connMu.Unlock()
+
+	c.subsMu.Lock()
+	args := make([]WSArg, 0, len(c.subs))
+	for _, arg := range c.subs {
+		args = append(args, arg)
+	}
+	c.subsMu.Unlock()
+
+	if len(args) > 0 {
+		if err := c.sendOp("subscribe", args); err != nil {
+			c.connMu.Lock()
+			c.conn = nil
+			c.connMu.Unlock()
+			conn.Close()
+			return nil, fmt.Errorf("恢复订阅失败: %v", err)
+		}
+	}
+
+	log.Printf("[WS] 已连接 %s, 订阅频道数: %d", c.url, len(args))
+	return conn, nil
+}
+
+// readLoop 读取消息直到连接出错，期间定时发送ping保活
+func (c *WSClient) readLoop(conn *websocket.Conn) {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		ticker := time.NewTicker(wsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := c.writeText(conn, "ping"); err != nil {
+					log.Printf("[WS] 发送ping失败: %v", err)
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(wsReadTimeout))
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-c.stopChan:
+			default:
+				log.Printf("[WS] 读取消息失败: %v", err)
+			}
+			return
+		}
+
+		if string(data) == "pong" {
+			continue
+		}
+
+		var msg WSMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.Printf("[WS] 解析消息失败: %v, 内容: %s", err, string(data))
+			continue
+		}
+
+		switch msg.Event {
+		case "subscribe", "unsubscribe", "login", "channel-conn-count":
+			continue
+		case "error":
+			log.Printf("[WS] 服务端错误: %s (错误码: %s)", msg.Msg, msg.Code)
+			continue
+		}
+
+		if c.handler != nil && len(msg.Data) > 0 {
+			c.handler(&msg)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+// Package candles 提供带本地缓存的历史K线服务，供策略预热和回测使用
+package candles
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"okxauto/internal/api"
+	"okxauto/internal/database"
+	"okxauto/internal/database/models"
+)
+
+// 单次请求history-candles的最大数量
+const pageLimit = 100
+
+// emptyRangeDelay 交易所没有返回K线的区间，结束时间早于该时长的部分才记录为空区间，
+// 最近的K线可能还未出现在history-candles中
+const emptyRangeDelay = time.Hour
+
+// barDurations OKX支持的K线周期
+var barDurations = map[string]time.Duration{
+	"1m":     time.Minute,
+	"3m":     3 * time.Minute,
+	"5m":     5 * time.Minute,
+	"15m":    15 * time.Minute,
+	"30m":    30 * time.Minute,
+	"1H":     time.Hour,
+	"2H":     2 * time.Hour,
+	"4H":     4 * time.Hour,
+	"6H":     6 * time.Hour,
+	"12H":    12 * time.Hour,
+	"1D":     24 * time.Hour,
+	"1W":     7 * 24 * time.Hour,
+	"6Hutc":  6 * time.Hour,
+	"12Hutc": 12 * time.Hour,
+	"1Dutc":  24 * time.Hour,
+	"1Wutc":  7 * 24 * time.Hour,
+}
+
+// BarDuration 返回K线周期的时长
+func BarDuration(bar string) (time.Duration, error) {
+	d, ok := barDurations[bar]
+	if !ok {
+		return 0, fmt.Errorf("不支持的K线周期: %s", bar)
+	}
+	return d, nil
+}
+
+// Service 历史K线服务，优先读取SQLite缓存，缺失部分分页请求交易所后写入缓存。
+// 只缓存已完结的K线
+type Service struct {
+	api api.Exchange
+	db  *database.Database
+}
+
+// NewService 创建K线服务
+func NewService(apiClient api.Exchange, db *database.Database) *Service {
+	return &Service{api: apiClient, db: db}
+}
+
+// Load 返回 [from, to) 范围内已完结的K线(按时间升序)，缓存缺失的部分先从交易所补齐
+func (s *Service) Load(ctx context.Context, symbol, bar string, from, to time.Time) ([]*models.Candle, error) {
+	if _, err := s.Backfill(ctx, symbol, bar, from, to); err != nil {
+		return nil, err
+	}
+	return s.db.GetCandles(symbol, bar, from.UnixMilli(), to.UnixMilli())
+}
+
+// Recent 返回最近n根已完结的K线(按时间升序)，用于策略预热
+func (s *Service) Recent(ctx context.Context, symbol, bar string, n int) ([]*models.Candle, error) {
+	d, err := BarDuration(bar)
+	if err != nil {
+		return nil, err
+	}
+	to := time.Now()
+	// 多取一根以覆盖未对齐的起点
+	candles, err := s.Load(ctx, symbol, bar, to.Add(-time.Duration(n+1)*d), to)
+	if err != nil {
+		return nil, err
+	}
+	if len(candles) > n {
+		candles = candles[len(candles)-n:]
+	}
+	return candles, nil
+}
+
+// Backfill 查找 [from, to) 范围内缓存的缺口并从交易所补齐，返回新写入的K线数量。
+// 补齐后仍缺失的区间(上市之前、停牌等)记录为空区间，之后不再请求
+func (s *Service) Backfill(ctx context.Context, symbol, bar string, from, to time.Time) (int, error) {
+	d, err := BarDuration(bar)
+	if err != nil {
+		return 0, err
+	}
+	if now := time.Now(); to.After(now) {
+		to = now
+	}
+	if !from.Before(to) {
+		return 0, nil
+	}
+
+	fromMs, toMs := from.UnixMilli(), to.UnixMilli()
+	timestamps, err := s.db.GetCandleTimestamps(symbol, bar, fromMs, toMs)
+	if err != nil {
+		return 0, err
+	}
+	empty, err := s.db.GetEmptyCandleRanges(symbol, bar, fromMs, toMs)
+	if err != nil {
+		return 0, err
+	}
+
+	saved := 0
+	for _, g := range subtractEmpty(findGaps(timestamps, fromMs, toMs, d.Milliseconds()), empty) {
+		n, err := s.fetchRange(ctx, symbol, bar, g.after, g.before, d)
+		saved += n
+		if err != nil {
+			return saved, err
+		}
+		if err := s.markEmpty(symbol, bar, g, d); err != nil {
+			return saved, err
+		}
+	}
+	if saved > 0 {
+		log.Printf("[%s] 补齐%s K线 %d 根 (%s ~ %s)", symbol, bar, saved,
+			from.Format("2006-01-02 15:04"), to.Format("2006-01-02 15:04"))
+	}
+	return saved, nil
+}
+
+// gap 缺失的开盘时间区间 (after, before)，两端不含
+type gap struct {
+	after  int64
+	before int64
+}
+
+// findGaps 根据已缓存的开盘时间(升序)找出 [from, to) 内缺失的已完结K线区间
+func findGaps(timestamps []int64, from, to, barMs int64) []gap {
+	if len(timestamps) == 0 {
+		return []gap{{after: from - 1, before: to}}
+	}
+
+	var gaps []gap
+	// 第一根之前还能放下一根K线
+	if first := timestamps[0]; first-barMs >= from {
+		gaps = append(gaps, gap{after: from - 1, before: first})
+	}
+	for i := 1; i < len(timestamps); i++ {
+		if timestamps[i]-timestamps[i-1] > barMs {
+			gaps = append(gaps, gap{after: timestamps[i-1], before: timestamps[i]})
+		}
+	}
+	// 最后一根之后还有已完结的K线
+	if last := timestamps[len(timestamps)-1]; last+2*barMs <= to {
+		gaps = append(gaps, gap{after: last, before: to})
+	}
+	return gaps
+}
+
+// subtractEmpty 从缺口中去掉已确认没有K线的空区间(按起始时间升序)
+func subtractEmpty(gaps []gap, empty []*models.CandleRange) []gap {
+	if len(empty) == 0 {
+		return gaps
+	}
+	var result []gap
+	for _, g := range gaps {
+		// 缺口内缺失的开盘时间为 [lo, hi)
+		lo, hi := g.after+1, g.before
+		for _, r := range empty {
+			if r.To <= lo || r.From >= hi {
+				continue
+			}
+			if r.From > lo {
+				result = append(result, gap{after: lo - 1, before: r.From})
+			}
+			lo = r.To
+			if lo >= hi {
+				break
+			}
+		}
+		if lo < hi {
+			result = append(result, gap{after: lo - 1, before: hi})
+		}
+	}
+	return result
+}
+
+// markEmpty 缺口补齐后仍缺失的部分说明交易所没有这段K线，记录为空区间；
+// 结束时间在emptyRangeDelay以内的部分不记录，下次加载时重新请求
+func (s *Service) markEmpty(symbol, bar string, g gap, d time.Duration) error {
+	timestamps, err := s.db.GetCandleTimestamps(symbol, bar, g.after+1, g.before)
+	if err != nil {
+		return err
+	}
+	limit := time.Now().Add(-emptyRangeDelay).UnixMilli()
+	for _, rest := range findGaps(timestamps, g.after+1, g.before, d.Milliseconds()) {
+		r := &models.CandleRange{Symbol: symbol, Bar: bar, From: rest.after + 1, To: rest.before}
+		if r.To > limit {
+			r.To = limit
+		}
+		if r.From >= r.To {
+			continue
+		}
+		if err := s.db.SaveEmptyCandleRange(r); err != nil {
+			return err
+		}
+		log.Printf("[%s] 交易所没有%s K线 (%s ~ %s)，记录为空区间", symbol, bar,
+			time.UnixMilli(r.From).Format("2006-01-02 15:04"), time.UnixMilli(r.To).Format("2006-01-02 15:04"))
+	}
+	return nil
+}
+
+// fetchRange 从before向前分页请求，直到越过after或没有更多数据
+func (s *Service) fetchRange(ctx context.Context, symbol, bar string, after, before int64, d time.Duration) (int, error) {
+	saved := 0
+	cursor := before
+	for cursor > after {
+		page, err := s.api.GetHistoryCandles(ctx, symbol, bar, cursor, after, pageLimit)
+		if err != nil {
+			return saved, fmt.Errorf("获取历史K线失败: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+
+		candles := make([]*models.Candle, 0, len(page))
+		oldest := cursor
+		for _, c := range page {
+			ts, err := strconv.ParseInt(c.Timestamp, 10, 64)
+			if err != nil {
+				continue
+			}
+			if ts < oldest {
+				oldest = ts
+			}
+			if c.Confirm == "0" || time.UnixMilli(ts).Add(d).After(time.Now()) {
+				continue
+			}
+			candles = append(candles, toModel(symbol, bar, ts, c))
+		}
+		if err := s.db.SaveCandles(candles); err != nil {
+			return saved, err
+		}
+		saved += len(candles)
+
+		if oldest >= cursor {
+			break
+		}
+		cursor = oldest
+	}
+	return saved, nil
+}
+
+func toModel(symbol, bar string, ts int64, c api.Candle) *models.Candle {
+	m := &models.Candle{Symbol: symbol, Bar: bar, Ts: ts}
+	m.Open, _ = strconv.ParseFloat(c.Open, 64)
+	m.High, _ = strconv.ParseFloat(c.High, 64)
+	m.Low, _ = strconv.ParseFloat(c.Low, 64)
+	m.Close, _ = strconv.ParseFloat(c.Close, 64)
+	m.Volume, _ = strconv.ParseFloat(c.Volume, 64)
+	return m
+}
@@ -0,0 +1,236 @@
+package candles
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"okxauto/internal/api"
+	"okxauto/internal/database"
+	"okxauto/internal/database/models"
+)
+
+const minute = int64(time.Minute / time.Millisecond)
+
+// stubCandles 按OKX history-candles的语义返回K线：after之前、before之后，按时间倒序
+type stubCandles struct {
+	api.Exchange
+	ts    []int64 // 交易所有数据的开盘时间，升序
+	calls int
+}
+
+func (s *stubCandles) GetHistoryCandles(ctx context.Context, instId, bar string, after, before int64, limit int) ([]api.Candle, error) {
+	s.calls++
+	var page []api.Candle
+	for i := len(s.ts) - 1; i >= 0 && len(page) < limit; i-- {
+		ts := s.ts[i]
+		if (after > 0 && ts >= after) || (before > 0 && ts <= before) {
+			continue
+		}
+		px := strconv.FormatInt(ts/minute%1000, 10)
+		page = append(page, api.Candle{Timestamp: strconv.FormatInt(ts, 10), Open: px, High: px, Low: px, Close: px, Volume: "1", Confirm: "1"})
+	}
+	return page, nil
+}
+
+// minutes 返回 [from, to) 内每分钟的开盘时间
+func minutes(from, to int64) []int64 {
+	var ts []int64
+	for t := from; t < to; t += minute {
+		ts = append(ts, t)
+	}
+	return ts
+}
+
+func newTestService(t *testing.T, stub *stubCandles) *Service {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "trades.db"))
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	return NewService(stub, db)
+}
+
+func TestFindGaps(t *testing.T) {
+	tests := []struct {
+		name       string
+		timestamps []int64
+		from, to   int64
+		want       []gap
+	}{
+		{"没有缓存", nil, 0, 10 * minute, []gap{{-1, 10 * minute}}},
+		{"完整无缺口", minutes(0, 10*minute), 0, 10 * minute, nil},
+		{"缺少开头", minutes(3*minute, 10*minute), 0, 10 * minute, []gap{{-1, 3 * minute}}},
+		{"中间缺口", []int64{0, minute, 5 * minute, 6 * minute, 7 * minute, 8 * minute, 9 * minute}, 0, 10 * minute,
+			[]gap{{minute, 5 * minute}}},
+		{"缺少结尾", minutes(0, 5*minute), 0, 10 * minute, []gap{{4 * minute, 10 * minute}}},
+		// 最后一根之后只有未完结的K线
+		{"结尾未完结", minutes(0, 9*minute), 0, 10*minute - 1, nil},
+		// 起点未对齐时第一根之前放不下一根K线
+		{"起点未对齐", minutes(minute, 10*minute), 30000, 10 * minute, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findGaps(tt.timestamps, tt.from, tt.to, minute); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubtractEmpty(t *testing.T) {
+	r := func(from, to int64) *models.CandleRange { return &models.CandleRange{From: from, To: to} }
+	tests := []struct {
+		name  string
+		gaps  []gap
+		empty []*models.CandleRange
+		want  []gap
+	}{
+		{"没有空区间", []gap{{-1, 10}}, nil, []gap{{-1, 10}}},
+		{"完全覆盖", []gap{{-1, 10}}, []*models.CandleRange{r(-5, 20)}, nil},
+		{"覆盖开头", []gap{{-1, 10}}, []*models.CandleRange{r(0, 4)}, []gap{{3, 10}}},
+		{"覆盖中间", []gap{{-1, 10}}, []*models.CandleRange{r(3, 5), r(6, 8)}, []gap{{-1, 3}, {4, 6}, {7, 10}}},
+		{"不重叠", []gap{{-1, 10}, {20, 30}}, []*models.CandleRange{r(12, 18)}, []gap{{-1, 10}, {20, 30}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subtractEmpty(tt.gaps, tt.empty); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchRange(t *testing.T) {
+	base := time.Now().Truncate(time.Minute).Add(-10 * time.Hour).UnixMilli()
+	stub := &stubCandles{ts: minutes(base, base+300*minute)}
+	s := newTestService(t, stub)
+
+	// 缺口 (base+9分钟, base+260分钟) 分3页拉取，第4页为空时停止
+	n, err := s.fetchRange(context.Background(), "BTC-USDT", "1m", base+9*minute, base+260*minute, time.Minute)
+	if err != nil {
+		t.Fatalf("补齐失败: %v", err)
+	}
+	if n != 250 {
+		t.Fatalf("写入 %d 根, 期望 250 根", n)
+	}
+	if stub.calls != 4 {
+		t.Fatalf("请求 %d 次, 期望 4 次", stub.calls)
+	}
+	timestamps, _ := s.db.GetCandleTimestamps("BTC-USDT", "1m", 0, base+300*minute)
+	if !reflect.DeepEqual(timestamps, minutes(base+10*minute, base+260*minute)) {
+		t.Fatalf("缓存的K线 %d 根, 首尾不符", len(timestamps))
+	}
+}
+
+func TestFetchRangeSkipsUnconfirmed(t *testing.T) {
+	now := time.Now().Truncate(time.Minute).UnixMilli()
+	stub := &stubCandles{ts: minutes(now-5*minute, now+minute)}
+	s := newTestService(t, stub)
+
+	// 当前这一分钟的K线尚未完结，不写入缓存
+	n, err := s.fetchRange(context.Background(), "BTC-USDT", "1m", now-6*minute, now+minute, time.Minute)
+	if err != nil || n != 5 {
+		t.Fatalf("写入 %d 根, 错误 %v; 期望 5 根", n, err)
+	}
+}
+
+func TestBackfillSkipsConfirmedEmptyRanges(t *testing.T) {
+	base := time.Now().Truncate(time.Minute).UnixMilli()
+	tests := []struct {
+		name       string
+		exchange   []int64 // 交易所有数据的开盘时间
+		from, to   int64
+		wantSaved  int
+		wantRanges int
+		wantRetry  bool // 第二次加载是否重新请求
+	}{
+		{
+			name:     "上市之前没有K线",
+			exchange: minutes(base-180*minute, base),
+			from:     base - 240*minute, to: base - 120*minute,
+			wantSaved: 60, wantRanges: 1,
+		},
+		{
+			name: "停牌期间没有K线",
+			exchange: append(minutes(base-240*minute, base-170*minute),
+				minutes(base-150*minute, base)...),
+			from: base - 240*minute, to: base - 120*minute,
+			wantSaved: 100, wantRanges: 1,
+		},
+		{
+			name:     "交易所完全没有数据",
+			exchange: nil,
+			from:     base - 240*minute, to: base - 120*minute,
+			wantRanges: 1,
+		},
+		{
+			// 最近的K线可能还未出现在history-candles中，不记录为空区间
+			name:     "最近的区间不记录",
+			exchange: minutes(base-240*minute, base-40*minute),
+			from:     base - 60*minute, to: base - 10*minute,
+			wantSaved: 20, wantRetry: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubCandles{ts: tt.exchange}
+			s := newTestService(t, stub)
+			ctx := context.Background()
+			from, to := time.UnixMilli(tt.from), time.UnixMilli(tt.to)
+
+			n, err := s.Backfill(ctx, "BTC-USDT", "1m", from, to)
+			if err != nil || n != tt.wantSaved {
+				t.Fatalf("第一次补齐写入 %d 根, 错误 %v; 期望 %d 根", n, err, tt.wantSaved)
+			}
+			ranges, _ := s.db.GetEmptyCandleRanges("BTC-USDT", "1m", tt.from, tt.to)
+			if len(ranges) != tt.wantRanges {
+				t.Fatalf("空区间 %d 个, 期望 %d 个", len(ranges), tt.wantRanges)
+			}
+
+			calls := stub.calls
+			candles, err := s.Load(ctx, "BTC-USDT", "1m", from, to)
+			if err != nil || len(candles) != tt.wantSaved {
+				t.Fatalf("加载 %d 根, 错误 %v", len(candles), err)
+			}
+			if retried := stub.calls > calls; retried != tt.wantRetry {
+				t.Fatalf("第二次加载请求 %d 次, 期望重新请求: %v", stub.calls-calls, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestBackfillKeepsEmptyRangesSorted(t *testing.T) {
+	base := time.Now().Truncate(time.Minute).UnixMilli()
+	stub := &stubCandles{ts: minutes(base-300*minute, base-290*minute)}
+	s := newTestService(t, stub)
+	ctx := context.Background()
+
+	// 先加载较晚的区间，再加载覆盖两端的更大区间
+	if _, err := s.Backfill(ctx, "BTC-USDT", "1m", time.UnixMilli(base-200*minute), time.UnixMilli(base-150*minute)); err != nil {
+		t.Fatalf("补齐失败: %v", err)
+	}
+	if _, err := s.Backfill(ctx, "BTC-USDT", "1m", time.UnixMilli(base-320*minute), time.UnixMilli(base-120*minute)); err != nil {
+		t.Fatalf("补齐失败: %v", err)
+	}
+	ranges, _ := s.db.GetEmptyCandleRanges("BTC-USDT", "1m", base-320*minute, base-120*minute)
+	if !sort.SliceIsSorted(ranges, func(i, j int) bool { return ranges[i].From < ranges[j].From }) {
+		t.Fatal("空区间应按起始时间升序返回")
+	}
+
+	calls := stub.calls
+	if _, err := s.Backfill(ctx, "BTC-USDT", "1m", time.UnixMilli(base-320*minute), time.UnixMilli(base-120*minute)); err != nil {
+		t.Fatalf("补齐失败: %v", err)
+	}
+	if stub.calls != calls {
+		t.Fatalf("区间已全部确认后不应再请求, 实际请求 %d 次", stub.calls-calls)
+	}
+}
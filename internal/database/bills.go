@@ -0,0 +1,131 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"okxauto/internal/database/models"
+)
+
+// SaveBills 批量保存账单，已存在的账单ID会被忽略，返回新写入的数量；未指定账户的账单归属视图所属账户
+func (db *Database) SaveBills(bills []*models.Bill) (int, error) {
+	if len(bills) == 0 {
+		return 0, nil
+	}
+
+	account := db.billAccount()
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("开始事务失败: %v", err)
+	}
+	stmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO bills (
+			account, bill_id, inst_id, inst_type, ccy, type, sub_type,
+			bal_chg, bal, sz, px, pnl, fee, order_id, trade_id, ts
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return 0, fmt.Errorf("准备保存账单失败: %v", err)
+	}
+	defer stmt.Close()
+
+	inserted := 0
+	for _, b := range bills {
+		if b.Account == "" {
+			b.Account = account
+		}
+		result, err := stmt.Exec(b.Account, b.BillID, b.InstID, b.InstType, b.Ccy, b.Type, b.SubType,
+			b.BalChg, b.Bal, b.Sz, b.Px, b.Pnl, b.Fee, b.OrderID, b.TradeID, b.Ts)
+		if err != nil {
+			tx.Rollback()
+			return 0, fmt.Errorf("保存账单失败: %v", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			inserted++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交账单失败: %v", err)
+	}
+	return inserted, nil
+}
+
+// LatestBillTime 返回视图所属账户最新账单的时间(毫秒)，没有账单时返回0
+func (db *Database) LatestBillTime() (int64, error) {
+	var ts sql.NullInt64
+	query := "SELECT MAX(ts) FROM bills WHERE " + accountFilter
+	if err := db.db.QueryRow(query, db.account, db.account).Scan(&ts); err != nil {
+		return 0, fmt.Errorf("查询最新账单时间失败: %v", err)
+	}
+	return ts.Int64, nil
+}
+
+// GetBills 按时间升序返回 [from, to) 范围内的账单，时间为毫秒
+func (db *Database) GetBills(from, to int64) ([]*models.Bill, error) {
+	query := `
+		SELECT id, account, bill_id, inst_id, inst_type, ccy, type, sub_type,
+			bal_chg, bal, sz, px, pnl, fee, order_id, trade_id, ts
+		FROM bills
+		WHERE ts >= ? AND ts < ? AND ` + accountFilter + `
+		ORDER BY ts ASC, bill_id ASC`
+
+	rows, err := db.db.Query(query, from, to, db.account, db.account)
+	if err != nil {
+		return nil, fmt.Errorf("查询账单失败: %v", err)
+	}
+	defer rows.Close()
+
+	var bills []*models.Bill
+	for rows.Next() {
+		b := &models.Bill{}
+		if err := rows.Scan(&b.ID, &b.Account, &b.BillID, &b.InstID, &b.InstType, &b.Ccy, &b.Type, &b.SubType,
+			&b.BalChg, &b.Bal, &b.Sz, &b.Px, &b.Pnl, &b.Fee, &b.OrderID, &b.TradeID, &b.Ts); err != nil {
+			return nil, fmt.Errorf("扫描账单失败: %v", err)
+		}
+		bills = append(bills, b)
+	}
+	return bills, rows.Err()
+}
+
+// billAccount 账单写入的账户，汇总视图写入default账户
+func (db *Database) billAccount() string {
+	if db.account == "" {
+		return DefaultAccount
+	}
+	return db.account
+}
+
+// GetBillCursor 返回视图所属账户未完成的账单同步进度，没有时返回nil
+func (db *Database) GetBillCursor() (*models.BillCursor, error) {
+	c := &models.BillCursor{}
+	err := db.db.QueryRow("SELECT account, after_id, until_ts FROM bill_cursors WHERE account = ?", db.billAccount()).
+		Scan(&c.Account, &c.AfterID, &c.UntilTs)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询账单同步进度失败: %v", err)
+	}
+	return c, nil
+}
+
+// SaveBillCursor 保存视图所属账户的账单同步进度，覆盖已有的进度
+func (db *Database) SaveBillCursor(c *models.BillCursor) error {
+	c.Account = db.billAccount()
+	_, err := db.db.Exec("INSERT OR REPLACE INTO bill_cursors (account, after_id, until_ts) VALUES (?, ?, ?)",
+		c.Account, c.AfterID, c.UntilTs)
+	if err != nil {
+		return fmt.Errorf("保存账单同步进度失败: %v", err)
+	}
+	return nil
+}
+
+// DeleteBillCursor 同步完成后删除视图所属账户的账单同步进度
+func (db *Database) DeleteBillCursor() error {
+	if _, err := db.db.Exec("DELETE FROM bill_cursors WHERE account = ?", db.billAccount()); err != nil {
+		return fmt.Errorf("删除账单同步进度失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package database
+
+import (
+	"fmt"
+
+	"okxauto/internal/database/models"
+)
+
+// SaveCandles 批量保存K线，已存在的 symbol/bar/ts 会被覆盖
+func (db *Database) SaveCandles(candles []*models.Candle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %v", err)
+	}
+	stmt, err := tx.Prepare(`
+		INSERT INTO candles (symbol, bar, ts, open, high, low, close, volume)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(symbol, bar, ts) DO UPDATE SET
+			open = excluded.open, high = excluded.high, low = excluded.low,
+			close = excluded.close, volume = excluded.volume`)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("准备保存K线失败: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, c := range candles {
+		if _, err := stmt.Exec(c.Symbol, c.Bar, c.Ts, c.Open, c.High, c.Low, c.Close, c.Volume); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("保存K线失败: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交K线失败: %v", err)
+	}
+	return nil
+}
+
+// GetCandles 按开盘时间升序返回 [from, to) 范围内的K线，时间为毫秒
+func (db *Database) GetCandles(symbol, bar string, from, to int64) ([]*models.Candle, error) {
+	query := `
+		SELECT symbol, bar, ts, open, high, low, close, volume
+		FROM candles
+		WHERE symbol = ? AND bar = ? AND ts >= ? AND ts < ?
+		ORDER BY ts ASC`
+
+	rows, err := db.db.Query(query, symbol, bar, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("查询K线失败: %v", err)
+	}
+	defer rows.Close()
+
+	var candles []*models.Candle
+	for rows.Next() {
+		c := &models.Candle{}
+		if err := rows.Scan(&c.Symbol, &c.Bar, &c.Ts, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume); err != nil {
+			return nil, fmt.Errorf("扫描K线失败: %v", err)
+		}
+		candles = append(candles, c)
+	}
+	return candles, rows.Err()
+}
+
+// GetCandleTimestamps 按升序返回 [from, to) 范围内已缓存K线的开盘时间，用于查找缺口
+func (db *Database) GetCandleTimestamps(symbol, bar string, from, to int64) ([]int64, error) {
+	rows, err := db.db.Query(
+		"SELECT ts FROM candles WHERE symbol = ? AND bar = ? AND ts >= ? AND ts < ? ORDER BY ts ASC",
+		symbol, bar, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("查询K线时间失败: %v", err)
+	}
+	defer rows.Close()
+
+	var timestamps []int64
+	for rows.Next() {
+		var ts int64
+		if err := rows.Scan(&ts); err != nil {
+			return nil, fmt.Errorf("扫描K线时间失败: %v", err)
+		}
+		timestamps = append(timestamps, ts)
+	}
+	return timestamps, rows.Err()
+}
+
+// SaveEmptyCandleRange 记录交易所没有K线数据的区间，之后查找缺口时跳过
+func (db *Database) SaveEmptyCandleRange(r *models.CandleRange) error {
+	_, err := db.db.Exec("INSERT INTO candle_empty_ranges (symbol, bar, from_ts, to_ts) VALUES (?, ?, ?, ?)",
+		r.Symbol, r.Bar, r.From, r.To)
+	if err != nil {
+		return fmt.Errorf("保存K线空区间失败: %v", err)
+	}
+	return nil
+}
+
+// GetEmptyCandleRanges 按起始时间升序返回与 [from, to) 重叠的K线空区间，时间为毫秒
+func (db *Database) GetEmptyCandleRanges(symbol, bar string, from, to int64) ([]*models.CandleRange, error) {
+	rows, err := db.db.Query(`
+		SELECT symbol, bar, from_ts, to_ts FROM candle_empty_ranges
+		WHERE symbol = ? AND bar = ? AND from_ts < ? AND to_ts > ?
+		ORDER BY from_ts ASC`, symbol, bar, to, from)
+	if err != nil {
+		return nil, fmt.Errorf("查询K线空区间失败: %v", err)
+	}
+	defer rows.Close()
+
+	var ranges []*models.CandleRange
+	for rows.Next() {
+		r := &models.CandleRange{}
+		if err := rows.Scan(&r.Symbol, &r.Bar, &r.From, &r.To); err != nil {
+			return nil, fmt.Errorf("扫描K线空区间失败: %v", err)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, rows.Err()
+}
@@ -5,14 +5,21 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"okxauto/internal/database/models"
 )
 
+// DefaultAccount 未配置多账户时交易记录所属的账户
+const DefaultAccount = "default"
+
 // Database 数据库结构
 type Database struct {
 	db *sql.DB
+
+	// account 交易记录所属账户，为空时查询所有账户的汇总数据
+	account string
 }
 
 // New 创建数据库连接
@@ -34,6 +41,20 @@ func (db *Database) Close() error {
 	return db.db.Close()
 }
 
+// ForAccount 返回按账户划分的数据库视图，共享同一连接，写入的交易记录归属该账户，
+// 查询只返回该账户的记录；K线等行情数据不区分账户
+func (db *Database) ForAccount(account string) *Database {
+	return &Database{db: db.db, account: account}
+}
+
+// Account 返回视图所属账户，为空表示汇总视图
+func (db *Database) Account() string {
+	return db.account
+}
+
+// accountFilter 按账户过滤的条件，参数为两次db.account，汇总视图不过滤
+const accountFilter = "(? = '' OR account = ?)"
+
 // 定义表结构
 type TableColumn struct {
 	Name     string
@@ -52,6 +73,7 @@ func (db *Database) Initialize() error {
 	tables := map[string][]TableColumn{
 		"trades": {
 			{Name: "id", Type: "INTEGER", PrimaryKey: true},
+			{Name: "account", Type: "TEXT", NotNull: true, Default: "'default'"},
 			{Name: "symbol", Type: "TEXT", NotNull: true},
 			{Name: "side", Type: "TEXT", NotNull: true},
 			{Name: "price", Type: "REAL", NotNull: true},
@@ -60,8 +82,52 @@ func (db *Database) Initialize() error {
 			{Name: "status", Type: "TEXT", NotNull: true},
 			{Name: "order_id", Type: "TEXT", NotNull: true},
 			{Name: "trade_type", Type: "TEXT", NotNull: true, Default: "'spot'"},
+			{Name: "fill_price", Type: "REAL", NotNull: true, Default: "0"},
+			{Name: "fill_amount", Type: "REAL", NotNull: true, Default: "0"},
+			{Name: "fee", Type: "REAL", NotNull: true, Default: "0"},
+			{Name: "fee_ccy", Type: "TEXT", NotNull: true, Default: "''"},
 			{Name: "created_at", Type: "DATETIME", NotNull: true},
 		},
+		"fills": {
+			{Name: "id", Type: "INTEGER", PrimaryKey: true},
+			{Name: "account", Type: "TEXT", NotNull: true, Default: "'default'"},
+			{Name: "symbol", Type: "TEXT", NotNull: true},
+			{Name: "trade_id", Type: "TEXT", NotNull: true},
+			{Name: "order_id", Type: "TEXT", NotNull: true},
+			{Name: "side", Type: "TEXT", NotNull: true},
+			{Name: "pos_side", Type: "TEXT", NotNull: true, Default: "''"},
+			{Name: "fill_price", Type: "REAL", NotNull: true},
+			{Name: "fill_size", Type: "REAL", NotNull: true},
+			{Name: "fee", Type: "REAL", NotNull: true, Default: "0"},
+			{Name: "fee_ccy", Type: "TEXT", NotNull: true, Default: "''"},
+			{Name: "exec_type", Type: "TEXT", NotNull: true, Default: "''"},
+			{Name: "ts", Type: "INTEGER", NotNull: true},
+		},
+		"bills": {
+			{Name: "id", Type: "INTEGER", PrimaryKey: true},
+			{Name: "account", Type: "TEXT", NotNull: true, Default: "'default'"},
+			{Name: "bill_id", Type: "TEXT", NotNull: true},
+			{Name: "inst_id", Type: "TEXT", NotNull: true, Default: "''"},
+			{Name: "inst_type", Type: "TEXT", NotNull: true, Default: "''"},
+			{Name: "ccy", Type: "TEXT", NotNull: true},
+			{Name: "type", Type: "TEXT", NotNull: true},
+			{Name: "sub_type", Type: "TEXT", NotNull: true, Default: "''"},
+			{Name: "bal_chg", Type: "REAL", NotNull: true, Default: "0"},
+			{Name: "bal", Type: "REAL", NotNull: true, Default: "0"},
+			{Name: "sz", Type: "REAL", NotNull: true, Default: "0"},
+			{Name: "px", Type: "REAL", NotNull: true, Default: "0"},
+			{Name: "pnl", Type: "REAL", NotNull: true, Default: "0"},
+			{Name: "fee", Type: "REAL", NotNull: true, Default: "0"},
+			{Name: "order_id", Type: "TEXT", NotNull: true, Default: "''"},
+			{Name: "trade_id", Type: "TEXT", NotNull: true, Default: "''"},
+			{Name: "ts", Type: "INTEGER", NotNull: true},
+		},
+		"bill_cursors": {
+			{Name: "id", Type: "INTEGER", PrimaryKey: true},
+			{Name: "account", Type: "TEXT", NotNull: true, Default: "'default'"},
+			{Name: "after_id", Type: "TEXT", NotNull: true, Default: "''"},
+			{Name: "until_ts", Type: "INTEGER", NotNull: true, Default: "0"},
+		},
 		"users": {
 			{Name: "id", Type: "INTEGER", PrimaryKey: true},
 			{Name: "username", Type: "TEXT", NotNull: true, Unique: true},
@@ -77,6 +143,36 @@ func (db *Database) Initialize() error {
 			{Name: "price", Type: "REAL", NotNull: true},
 			{Name: "created_at", Type: "DATETIME", NotNull: true},
 		},
+		"candles": {
+			{Name: "id", Type: "INTEGER", PrimaryKey: true},
+			{Name: "symbol", Type: "TEXT", NotNull: true},
+			{Name: "bar", Type: "TEXT", NotNull: true},
+			{Name: "ts", Type: "INTEGER", NotNull: true},
+			{Name: "open", Type: "REAL", NotNull: true},
+			{Name: "high", Type: "REAL", NotNull: true},
+			{Name: "low", Type: "REAL", NotNull: true},
+			{Name: "close", Type: "REAL", NotNull: true},
+			{Name: "volume", Type: "REAL", NotNull: true},
+		},
+		"candle_empty_ranges": {
+			{Name: "id", Type: "INTEGER", PrimaryKey: true},
+			{Name: "symbol", Type: "TEXT", NotNull: true},
+			{Name: "bar", Type: "TEXT", NotNull: true},
+			{Name: "from_ts", Type: "INTEGER", NotNull: true},
+			{Name: "to_ts", Type: "INTEGER", NotNull: true},
+		},
+	}
+
+	// 需要组合唯一约束的索引，表创建后建立
+	indexes := []string{
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_candles_key ON candles(symbol, bar, ts)",
+		"CREATE INDEX IF NOT EXISTS idx_candle_empty_ranges ON candle_empty_ranges(symbol, bar, from_ts)",
+		"CREATE INDEX IF NOT EXISTS idx_trades_account ON trades(account)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_fills_key ON fills(account, symbol, trade_id)",
+		"CREATE INDEX IF NOT EXISTS idx_fills_order ON fills(order_id)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_bills_key ON bills(account, bill_id)",
+		"CREATE INDEX IF NOT EXISTS idx_bills_ts ON bills(account, ts)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_bill_cursors_account ON bill_cursors(account)",
 	}
 
 	// 检查并创建每个表
@@ -85,11 +181,80 @@ func (db *Database) Initialize() error {
 			return fmt.Errorf("确保表 %s 存在失败: %v", tableName, err)
 		}
 	}
+	for _, index := range indexes {
+		if _, err := db.db.Exec(index); err != nil {
+			return fmt.Errorf("创建索引失败: %v", err)
+		}
+	}
 
 	log.Println("数据库初始化完成")
 	return nil
 }
 
+// accountTables 按账户划分记录的表
+var accountTables = []string{"trades", "fills", "bills"}
+
+// MigrateLegacyAccount 处理升级到多账户之前写入的记录，这些记录在添加account列时归属default账户。
+// 配置中只有一个账户且名称不是default时，把这些记录转给该账户，否则该账户查询不到升级前的
+// 持仓；配置了多个账户时无法判断归属，只记录警告，需要手动执行日志中的UPDATE语句
+func (db *Database) MigrateLegacyAccount(accounts []string) error {
+	for _, name := range accounts {
+		if name == DefaultAccount {
+			return nil
+		}
+	}
+
+	counts := make(map[string]int)
+	total := 0
+	for _, table := range accountTables {
+		var n int
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE account = ?", table)
+		if err := db.db.QueryRow(query, DefaultAccount).Scan(&n); err != nil {
+			return fmt.Errorf("统计表 %s 的default账户记录失败: %v", table, err)
+		}
+		counts[table] = n
+		total += n
+	}
+	if total == 0 {
+		return nil
+	}
+
+	if len(accounts) != 1 {
+		log.Printf("警告: 数据库中有 %d 条交易、%d 条成交、%d 条账单属于default账户，配置中没有该账户，这些记录不会被任何账户查询到",
+			counts["trades"], counts["fills"], counts["bills"])
+		for _, table := range accountTables {
+			if counts[table] > 0 {
+				log.Printf("请确认归属后手动执行: UPDATE %s SET account = '<账户名>' WHERE account = '%s'", table, DefaultAccount)
+			}
+		}
+		return nil
+	}
+
+	account := accounts[0]
+	tx, err := db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %v", err)
+	}
+	defer tx.Rollback()
+	for _, table := range accountTables {
+		// 升级后已重新同步过的成交和账单与旧记录重复，唯一索引冲突的旧记录直接删除
+		query := fmt.Sprintf("UPDATE OR IGNORE %s SET account = ? WHERE account = ?", table)
+		if _, err := tx.Exec(query, account, DefaultAccount); err != nil {
+			return fmt.Errorf("迁移表 %s 的default账户记录失败: %v", table, err)
+		}
+		query = fmt.Sprintf("DELETE FROM %s WHERE account = ?", table)
+		if _, err := tx.Exec(query, DefaultAccount); err != nil {
+			return fmt.Errorf("删除表 %s 的重复记录失败: %v", table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %v", err)
+	}
+	log.Printf("已将default账户的 %d 条交易、%d 条成交、%d 条账单迁移到账户 %s",
+		counts["trades"], counts["fills"], counts["bills"], account)
+	return nil
+}
+
 // ensureTable 确保表存在并且列完整
 func (db *Database) ensureTable(tableName string, requiredColumns []TableColumn) error {
 	// 检查表是否存在
@@ -202,10 +367,19 @@ func (db *Database) ensureColumns(tableName string, requiredColumns []TableColum
 func (db *Database) SaveTrade(trade *models.Trade) error {
 	query := `
 		INSERT INTO trades (
-			symbol, side, price, amount, strategy, status, order_id, trade_type, created_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+			account, symbol, side, price, amount, strategy, status, order_id, trade_type,
+			fill_price, fill_amount, fee, fee_ccy, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	if trade.Account == "" {
+		trade.Account = db.account
+	}
+	if trade.Account == "" {
+		trade.Account = DefaultAccount
+	}
 
 	result, err := db.db.Exec(query,
+		trade.Account,
 		trade.Symbol,
 		trade.Side,
 		trade.Price,
@@ -214,6 +388,10 @@ func (db *Database) SaveTrade(trade *models.Trade) error {
 		trade.Status,
 		trade.OrderID,
 		trade.TradeType,
+		trade.FillPrice,
+		trade.FillAmount,
+		trade.Fee,
+		trade.FeeCcy,
 		trade.CreatedAt,
 	)
 
@@ -222,29 +400,22 @@ func (db *Database) SaveTrade(trade *models.Trade) error {
 	}
 
 	id, _ := result.LastInsertId()
+	trade.ID = id
 	log.Printf("成功保存交易记录，ID=%d", id)
 	return nil
 }
 
-// GetTrades 获取交易记录
-func (db *Database) GetTrades(limit int) ([]*models.Trade, error) {
-	query := `
-		SELECT id, symbol, side, price, amount, strategy, status, order_id, trade_type, created_at
-		FROM trades
-		ORDER BY created_at DESC
-		LIMIT ?`
-
-	rows, err := db.db.Query(query, limit)
-	if err != nil {
-		return nil, fmt.Errorf("查询交易记录失败: %v", err)
-	}
-	defer rows.Close()
+// tradeColumns 查询交易记录的列，顺序与scanTrades一致
+const tradeColumns = "id, account, symbol, side, price, amount, strategy, status, order_id, trade_type, fill_price, fill_amount, fee, fee_ccy, created_at"
 
+// scanTrades 扫描交易记录
+func scanTrades(rows *sql.Rows) ([]*models.Trade, error) {
 	var trades []*models.Trade
 	for rows.Next() {
 		trade := &models.Trade{}
 		err := rows.Scan(
 			&trade.ID,
+			&trade.Account,
 			&trade.Symbol,
 			&trade.Side,
 			&trade.Price,
@@ -253,6 +424,10 @@ func (db *Database) GetTrades(limit int) ([]*models.Trade, error) {
 			&trade.Status,
 			&trade.OrderID,
 			&trade.TradeType,
+			&trade.FillPrice,
+			&trade.FillAmount,
+			&trade.Fee,
+			&trade.FeeCcy,
 			&trade.CreatedAt,
 		)
 		if err != nil {
@@ -264,82 +439,129 @@ func (db *Database) GetTrades(limit int) ([]*models.Trade, error) {
 	return trades, nil
 }
 
-// GetTradesBySymbol 根据交易对获取交易记录
-func (db *Database) GetTradesBySymbol(symbol string, limit int) ([]*models.Trade, error) {
+// UpdateTradeFill 按订单ID更新交易记录的状态和成交信息
+func (db *Database) UpdateTradeFill(orderID, status string, fillPrice, fillAmount, fee float64, feeCcy string) error {
 	query := `
-		SELECT id, symbol, side, price, amount, strategy, status, order_id, trade_type, created_at
+		UPDATE trades
+		SET status = ?, fill_price = ?, fill_amount = ?, fee = ?, fee_ccy = ?
+		WHERE order_id = ? AND ` + accountFilter
+
+	if _, err := db.db.Exec(query, status, fillPrice, fillAmount, fee, feeCcy, orderID, db.account, db.account); err != nil {
+		return fmt.Errorf("更新交易记录失败: %v", err)
+	}
+	return nil
+}
+
+// GetOpenTrades 获取尚未终结(live/partially_filled)的交易记录
+func (db *Database) GetOpenTrades() ([]*models.Trade, error) {
+	query := `
+		SELECT ` + tradeColumns + `
+		FROM trades
+		WHERE status IN ('live', 'partially_filled') AND ` + accountFilter + `
+		ORDER BY created_at ASC`
+
+	rows, err := db.db.Query(query, db.account, db.account)
+	if err != nil {
+		return nil, fmt.Errorf("查询未完成交易记录失败: %v", err)
+	}
+	defer rows.Close()
+
+	return scanTrades(rows)
+}
+
+// GetTrades 获取交易记录
+func (db *Database) GetTrades(limit int) ([]*models.Trade, error) {
+	query := `
+		SELECT ` + tradeColumns + `
 		FROM trades
-		WHERE symbol = ?
+		WHERE ` + accountFilter + `
 		ORDER BY created_at DESC
 		LIMIT ?`
 
-	rows, err := db.db.Query(query, symbol, limit)
+	rows, err := db.db.Query(query, db.account, db.account, limit)
 	if err != nil {
 		return nil, fmt.Errorf("查询交易记录失败: %v", err)
 	}
 	defer rows.Close()
 
-	var trades []*models.Trade
-	for rows.Next() {
-		trade := &models.Trade{}
-		err := rows.Scan(
-			&trade.ID,
-			&trade.Symbol,
-			&trade.Side,
-			&trade.Price,
-			&trade.Amount,
-			&trade.Strategy,
-			&trade.Status,
-			&trade.OrderID,
-			&trade.TradeType,
-			&trade.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("扫描交易记录失败: %v", err)
-		}
-		trades = append(trades, trade)
+	return scanTrades(rows)
+}
+
+// GetTradesBetween 按创建时间升序返回 [from, to) 范围内的交易记录
+func (db *Database) GetTradesBetween(from, to time.Time) ([]*models.Trade, error) {
+	query := `
+		SELECT ` + tradeColumns + `
+		FROM trades
+		WHERE created_at >= ? AND created_at < ? AND ` + accountFilter + `
+		ORDER BY created_at ASC`
+
+	rows, err := db.db.Query(query, from, to, db.account, db.account)
+	if err != nil {
+		return nil, fmt.Errorf("查询交易记录失败: %v", err)
 	}
+	defer rows.Close()
 
-	return trades, nil
+	return scanTrades(rows)
+}
+
+// GetTradesBySymbol 根据交易对获取交易记录
+func (db *Database) GetTradesBySymbol(symbol string, limit int) ([]*models.Trade, error) {
+	query := `
+		SELECT ` + tradeColumns + `
+		FROM trades
+		WHERE symbol = ? AND ` + accountFilter + `
+		ORDER BY created_at DESC
+		LIMIT ?`
+
+	rows, err := db.db.Query(query, symbol, db.account, db.account, limit)
+	if err != nil {
+		return nil, fmt.Errorf("查询交易记录失败: %v", err)
+	}
+	defer rows.Close()
+
+	return scanTrades(rows)
 }
 
 // GetTradesByStrategy 根据策略获取交易记录
 func (db *Database) GetTradesByStrategy(strategy string, limit int) ([]*models.Trade, error) {
 	query := `
-		SELECT id, symbol, side, price, amount, strategy, status, order_id, trade_type, created_at
+		SELECT ` + tradeColumns + `
 		FROM trades
-		WHERE strategy = ?
+		WHERE strategy = ? AND ` + accountFilter + `
 		ORDER BY created_at DESC
 		LIMIT ?`
 
-	rows, err := db.db.Query(query, strategy, limit)
+	rows, err := db.db.Query(query, strategy, db.account, db.account, limit)
 	if err != nil {
 		return nil, fmt.Errorf("查询交易记录失败: %v", err)
 	}
 	defer rows.Close()
 
-	var trades []*models.Trade
-	for rows.Next() {
-		trade := &models.Trade{}
-		err := rows.Scan(
-			&trade.ID,
-			&trade.Symbol,
-			&trade.Side,
-			&trade.Price,
-			&trade.Amount,
-			&trade.Strategy,
-			&trade.Status,
-			&trade.OrderID,
-			&trade.TradeType,
-			&trade.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("扫描交易记录失败: %v", err)
-		}
-		trades = append(trades, trade)
-	}
+	return scanTrades(rows)
+}
 
-	return trades, nil
+// SpotHolding 汇总视图所属账户在现货symbol上的成交，返回程序自己持有的交易货币数量和买入成本均价。
+// 数量为买入成交数量扣除以交易货币收取的手续费，再减去卖出成交数量；均价按含手续费的买入成本计算
+func (db *Database) SpotHolding(symbol, baseCcy string) (qty, avgPx float64, err error) {
+	query := `
+		SELECT
+			COALESCE(SUM(CASE WHEN side = 'buy' THEN fill_amount + CASE WHEN fee_ccy = ? THEN fee ELSE 0 END
+				ELSE -fill_amount END), 0),
+			COALESCE(SUM(CASE WHEN side = 'buy' THEN fill_price * fill_amount ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN side = 'buy' THEN fill_amount + CASE WHEN fee_ccy = ? THEN fee ELSE 0 END
+				ELSE 0 END), 0)
+		FROM trades
+		WHERE symbol = ? AND fill_amount > 0 AND ` + accountFilter
+
+	var cost, bought float64
+	row := db.db.QueryRow(query, baseCcy, baseCcy, symbol, db.account, db.account)
+	if err := row.Scan(&qty, &cost, &bought); err != nil {
+		return 0, 0, fmt.Errorf("查询现货持仓失败: %v", err)
+	}
+	if bought > 0 {
+		avgPx = cost / bought
+	}
+	return qty, avgPx, nil
 }
 
 // GetTradeStats 获取交易统计信息
@@ -352,9 +574,9 @@ func (db *Database) GetTradeStats(symbol string) (map[string]float64, error) {
 			AVG(price) as avg_price,
 			SUM(amount) as total_amount
 		FROM trades
-		WHERE symbol = ?`
+		WHERE symbol = ? AND ` + accountFilter
 
-	row := db.db.QueryRow(query, symbol)
+	row := db.db.QueryRow(query, symbol, db.account, db.account)
 
 	var stats = make(map[string]float64)
 	var totalTrades, buyTrades, sellTrades, avgPrice, totalAmount float64
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"okxauto/internal/database/models"
+)
+
+func newTestDB(t *testing.T) *Database {
+	t.Helper()
+	db, err := New(filepath.Join(t.TempDir(), "trades.db"))
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	return db
+}
+
+// seedLegacy 写入升级前归属default账户的一笔未成交交易、一笔成交和一条账单
+func seedLegacy(t *testing.T, db *Database) {
+	t.Helper()
+	legacy := db.ForAccount(DefaultAccount)
+	trade := &models.Trade{Symbol: "BTC-USDT-SWAP", Side: "buy", Price: 100, Amount: 1, Strategy: "rsi",
+		Status: "live", OrderID: "o1", TradeType: "futures", CreatedAt: time.Now()}
+	if err := legacy.SaveTrade(trade); err != nil {
+		t.Fatalf("保存交易失败: %v", err)
+	}
+	if err := legacy.SaveFills([]*models.Fill{{Symbol: "BTC-USDT-SWAP", TradeID: "t1", OrderID: "o1", Ts: 1}}); err != nil {
+		t.Fatalf("保存成交失败: %v", err)
+	}
+	if _, err := legacy.SaveBills([]*models.Bill{{BillID: "b1", Ccy: "USDT", Type: "2", Ts: 1}}); err != nil {
+		t.Fatalf("保存账单失败: %v", err)
+	}
+}
+
+func TestMigrateLegacyAccountSingle(t *testing.T) {
+	db := newTestDB(t)
+	seedLegacy(t, db)
+	// 升级后已重新同步过的账单与旧记录重复
+	if _, err := db.ForAccount("main").SaveBills([]*models.Bill{{BillID: "b1", Ccy: "USDT", Type: "2", Ts: 1}}); err != nil {
+		t.Fatalf("保存账单失败: %v", err)
+	}
+
+	if err := db.MigrateLegacyAccount([]string{"main"}); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+
+	mainAcc := db.ForAccount("main")
+	if trades, _ := mainAcc.GetOpenTrades(); len(trades) != 1 {
+		t.Fatalf("迁移后账户应能查询到升级前的未成交交易, 实际 %d 笔", len(trades))
+	}
+	if fills, _ := mainAcc.GetFillsByOrder("o1"); len(fills) != 1 {
+		t.Fatalf("迁移后成交数量 = %d", len(fills))
+	}
+	if bills, _ := mainAcc.GetBills(0, 10); len(bills) != 1 {
+		t.Fatalf("重复的账单应只保留一条, 实际 %d 条", len(bills))
+	}
+	if trades, _ := db.ForAccount(DefaultAccount).GetTrades(10); len(trades) != 0 {
+		t.Fatalf("default账户不应再有记录, 实际 %d 笔", len(trades))
+	}
+}
+
+func TestMigrateLegacyAccountAmbiguous(t *testing.T) {
+	tests := []struct {
+		name     string
+		accounts []string
+	}{
+		{"多个账户无法判断归属", []string{"main", "sub1"}},
+		{"配置中有default账户", []string{DefaultAccount}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			seedLegacy(t, db)
+			if err := db.MigrateLegacyAccount(tt.accounts); err != nil {
+				t.Fatalf("迁移失败: %v", err)
+			}
+			if trades, _ := db.ForAccount(DefaultAccount).GetOpenTrades(); len(trades) != 1 {
+				t.Fatalf("记录应保留在default账户, 实际 %d 笔", len(trades))
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"fmt"
+
+	"okxauto/internal/database/models"
+)
+
+// SaveFills 批量保存成交明细，已存在的成交ID会被忽略；未指定账户的成交归属视图所属账户
+func (db *Database) SaveFills(fills []*models.Fill) error {
+	if len(fills) == 0 {
+		return nil
+	}
+
+	account := db.account
+	if account == "" {
+		account = DefaultAccount
+	}
+
+	tx, err := db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %v", err)
+	}
+	stmt, err := tx.Prepare(`
+		INSERT OR IGNORE INTO fills (
+			account, symbol, trade_id, order_id, side, pos_side,
+			fill_price, fill_size, fee, fee_ccy, exec_type, ts
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("准备保存成交明细失败: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, f := range fills {
+		if f.Account == "" {
+			f.Account = account
+		}
+		if _, err := stmt.Exec(f.Account, f.Symbol, f.TradeID, f.OrderID, f.Side, f.PosSide,
+			f.FillPrice, f.FillSize, f.Fee, f.FeeCcy, f.ExecType, f.Ts); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("保存成交明细失败: %v", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交成交明细失败: %v", err)
+	}
+	return nil
+}
+
+// GetFillsByOrder 按成交时间升序返回订单的成交明细
+func (db *Database) GetFillsByOrder(orderID string) ([]*models.Fill, error) {
+	query := `
+		SELECT id, account, symbol, trade_id, order_id, side, pos_side,
+			fill_price, fill_size, fee, fee_ccy, exec_type, ts
+		FROM fills
+		WHERE order_id = ? AND ` + accountFilter + `
+		ORDER BY ts ASC`
+
+	rows, err := db.db.Query(query, orderID, db.account, db.account)
+	if err != nil {
+		return nil, fmt.Errorf("查询成交明细失败: %v", err)
+	}
+	defer rows.Close()
+
+	var fills []*models.Fill
+	for rows.Next() {
+		f := &models.Fill{}
+		if err := rows.Scan(&f.ID, &f.Account, &f.Symbol, &f.TradeID, &f.OrderID, &f.Side, &f.PosSide,
+			&f.FillPrice, &f.FillSize, &f.Fee, &f.FeeCcy, &f.ExecType, &f.Ts); err != nil {
+			return nil, fmt.Errorf("扫描成交明细失败: %v", err)
+		}
+		fills = append(fills, f)
+	}
+	return fills, rows.Err()
+}
@@ -13,16 +13,87 @@ type User struct {
 
 // Trade 交易记录
 type Trade struct {
-	ID        int64     `db:"id"`
-	Symbol    string    `db:"symbol"`     // 交易对
-	Side      string    `db:"side"`       // 买卖方向
-	Price     float64   `db:"price"`      // 价格
-	Amount    float64   `db:"amount"`     // 数量
-	Strategy  string    `db:"strategy"`   // 策略名称
-	Status    string    `db:"status"`     // 状态
-	OrderID   string    `db:"order_id"`   // 订单ID
-	TradeType string    `db:"trade_type"` // 交易类型：spot/futures
-	CreatedAt time.Time `db:"created_at"` // 创建时间
+	ID         int64     `db:"id"`
+	Account    string    `db:"account"`     // 所属账户
+	Symbol     string    `db:"symbol"`      // 交易对
+	Side       string    `db:"side"`        // 买卖方向
+	Price      float64   `db:"price"`       // 价格
+	Amount     float64   `db:"amount"`      // 数量
+	Strategy   string    `db:"strategy"`    // 策略名称
+	Status     string    `db:"status"`      // 订单状态：live/partially_filled/filled/canceled
+	OrderID    string    `db:"order_id"`    // 订单ID
+	TradeType  string    `db:"trade_type"`  // 交易类型：spot/futures
+	FillPrice  float64   `db:"fill_price"`  // 成交均价
+	FillAmount float64   `db:"fill_amount"` // 累计成交数量(币)
+	Fee        float64   `db:"fee"`         // 累计手续费，负数表示扣除
+	FeeCcy     string    `db:"fee_ccy"`     // 手续费币种
+	CreatedAt  time.Time `db:"created_at"`  // 创建时间
+}
+
+// Fill 成交明细，按 account/symbol/trade_id 唯一
+type Fill struct {
+	ID        int64   `db:"id"`
+	Account   string  `db:"account"`
+	Symbol    string  `db:"symbol"`
+	TradeID   string  `db:"trade_id"`   // 交易所成交ID
+	OrderID   string  `db:"order_id"`   // 订单ID
+	Side      string  `db:"side"`       // 买卖方向
+	PosSide   string  `db:"pos_side"`   // 持仓方向
+	FillPrice float64 `db:"fill_price"` // 成交价格
+	FillSize  float64 `db:"fill_size"`  // 成交数量(合约为张数)
+	Fee       float64 `db:"fee"`        // 手续费，负数表示扣除
+	FeeCcy    string  `db:"fee_ccy"`    // 手续费币种
+	ExecType  string  `db:"exec_type"`  // T:taker M:maker
+	Ts        int64   `db:"ts"`         // 成交时间，毫秒
+}
+
+// Bill 交易所账单流水，按 account/bill_id 唯一
+type Bill struct {
+	ID       int64   `db:"id"`
+	Account  string  `db:"account"`
+	BillID   string  `db:"bill_id"`   // 账单ID
+	InstID   string  `db:"inst_id"`   // 产品ID，划转等账单为空
+	InstType string  `db:"inst_type"` // 产品类型
+	Ccy      string  `db:"ccy"`       // 币种
+	Type     string  `db:"type"`      // 账单类型: 1划转 2交易 5强平 6保证金划转 8资金费等
+	SubType  string  `db:"sub_type"`  // 账单子类型
+	BalChg   float64 `db:"bal_chg"`   // 余额变动
+	Bal      float64 `db:"bal"`       // 变动后余额
+	Sz       float64 `db:"sz"`        // 数量
+	Px       float64 `db:"px"`        // 价格
+	Pnl      float64 `db:"pnl"`       // 收益
+	Fee      float64 `db:"fee"`       // 手续费，负数表示扣除
+	OrderID  string  `db:"order_id"`  // 订单ID
+	TradeID  string  `db:"trade_id"`  // 成交ID
+	Ts       int64   `db:"ts"`        // 账单时间，毫秒
+}
+
+// BillCursor 未完成的账单同步进度，每个账户最多一条。同步从AfterID之前(更早)的账单继续向前翻页，
+// 直到早于UntilTs；AfterID为空表示从最新账单开始，UntilTs为0表示拉取全部可查询的账单
+type BillCursor struct {
+	Account string `db:"account"`
+	AfterID string `db:"after_id"` // 已写入的最早账单ID
+	UntilTs int64  `db:"until_ts"` // 同步开始时本地最新账单的时间，毫秒
+}
+
+// Candle 已完结的K线，按 symbol/bar/ts 唯一
+type Candle struct {
+	Symbol string  `db:"symbol"`
+	Bar    string  `db:"bar"` // K线周期，如1m/1H/1D
+	Ts     int64   `db:"ts"`  // 开盘时间，毫秒
+	Open   float64 `db:"open"`
+	High   float64 `db:"high"`
+	Low    float64 `db:"low"`
+	Close  float64 `db:"close"`
+	Volume float64 `db:"volume"`
+}
+
+// CandleRange 交易所没有K线数据的开盘时间区间 [From, To)，如上市之前或停牌期间
+type CandleRange struct {
+	Symbol string `db:"symbol"`
+	Bar    string `db:"bar"`
+	From   int64  `db:"from_ts"` // 毫秒，含
+	To     int64  `db:"to_ts"`   // 毫秒，不含
 }
 
 // Signal 交易信号
@@ -0,0 +1,180 @@
+package ledger
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"okxauto/internal/api"
+)
+
+// 对账时数量和手续费允许的误差
+const tolerance = 1e-8
+
+// billTypeNames 账单类型名称
+var billTypeNames = map[string]string{
+	api.BillTypeTransfer:       "划转",
+	api.BillTypeTrade:          "交易",
+	api.BillTypeDelivery:       "交割",
+	api.BillTypeLiquidation:    "强平",
+	api.BillTypeMarginTransfer: "保证金划转",
+	api.BillTypeInterest:       "扣息",
+	api.BillTypeFundingFee:     "资金费",
+	api.BillTypeADL:            "自动减仓",
+}
+
+// Report 本地交易记录与交易所账单的对账结果
+type Report struct {
+	Account string    `json:"account"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+
+	Trades     int         `json:"trades"`     // 有成交的本地交易记录数
+	Matched    int         `json:"matched"`    // 与账单一致的记录数
+	Mismatches []*Mismatch `json:"mismatches"` // 与账单不一致的字段
+
+	// MissingBills 本地有成交但账单中没有对应交易流水的订单
+	MissingBills []string `json:"missing_bills"`
+	// UnknownOrders 账单中有交易流水但本地没有交易记录的订单，如交易所止盈止损触发的平仓单或手动下单
+	UnknownOrders []string `json:"unknown_orders"`
+
+	// Totals 按账单类型汇总的余额变动，资金费、强平、划转等不经过本地交易记录
+	Totals []*BillTotal `json:"totals"`
+}
+
+// Mismatch 单个订单某一字段的本地值与账单值
+type Mismatch struct {
+	OrderID  string  `json:"order_id"`
+	Symbol   string  `json:"symbol"`
+	Field    string  `json:"field"` // fee/size
+	Local    float64 `json:"local"`
+	Exchange float64 `json:"exchange"`
+}
+
+// BillTotal 单个账单类型和币种的汇总
+type BillTotal struct {
+	Type   string  `json:"type"`
+	Name   string  `json:"name"`
+	Ccy    string  `json:"ccy"`
+	Count  int     `json:"count"`
+	BalChg float64 `json:"bal_chg"`
+	Pnl    float64 `json:"pnl"`
+	Fee    float64 `json:"fee"`
+}
+
+// orderBills 单个订单的交易流水汇总
+type orderBills struct {
+	symbol string
+	sz     float64
+	fee    float64
+}
+
+// Reconcile 对比 [from, to) 范围内的本地交易记录与账单：逐笔核对订单的手续费和成交数量，
+// 并列出两边缺失的订单。调用前应先Sync
+func (s *Service) Reconcile(from, to time.Time) (*Report, error) {
+	bills, err := s.db.GetBills(from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	trades, err := s.db.GetTradesBetween(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Account:       s.db.Account(),
+		From:          from,
+		To:            to,
+		Mismatches:    []*Mismatch{},
+		MissingBills:  []string{},
+		UnknownOrders: []string{},
+		Totals:        []*BillTotal{},
+	}
+
+	orders := make(map[string]*orderBills)
+	totals := make(map[string]*BillTotal)
+	for _, b := range bills {
+		key := b.Type + "|" + b.Ccy
+		total, ok := totals[key]
+		if !ok {
+			name, ok := billTypeNames[b.Type]
+			if !ok {
+				name = "其他"
+			}
+			total = &BillTotal{Type: b.Type, Name: name, Ccy: b.Ccy}
+			totals[key] = total
+			report.Totals = append(report.Totals, total)
+		}
+		total.Count++
+		total.BalChg += b.BalChg
+		total.Pnl += b.Pnl
+		total.Fee += b.Fee
+
+		if b.Type == api.BillTypeTrade && b.OrderID != "" {
+			o, ok := orders[b.OrderID]
+			if !ok {
+				o = &orderBills{symbol: b.InstID}
+				orders[b.OrderID] = o
+			}
+			o.sz += math.Abs(b.Sz)
+			o.fee += b.Fee
+		}
+	}
+	sort.Slice(report.Totals, func(i, j int) bool {
+		if report.Totals[i].Type != report.Totals[j].Type {
+			return report.Totals[i].Type < report.Totals[j].Type
+		}
+		return report.Totals[i].Ccy < report.Totals[j].Ccy
+	})
+
+	local := make(map[string]bool)
+	for _, trade := range trades {
+		if trade.OrderID == "" || trade.FillAmount == 0 {
+			continue
+		}
+		local[trade.OrderID] = true
+		report.Trades++
+
+		o, ok := orders[trade.OrderID]
+		if !ok {
+			report.MissingBills = append(report.MissingBills, trade.OrderID)
+			continue
+		}
+
+		matched := true
+		if math.Abs(trade.Fee-o.fee) > tolerance {
+			matched = false
+			report.Mismatches = append(report.Mismatches, &Mismatch{
+				OrderID: trade.OrderID, Symbol: trade.Symbol, Field: "fee", Local: trade.Fee, Exchange: o.fee,
+			})
+		}
+		// 交易记录的数量为币数量，按成交明细(张数)核对
+		fills, err := s.db.GetFillsByOrder(trade.OrderID)
+		if err != nil {
+			return nil, err
+		}
+		if len(fills) > 0 {
+			var sz float64
+			for _, f := range fills {
+				sz += f.FillSize
+			}
+			if math.Abs(sz-o.sz) > tolerance {
+				matched = false
+				report.Mismatches = append(report.Mismatches, &Mismatch{
+					OrderID: trade.OrderID, Symbol: trade.Symbol, Field: "size", Local: sz, Exchange: o.sz,
+				})
+			}
+		}
+		if matched {
+			report.Matched++
+		}
+	}
+
+	for ordId := range orders {
+		if !local[ordId] {
+			report.UnknownOrders = append(report.UnknownOrders, ordId)
+		}
+	}
+	sort.Strings(report.UnknownOrders)
+	return report, nil
+}
@@ -0,0 +1,144 @@
+// Package ledger 同步交易所账单流水，并与本地交易记录对账
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"okxauto/internal/api"
+	"okxauto/internal/database"
+	"okxauto/internal/database/models"
+)
+
+const (
+	// 单次请求账单的最大数量
+	pageLimit = 100
+	// bills接口只能查询最近7天，更早的账单需要从bills-archive获取(最近3个月)
+	recentWindow = 7 * 24 * time.Hour
+	// DefaultSyncInterval 默认账单同步间隔
+	DefaultSyncInterval = 10 * time.Minute
+)
+
+// Service 账单服务，分页拉取账单写入bills表并提供对账报告
+type Service struct {
+	api api.Exchange
+	db  *database.Database
+}
+
+// NewService 创建账单服务，db应为账户视图，账单归属该账户
+func NewService(apiClient api.Exchange, db *database.Database) *Service {
+	return &Service{api: apiClient, db: db}
+}
+
+// Run 启动后立即同步一次，之后按interval定期同步，ctx结束时退出
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultSyncInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.Sync(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("同步账单失败(已写入%d条): %v", n, err)
+		} else if n > 0 {
+			log.Printf("同步账单 %d 条", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync 先补齐上次同步中断留下的缺口，再从最新的账单开始向前翻页，直到与本地已有的账单衔接，
+// 返回新写入的数量
+func (s *Service) Sync(ctx context.Context) (int, error) {
+	total := 0
+	cursor, err := s.db.GetBillCursor()
+	if err != nil {
+		return 0, err
+	}
+	if cursor != nil {
+		n, err := s.page(ctx, cursor)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("补齐中断的账单失败: %v", err)
+		}
+	}
+
+	latest, err := s.db.LatestBillTime()
+	if err != nil {
+		return total, err
+	}
+	n, err := s.page(ctx, &models.BillCursor{UntilTs: latest})
+	return total + n, err
+}
+
+// page 从cursor.AfterID开始向前翻页，直到本页最早的账单早于cursor.UntilTs或没有更早的账单。
+// 进度在翻页前和每页写入后保存到数据库，中途失败或进程退出时下次Sync从保存的位置继续，
+// 否则已写入的新账单会让LatestBillTime前移，更早的几页再也不会被拉取。
+// 需要衔接的账单已超出bills接口的查询范围时改用bills-archive
+func (s *Service) page(ctx context.Context, cursor *models.BillCursor) (int, error) {
+	fetch := s.api.GetBills
+	if cursor.UntilTs == 0 || time.Since(time.UnixMilli(cursor.UntilTs)) > recentWindow-time.Hour {
+		fetch = s.api.GetBillsArchive
+	}
+	if err := s.db.SaveBillCursor(cursor); err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for {
+		bills, err := fetch(ctx, api.BillsQuery{After: cursor.AfterID, Limit: pageLimit})
+		if err != nil {
+			return total, fmt.Errorf("拉取账单失败: %v", err)
+		}
+
+		n, err := s.db.SaveBills(toRecords(bills))
+		if err != nil {
+			return total, err
+		}
+		total += n
+
+		// 账单按时间倒序，本页最早的账单已早于衔接时间时说明已衔接
+		if len(bills) < pageLimit || bills[len(bills)-1].Ts.UnixMilli() < cursor.UntilTs {
+			return total, s.db.DeleteBillCursor()
+		}
+		cursor.AfterID = bills[len(bills)-1].BillId
+		if err := s.db.SaveBillCursor(cursor); err != nil {
+			return total, err
+		}
+	}
+}
+
+func toRecords(bills []*api.Bill) []*models.Bill {
+	records := make([]*models.Bill, 0, len(bills))
+	for _, b := range bills {
+		records = append(records, &models.Bill{
+			BillID:   b.BillId,
+			InstID:   b.InstId,
+			InstType: b.InstType,
+			Ccy:      b.Ccy,
+			Type:     b.Type,
+			SubType:  b.SubType,
+			BalChg:   b.BalChg,
+			Bal:      b.Bal,
+			Sz:       b.Sz,
+			Px:       b.Px,
+			Pnl:      b.Pnl,
+			Fee:      b.Fee,
+			OrderID:  b.OrdId,
+			TradeID:  b.TradeId,
+			Ts:       b.Ts.UnixMilli(),
+		})
+	}
+	return records
+}
@@ -0,0 +1,205 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"okxauto/internal/api"
+	"okxauto/internal/database"
+	"okxauto/internal/database/models"
+)
+
+// stubBills 按时间倒序分页返回账单，fails中的请求序号(从1开始)返回错误
+type stubBills struct {
+	api.Exchange
+	bills   []*api.Bill
+	fails   map[int]bool
+	calls   int
+	archive int // bills-archive的请求次数
+}
+
+func (s *stubBills) GetBills(ctx context.Context, q api.BillsQuery) ([]*api.Bill, error) {
+	return s.fetch(q)
+}
+
+func (s *stubBills) GetBillsArchive(ctx context.Context, q api.BillsQuery) ([]*api.Bill, error) {
+	s.archive++
+	return s.fetch(q)
+}
+
+func (s *stubBills) fetch(q api.BillsQuery) ([]*api.Bill, error) {
+	s.calls++
+	if s.fails[s.calls] {
+		return nil, errors.New("connection reset by peer")
+	}
+	start := 0
+	if q.After != "" {
+		for i, b := range s.bills {
+			if b.BillId == q.After {
+				start = i + 1
+				break
+			}
+		}
+	}
+	end := start + q.Limit
+	if end > len(s.bills) {
+		end = len(s.bills)
+	}
+	return s.bills[start:end], nil
+}
+
+var billBase = time.Now().Add(-time.Hour).Truncate(time.Second)
+
+// makeBills 生成n条按时间倒序的资金费账单，第i条账单的ID为b%04d，时间为billBase后i秒
+func makeBills(n int) []*api.Bill {
+	bills := make([]*api.Bill, 0, n)
+	for i := n - 1; i >= 0; i-- {
+		bills = append(bills, &api.Bill{
+			BillId: fmt.Sprintf("b%04d", i),
+			Ccy:    "USDT",
+			Type:   api.BillTypeFundingFee,
+			BalChg: -0.01,
+			Ts:     billBase.Add(time.Duration(i) * time.Second),
+		})
+	}
+	return bills
+}
+
+// newTestService 返回连接到账单桩的服务，本地预先写入最早的stored条账单
+func newTestService(t *testing.T, stub *stubBills, stored int) *Service {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "trades.db"))
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	view := db.ForAccount("main")
+	if stored > 0 {
+		if _, err := view.SaveBills(toRecords(stub.bills[len(stub.bills)-stored:])); err != nil {
+			t.Fatalf("写入本地账单失败: %v", err)
+		}
+	}
+	return NewService(stub, view)
+}
+
+func TestSync(t *testing.T) {
+	type run struct {
+		wantN   int
+		wantErr bool
+	}
+	tests := []struct {
+		name        string
+		total       int // 交易所的账单数
+		stored      int // 本地已有的最早账单数
+		fails       []int
+		runs        []run
+		wantCalls   int
+		wantArchive bool
+	}{
+		{
+			name: "首次同步拉取全部账单", total: 250,
+			runs:      []run{{wantN: 250}},
+			wantCalls: 3, wantArchive: true,
+		},
+		{
+			name: "增量同步与本地衔接后停止", total: 250, stored: 200,
+			runs:      []run{{wantN: 50}},
+			wantCalls: 1,
+		},
+		{
+			name: "中断后从保存的位置补齐缺口", total: 350, stored: 50, fails: []int{2},
+			runs: []run{{wantN: 100, wantErr: true}, {wantN: 200}},
+			// 中断前1次成功，续传3页，之后从最新账单同步1页
+			wantCalls: 6,
+		},
+		{
+			name: "续传再次中断时保留进度", total: 350, stored: 50, fails: []int{2, 4},
+			runs:      []run{{wantN: 100, wantErr: true}, {wantN: 100, wantErr: true}, {wantN: 100}},
+			wantCalls: 7,
+		},
+		{
+			name: "首次同步中断后拉取剩余的历史账单", total: 250, fails: []int{2},
+			runs:      []run{{wantN: 100, wantErr: true}, {wantN: 150}},
+			wantCalls: 5, wantArchive: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubBills{bills: makeBills(tt.total), fails: make(map[int]bool)}
+			for _, n := range tt.fails {
+				stub.fails[n] = true
+			}
+			s := newTestService(t, stub, tt.stored)
+
+			for i, r := range tt.runs {
+				n, err := s.Sync(context.Background())
+				if (err != nil) != r.wantErr || n != r.wantN {
+					t.Fatalf("第%d次同步: 写入 %d 条, 错误 %v; 期望写入 %d 条, 出错 %v", i+1, n, err, r.wantN, r.wantErr)
+				}
+			}
+
+			bills, err := s.db.GetBills(0, billBase.Add(time.Hour).UnixMilli())
+			if err != nil {
+				t.Fatalf("查询账单失败: %v", err)
+			}
+			if len(bills) != tt.total {
+				t.Fatalf("本地账单 %d 条, 期望 %d 条", len(bills), tt.total)
+			}
+			if cursor, _ := s.db.GetBillCursor(); cursor != nil {
+				t.Fatalf("同步完成后应删除进度: %+v", cursor)
+			}
+			if stub.calls != tt.wantCalls {
+				t.Fatalf("请求次数 = %d, 期望 %d", stub.calls, tt.wantCalls)
+			}
+			if (stub.archive > 0) != tt.wantArchive {
+				t.Fatalf("bills-archive请求次数 = %d", stub.archive)
+			}
+		})
+	}
+}
+
+func TestReconcileAfterResumedSync(t *testing.T) {
+	stub := &stubBills{bills: makeBills(350), fails: map[int]bool{2: true}}
+	// 订单的交易流水位于中断时未拉取的页中
+	bill := stub.bills[349-200]
+	bill.Type, bill.InstId, bill.OrdId, bill.Sz, bill.Fee = api.BillTypeTrade, "BTC-USDT-SWAP", "o1", 1, -0.05
+	s := newTestService(t, stub, 50)
+
+	trade := &models.Trade{Symbol: "BTC-USDT-SWAP", Side: "buy", Price: 100, Amount: 0.01, Strategy: "rsi",
+		Status: "filled", OrderID: "o1", TradeType: "futures", FillPrice: 100, FillAmount: 0.01, Fee: -0.05,
+		CreatedAt: bill.Ts}
+	if err := s.db.SaveTrade(trade); err != nil {
+		t.Fatalf("保存交易失败: %v", err)
+	}
+	from, to := billBase, billBase.Add(time.Hour)
+
+	if _, err := s.Sync(context.Background()); err == nil {
+		t.Fatal("第2页应拉取失败")
+	}
+	report, err := s.Reconcile(from, to)
+	if err != nil {
+		t.Fatalf("对账失败: %v", err)
+	}
+	if len(report.MissingBills) != 1 {
+		t.Fatalf("中断后缺口中的订单应缺少账单: %+v", report)
+	}
+
+	if _, err := s.Sync(context.Background()); err != nil {
+		t.Fatalf("续传失败: %v", err)
+	}
+	report, err = s.Reconcile(from, to)
+	if err != nil {
+		t.Fatalf("对账失败: %v", err)
+	}
+	if report.Matched != 1 || len(report.MissingBills) != 0 || len(report.Mismatches) != 0 {
+		t.Fatalf("补齐缺口后应对账一致: %+v", report)
+	}
+}
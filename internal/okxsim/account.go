@@ -0,0 +1,225 @@
+package okxsim
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// 持仓模式
+const (
+	posModeNet       = "net_mode"
+	posModeLongShort = "long_short_mode"
+)
+
+// SetPositionMode 设置账户持仓模式: net_mode 或 long_short_mode
+func (s *Server) SetPositionMode(mode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.posMode = mode
+}
+
+// SetBalance 设置交易账户的币种余额，用于模拟账户中已有的现货持仓
+func (s *Server) SetBalance(ccy string, amt float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cash[ccy] = amt
+}
+
+// checkPosSide 校验合约订单的posSide与账户持仓模式是否匹配，调用方需持有锁
+func (s *Server) checkPosSide(inst *instrument, posSide string) *apiError {
+	if inst.instType == "SPOT" {
+		return nil
+	}
+	switch s.posMode {
+	case posModeNet:
+		if posSide != "" && posSide != "net" {
+			return &apiError{code: "51000", msg: "Parameter posSide error"}
+		}
+	default:
+		if posSide != "long" && posSide != "short" {
+			return &apiError{code: "51000", msg: "Parameter posSide error"}
+		}
+	}
+	return nil
+}
+
+// handleAccountConfig GET /api/v5/account/config
+func (s *Server) handleAccountConfig(w http.ResponseWriter, r *http.Request, body []byte) {
+	s.mu.Lock()
+	posMode := s.posMode
+	s.mu.Unlock()
+
+	writeData(w, []map[string]interface{}{{
+		"uid":              "10000001",
+		"mainUid":          "10000001",
+		"acctLv":           "2",
+		"posMode":          posMode,
+		"autoLoan":         false,
+		"greeksType":       "PA",
+		"level":            "Lv1",
+		"ctIsoMode":        "automatic",
+		"mgnIsoMode":       "automatic",
+		"label":            "okxsim",
+		"roleType":         "0",
+		"perm":             "read_only,trade",
+		"type":             "0",
+		"enableSpotBorrow": false,
+	}})
+}
+
+// handleSetPositionMode POST /api/v5/account/set-position-mode，有持仓或挂单时不允许切换
+func (s *Server) handleSetPositionMode(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req struct {
+		PosMode string `json:"posMode"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, &apiError{code: "50002", msg: "JSON syntax error"})
+		return
+	}
+	if req.PosMode != posModeNet && req.PosMode != posModeLongShort {
+		writeError(w, &apiError{code: "51000", msg: "Parameter posMode error"})
+		return
+	}
+
+	s.mu.Lock()
+	if req.PosMode != s.posMode && s.hasOpenExposure() {
+		s.mu.Unlock()
+		writeError(w, &apiError{code: "59000", msg: "Settings failed. Close any open positions or orders before modifying settings."})
+		return
+	}
+	s.posMode = req.PosMode
+	s.mu.Unlock()
+
+	writeData(w, []map[string]string{{"posMode": req.PosMode}})
+}
+
+// hasOpenExposure 是否存在持仓或未成交订单，调用方需持有锁
+func (s *Server) hasOpenExposure() bool {
+	for _, p := range s.positions {
+		if p.pos != 0 {
+			return true
+		}
+	}
+	for _, o := range s.orders {
+		if o.state == "live" || o.state == "partially_filled" {
+			return true
+		}
+	}
+	return false
+}
+
+// positionSize 返回持仓数量，net模式下为带符号的数量，调用方需持有锁
+func (s *Server) positionSize(instId, posSide string) float64 {
+	if p, ok := s.positions[positionKey(instId, posSide)]; ok {
+		return p.pos
+	}
+	return 0
+}
+
+// handleMaxSize GET /api/v5/account/max-size，按可用保证金、杠杆和手续费计算可开数量，net模式另加可平数量
+func (s *Server) handleMaxSize(w http.ResponseWriter, r *http.Request, body []byte) {
+	q := r.URL.Query()
+	instId, tdMode := q.Get("instId"), q.Get("tdMode")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inst, ok := s.instruments[instId]
+	if !ok {
+		writeError(w, &apiError{code: "51001", msg: "Instrument ID does not exist"})
+		return
+	}
+	px := parseFloat(q.Get("px"))
+	if px <= 0 {
+		px = inst.price
+	}
+
+	// 单张开仓成本 = 面值*价格*(1/杠杆 + 手续费率)
+	openSize := func(posSide string) float64 {
+		lever := parseFloat(q.Get("leverage"))
+		if lever <= 0 {
+			lever = s.lever(instId, tdMode, posSide)
+		}
+		cost := inst.ctVal * px * (1/lever + s.takerRate)
+		return s.available("USDT") / cost
+	}
+
+	var maxBuy, maxSell float64
+	if s.posMode == posModeNet || inst.instType == "SPOT" {
+		pos := s.positionSize(instId, "net")
+		maxBuy = openSize("net") + math.Max(-pos, 0)
+		maxSell = openSize("net") + math.Max(pos, 0)
+	} else {
+		maxBuy = openSize("long")
+		maxSell = openSize("short")
+	}
+
+	lot := 1.0
+	if inst.instType == "SPOT" {
+		lot = 0.0001
+	}
+	writeData(w, []map[string]string{{
+		"instId":  instId,
+		"ccy":     "USDT",
+		"maxBuy":  formatFloat(math.Floor(maxBuy/lot) * lot),
+		"maxSell": formatFloat(math.Floor(maxSell/lot) * lot),
+	}})
+}
+
+// handleMaxAvailSize GET /api/v5/account/max-avail-size，reduceOnly时返回可平仓数量
+func (s *Server) handleMaxAvailSize(w http.ResponseWriter, r *http.Request, body []byte) {
+	q := r.URL.Query()
+	instId := q.Get("instId")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.instruments[instId]; !ok {
+		writeError(w, &apiError{code: "51001", msg: "Instrument ID does not exist"})
+		return
+	}
+
+	availBuy, availSell := s.available("USDT"), s.available("USDT")
+	if q.Get("reduceOnly") == "true" {
+		if s.posMode == posModeNet {
+			pos := s.positionSize(instId, "net")
+			availBuy, availSell = math.Max(-pos, 0), math.Max(pos, 0)
+		} else {
+			availBuy, availSell = s.positionSize(instId, "short"), s.positionSize(instId, "long")
+		}
+	}
+	writeData(w, []map[string]string{{
+		"instId":    instId,
+		"availBuy":  formatFloat(availBuy),
+		"availSell": formatFloat(availSell),
+	}})
+}
+
+// handleTradeFee GET /api/v5/account/trade-fee，费率为负数表示收取
+func (s *Server) handleTradeFee(w http.ResponseWriter, r *http.Request, body []byte) {
+	instType := r.URL.Query().Get("instType")
+	if instType == "" {
+		writeError(w, &apiError{code: "51000", msg: "Parameter instType error"})
+		return
+	}
+
+	s.mu.Lock()
+	maker, taker := formatFloat(-s.makerRate), formatFloat(-s.takerRate)
+	s.mu.Unlock()
+
+	data := map[string]string{
+		"instType": instType,
+		"level":    "Lv1",
+		"maker":    maker,
+		"taker":    taker,
+		"makerU":   "",
+		"takerU":   "",
+		"ts":       strconv.FormatInt(nowMillis(), 10),
+	}
+	if instType == "SWAP" || instType == "FUTURES" {
+		data["makerU"], data["takerU"] = maker, taker
+	}
+	writeData(w, []map[string]string{data})
+}
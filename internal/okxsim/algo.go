@@ -0,0 +1,357 @@
+package okxsim
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// algoOrder 模拟策略委托
+type algoOrder struct {
+	algoId      string
+	algoClOrdId string
+	instId      string
+	tdMode      string
+	side        string
+	posSide     string
+	ordType     string // conditional/oco/trigger/move_order_stop
+	sz          float64
+
+	tpTriggerPx float64
+	tpOrdPx     float64 // -1为市价
+	slTriggerPx float64
+	slOrdPx     float64
+	triggerPx   float64
+	orderPx     float64
+	triggerUp   bool // 计划委托是否为价格上涨触发
+
+	callbackRatio  float64
+	callbackSpread float64
+	activePx       float64
+	active         bool
+	extreme        float64 // 激活后的最高价(卖出)或最低价(买入)
+
+	state string // live/effective/canceled/order_failed
+	ordId string
+	cTime int64
+}
+
+type algoRequest struct {
+	InstId         string `json:"instId"`
+	TdMode         string `json:"tdMode"`
+	Side           string `json:"side"`
+	PosSide        string `json:"posSide"`
+	OrdType        string `json:"ordType"`
+	Sz             string `json:"sz"`
+	AlgoClOrdId    string `json:"algoClOrdId"`
+	TpTriggerPx    string `json:"tpTriggerPx"`
+	TpOrdPx        string `json:"tpOrdPx"`
+	SlTriggerPx    string `json:"slTriggerPx"`
+	SlOrdPx        string `json:"slOrdPx"`
+	TriggerPx      string `json:"triggerPx"`
+	OrderPx        string `json:"orderPx"`
+	CallbackRatio  string `json:"callbackRatio"`
+	CallbackSpread string `json:"callbackSpread"`
+	ActivePx       string `json:"activePx"`
+}
+
+// handleOrderAlgo POST /api/v5/trade/order-algo
+func (s *Server) handleOrderAlgo(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req algoRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, &apiError{code: "50002", msg: "JSON syntax error"})
+		return
+	}
+
+	s.mu.Lock()
+	a, err := s.placeAlgo(&req)
+	s.unlockAndFlush()
+
+	if err != nil {
+		writeJSON(w, http.StatusOK, apiResponse{
+			Code: "1",
+			Msg:  "All operations failed",
+			Data: []map[string]string{{"algoId": "", "algoClOrdId": req.AlgoClOrdId, "sCode": err.code, "sMsg": err.msg}},
+		})
+		return
+	}
+	writeData(w, []map[string]string{{"algoId": a.algoId, "algoClOrdId": a.algoClOrdId, "sCode": "0", "sMsg": ""}})
+}
+
+// placeAlgo 校验并创建策略委托，调用方需持有锁
+func (s *Server) placeAlgo(req *algoRequest) (*algoOrder, *apiError) {
+	inst, ok := s.instruments[req.InstId]
+	if !ok {
+		return nil, &apiError{code: "51001", msg: "Instrument ID does not exist"}
+	}
+	if req.Side != "buy" && req.Side != "sell" {
+		return nil, &apiError{code: "51000", msg: "Parameter side error"}
+	}
+	sz := parseFloat(req.Sz)
+	if sz <= 0 {
+		return nil, &apiError{code: "51000", msg: "Parameter sz error"}
+	}
+
+	posSide := req.PosSide
+	if posSide == "" {
+		posSide = "net"
+	}
+	a := &algoOrder{
+		algoClOrdId:    req.AlgoClOrdId,
+		instId:         req.InstId,
+		tdMode:         req.TdMode,
+		side:           req.Side,
+		posSide:        posSide,
+		ordType:        req.OrdType,
+		sz:             sz,
+		tpTriggerPx:    parseFloat(req.TpTriggerPx),
+		tpOrdPx:        parseFloat(req.TpOrdPx),
+		slTriggerPx:    parseFloat(req.SlTriggerPx),
+		slOrdPx:        parseFloat(req.SlOrdPx),
+		triggerPx:      parseFloat(req.TriggerPx),
+		orderPx:        parseFloat(req.OrderPx),
+		callbackRatio:  parseFloat(req.CallbackRatio),
+		callbackSpread: parseFloat(req.CallbackSpread),
+		activePx:       parseFloat(req.ActivePx),
+		state:          "live",
+		cTime:          nowMillis(),
+	}
+
+	switch req.OrdType {
+	case "conditional":
+		if a.tpTriggerPx <= 0 && a.slTriggerPx <= 0 {
+			return nil, &apiError{code: "51000", msg: "Parameter tpTriggerPx or slTriggerPx error"}
+		}
+	case "oco":
+		if a.tpTriggerPx <= 0 || a.slTriggerPx <= 0 {
+			return nil, &apiError{code: "51000", msg: "Parameter tpTriggerPx and slTriggerPx error"}
+		}
+	case "trigger":
+		if a.triggerPx <= 0 {
+			return nil, &apiError{code: "51000", msg: "Parameter triggerPx error"}
+		}
+		a.triggerUp = a.triggerPx >= inst.price
+	case "move_order_stop":
+		if a.callbackRatio <= 0 && a.callbackSpread <= 0 {
+			return nil, &apiError{code: "51000", msg: "Parameter callbackRatio or callbackSpread error"}
+		}
+		a.active = a.activePx <= 0
+		a.extreme = inst.price
+	default:
+		return nil, &apiError{code: "51000", msg: "Parameter ordType error"}
+	}
+
+	s.orderSeq++
+	a.algoId = strconv.FormatInt(700000000000000000+s.orderSeq, 10)
+	s.algos[a.algoId] = a
+	return a, nil
+}
+
+// matchAlgos 检查策略委托是否触发，触发后按委托价格下单，调用方需持有锁
+func (s *Server) matchAlgos(inst *instrument) {
+	price := inst.price
+	for _, a := range s.algos {
+		if a.instId != inst.instId || a.state != "live" {
+			continue
+		}
+
+		// 卖出委托价格上涨为止盈，买入委托反之
+		up := a.side == "sell"
+		ordPx := 0.0
+		triggered := false
+		switch a.ordType {
+		case "conditional", "oco":
+			if a.tpTriggerPx > 0 && ((up && price >= a.tpTriggerPx) || (!up && price <= a.tpTriggerPx)) {
+				triggered, ordPx = true, a.tpOrdPx
+			} else if a.slTriggerPx > 0 && ((up && price <= a.slTriggerPx) || (!up && price >= a.slTriggerPx)) {
+				triggered, ordPx = true, a.slOrdPx
+			}
+		case "trigger":
+			if (a.triggerUp && price >= a.triggerPx) || (!a.triggerUp && price <= a.triggerPx) {
+				triggered, ordPx = true, a.orderPx
+			}
+		case "move_order_stop":
+			if !a.active {
+				if (up && price >= a.activePx) || (!up && price <= a.activePx) {
+					a.active, a.extreme = true, price
+				}
+				continue
+			}
+			if up {
+				a.extreme = math.Max(a.extreme, price)
+			} else {
+				a.extreme = math.Min(a.extreme, price)
+			}
+			callback := a.callbackSpread
+			if a.callbackRatio > 0 {
+				callback = a.extreme * a.callbackRatio
+			}
+			if (up && price <= a.extreme-callback) || (!up && price >= a.extreme+callback) {
+				triggered, ordPx = true, -1
+			}
+		}
+		if !triggered {
+			continue
+		}
+
+		req := &orderRequest{
+			InstId:  a.instId,
+			TdMode:  a.tdMode,
+			Side:    a.side,
+			PosSide: a.posSide,
+			OrdType: "market",
+			Sz:      formatFloat(a.sz),
+		}
+		if a.posSide == "net" {
+			req.PosSide = ""
+		}
+		if ordPx > 0 {
+			req.OrdType = "limit"
+			req.Px = formatFloat(ordPx)
+		}
+		o, err := s.placeOrder(req)
+		if err != nil {
+			a.state = "order_failed"
+			continue
+		}
+		a.state = "effective"
+		a.ordId = o.ordId
+	}
+}
+
+// handleCancelAlgos POST /api/v5/trade/cancel-algos
+func (s *Server) handleCancelAlgos(w http.ResponseWriter, r *http.Request, body []byte) {
+	var reqs []struct {
+		InstId string `json:"instId"`
+		AlgoId string `json:"algoId"`
+	}
+	if err := json.Unmarshal(body, &reqs); err != nil {
+		writeError(w, &apiError{code: "50002", msg: "JSON syntax error"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := make([]map[string]string, 0, len(reqs))
+	failed := 0
+	for _, req := range reqs {
+		a, ok := s.algos[req.AlgoId]
+		if !ok || a.instId != req.InstId || a.state != "live" {
+			failed++
+			data = append(data, map[string]string{"algoId": req.AlgoId, "sCode": "51400", "sMsg": "Cancellation failed as the order does not exist."})
+			continue
+		}
+		a.state = "canceled"
+		data = append(data, map[string]string{"algoId": a.algoId, "sCode": "0", "sMsg": ""})
+	}
+	if failed > 0 && failed == len(reqs) {
+		writeJSON(w, http.StatusOK, apiResponse{Code: "1", Msg: "All operations failed", Data: data})
+		return
+	}
+	writeData(w, data)
+}
+
+// handleAmendAlgos POST /api/v5/trade/amend-algos
+func (s *Server) handleAmendAlgos(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req struct {
+		InstId         string `json:"instId"`
+		AlgoId         string `json:"algoId"`
+		NewSz          string `json:"newSz"`
+		NewTpTriggerPx string `json:"newTpTriggerPx"`
+		NewTpOrdPx     string `json:"newTpOrdPx"`
+		NewSlTriggerPx string `json:"newSlTriggerPx"`
+		NewSlOrdPx     string `json:"newSlOrdPx"`
+		NewTriggerPx   string `json:"newTriggerPx"`
+		NewOrdPx       string `json:"newOrdPx"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, &apiError{code: "50002", msg: "JSON syntax error"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.algos[req.AlgoId]
+	if !ok || a.instId != req.InstId || a.state != "live" || a.ordType == "move_order_stop" {
+		writeJSON(w, http.StatusOK, apiResponse{
+			Code: "1",
+			Msg:  "All operations failed",
+			Data: []map[string]string{{"algoId": req.AlgoId, "sCode": "51503", "sMsg": "Order modification failed as the order does not exist."}},
+		})
+		return
+	}
+
+	amend := func(dst *float64, v string) {
+		if v != "" {
+			*dst = parseFloat(v)
+		}
+	}
+	amend(&a.sz, req.NewSz)
+	amend(&a.tpTriggerPx, req.NewTpTriggerPx)
+	amend(&a.tpOrdPx, req.NewTpOrdPx)
+	amend(&a.slTriggerPx, req.NewSlTriggerPx)
+	amend(&a.slOrdPx, req.NewSlOrdPx)
+	amend(&a.triggerPx, req.NewTriggerPx)
+	amend(&a.orderPx, req.NewOrdPx)
+
+	writeData(w, []map[string]string{{"algoId": a.algoId, "sCode": "0", "sMsg": ""}})
+}
+
+// handleAlgosPending GET /api/v5/trade/orders-algo-pending
+func (s *Server) handleAlgosPending(w http.ResponseWriter, r *http.Request, body []byte) {
+	q := r.URL.Query()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := make([]map[string]string, 0)
+	for _, a := range s.algos {
+		if a.state != "live" || a.ordType != q.Get("ordType") {
+			continue
+		}
+		if q.Get("instId") != "" && a.instId != q.Get("instId") {
+			continue
+		}
+		data = append(data, s.algoData(a))
+	}
+	writeData(w, data)
+}
+
+func (s *Server) algoData(a *algoOrder) map[string]string {
+	instType := "SWAP"
+	if inst, ok := s.instruments[a.instId]; ok {
+		instType = inst.instType
+	}
+	optional := func(v float64) string {
+		if v == 0 {
+			return ""
+		}
+		return formatFloat(v)
+	}
+	return map[string]string{
+		"instType":       instType,
+		"instId":         a.instId,
+		"algoId":         a.algoId,
+		"algoClOrdId":    a.algoClOrdId,
+		"ordType":        a.ordType,
+		"side":           a.side,
+		"posSide":        a.posSide,
+		"tdMode":         a.tdMode,
+		"sz":             formatFloat(a.sz),
+		"state":          a.state,
+		"tpTriggerPx":    optional(a.tpTriggerPx),
+		"tpOrdPx":        optional(a.tpOrdPx),
+		"slTriggerPx":    optional(a.slTriggerPx),
+		"slOrdPx":        optional(a.slOrdPx),
+		"triggerPx":      optional(a.triggerPx),
+		"ordPx":          optional(a.orderPx),
+		"callbackRatio":  optional(a.callbackRatio),
+		"callbackSpread": optional(a.callbackSpread),
+		"activePx":       optional(a.activePx),
+		"ordId":          a.ordId,
+		"cTime":          strconv.FormatInt(a.cTime, 10),
+	}
+}
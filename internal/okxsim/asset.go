@@ -0,0 +1,104 @@
+package okxsim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// 资金划转的账户类型
+const (
+	accountFunding = "6"
+	accountTrading = "18"
+)
+
+// SetFundingBalance 设置资金账户余额
+func (s *Server) SetFundingBalance(ccy string, amt float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.funding[ccy] = amt
+}
+
+// handleAssetBalances GET /api/v5/asset/balances
+func (s *Server) handleAssetBalances(w http.ResponseWriter, r *http.Request, body []byte) {
+	ccy := r.URL.Query().Get("ccy")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := make([]map[string]string, 0, len(s.funding))
+	for c, bal := range s.funding {
+		if ccy != "" && c != ccy {
+			continue
+		}
+		data = append(data, map[string]string{
+			"ccy":       c,
+			"bal":       formatFloat(bal),
+			"availBal":  formatFloat(bal),
+			"frozenBal": "0",
+		})
+	}
+	writeData(w, data)
+}
+
+// handleTransfer POST /api/v5/asset/transfer，只支持资金账户与交易账户之间划转，
+// 交易账户转出不能超过可用余额
+func (s *Server) handleTransfer(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req struct {
+		Ccy      string `json:"ccy"`
+		Amt      string `json:"amt"`
+		From     string `json:"from"`
+		To       string `json:"to"`
+		Type     string `json:"type"`
+		ClientId string `json:"clientId"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, &apiError{code: "50002", msg: "JSON syntax error"})
+		return
+	}
+	amt := parseFloat(req.Amt)
+	if amt <= 0 {
+		writeError(w, &apiError{code: "51000", msg: "Parameter amt error"})
+		return
+	}
+	if req.Type != "" && req.Type != "0" {
+		writeError(w, &apiError{code: "51000", msg: "Parameter type error"})
+		return
+	}
+	if !((req.From == accountFunding && req.To == accountTrading) || (req.From == accountTrading && req.To == accountFunding)) {
+		writeError(w, &apiError{code: "51000", msg: "Parameter from or to error"})
+		return
+	}
+
+	s.mu.Lock()
+	if req.From == accountFunding {
+		if amt > s.funding[req.Ccy] {
+			s.mu.Unlock()
+			writeError(w, &apiError{code: "58350", msg: "Insufficient balance"})
+			return
+		}
+		s.funding[req.Ccy] -= amt
+		s.cash[req.Ccy] += amt
+	} else {
+		if amt > s.available(req.Ccy) {
+			s.mu.Unlock()
+			writeError(w, &apiError{code: "58350", msg: "Insufficient balance"})
+			return
+		}
+		s.cash[req.Ccy] -= amt
+		s.funding[req.Ccy] += amt
+	}
+	s.transferSeq++
+	transId := strconv.FormatInt(s.transferSeq, 10)
+	s.pushAccount()
+	s.unlockAndFlush()
+
+	writeData(w, []map[string]string{{
+		"transId":  transId,
+		"clientId": req.ClientId,
+		"ccy":      req.Ccy,
+		"amt":      req.Amt,
+		"from":     req.From,
+		"to":       req.To,
+	}})
+}
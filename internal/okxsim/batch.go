@@ -0,0 +1,179 @@
+package okxsim
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// 批量接口单次最多处理的订单数量
+const maxBatchOrders = 20
+
+type amendRequest struct {
+	InstId    string `json:"instId"`
+	OrdId     string `json:"ordId"`
+	ClOrdId   string `json:"clOrdId"`
+	ReqId     string `json:"reqId"`
+	NewSz     string `json:"newSz"`
+	NewPx     string `json:"newPx"`
+	CxlOnFail bool   `json:"cxlOnFail"`
+}
+
+// writeResults 输出逐笔结果，全部失败时code为1，部分失败时code为2
+func writeResults(w http.ResponseWriter, data []map[string]string, failed int) {
+	switch {
+	case failed == 0:
+		writeData(w, data)
+	case failed == len(data):
+		writeJSON(w, http.StatusOK, apiResponse{Code: "1", Msg: "All operations failed", Data: data})
+	default:
+		writeJSON(w, http.StatusOK, apiResponse{Code: "2", Msg: "Bulk operation partially succeeded", Data: data})
+	}
+}
+
+// handleBatchOrders POST /api/v5/trade/batch-orders
+func (s *Server) handleBatchOrders(w http.ResponseWriter, r *http.Request, body []byte) {
+	var reqs []orderRequest
+	if err := json.Unmarshal(body, &reqs); err != nil || len(reqs) == 0 {
+		writeError(w, &apiError{code: "50002", msg: "JSON syntax error"})
+		return
+	}
+	if len(reqs) > maxBatchOrders {
+		writeError(w, &apiError{code: "51006", msg: "Exceeded maximum number of orders"})
+		return
+	}
+
+	s.mu.Lock()
+	data := make([]map[string]string, 0, len(reqs))
+	failed := 0
+	for i := range reqs {
+		o, err := s.placeOrder(&reqs[i])
+		if err != nil {
+			failed++
+			data = append(data, map[string]string{"ordId": "", "clOrdId": reqs[i].ClOrdId, "tag": "", "sCode": err.code, "sMsg": err.msg})
+			continue
+		}
+		data = append(data, map[string]string{"ordId": o.ordId, "clOrdId": o.clOrdId, "tag": "", "sCode": "0", "sMsg": "Order placed"})
+	}
+	s.unlockAndFlush()
+
+	writeResults(w, data, failed)
+}
+
+// handleCancelBatchOrders POST /api/v5/trade/cancel-batch-orders
+func (s *Server) handleCancelBatchOrders(w http.ResponseWriter, r *http.Request, body []byte) {
+	var reqs []cancelRequest
+	if err := json.Unmarshal(body, &reqs); err != nil || len(reqs) == 0 {
+		writeError(w, &apiError{code: "50002", msg: "JSON syntax error"})
+		return
+	}
+	if len(reqs) > maxBatchOrders {
+		writeError(w, &apiError{code: "51006", msg: "Exceeded maximum number of orders"})
+		return
+	}
+
+	s.mu.Lock()
+	data := make([]map[string]string, 0, len(reqs))
+	failed := 0
+	for i := range reqs {
+		o, err := s.cancelOrder(&reqs[i])
+		if err != nil {
+			failed++
+			data = append(data, map[string]string{"ordId": reqs[i].OrdId, "clOrdId": reqs[i].ClOrdId, "sCode": err.code, "sMsg": err.msg})
+			continue
+		}
+		data = append(data, map[string]string{"ordId": o.ordId, "clOrdId": o.clOrdId, "sCode": "0", "sMsg": ""})
+	}
+	s.unlockAndFlush()
+
+	writeResults(w, data, failed)
+}
+
+// handleAmendOrder POST /api/v5/trade/amend-order
+func (s *Server) handleAmendOrder(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req amendRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, &apiError{code: "50002", msg: "JSON syntax error"})
+		return
+	}
+
+	s.mu.Lock()
+	data, failed := s.amendOrders([]amendRequest{req})
+	s.unlockAndFlush()
+
+	writeResults(w, data, failed)
+}
+
+// handleAmendBatchOrders POST /api/v5/trade/amend-batch-orders
+func (s *Server) handleAmendBatchOrders(w http.ResponseWriter, r *http.Request, body []byte) {
+	var reqs []amendRequest
+	if err := json.Unmarshal(body, &reqs); err != nil || len(reqs) == 0 {
+		writeError(w, &apiError{code: "50002", msg: "JSON syntax error"})
+		return
+	}
+	if len(reqs) > maxBatchOrders {
+		writeError(w, &apiError{code: "51006", msg: "Exceeded maximum number of orders"})
+		return
+	}
+
+	s.mu.Lock()
+	data, failed := s.amendOrders(reqs)
+	s.unlockAndFlush()
+
+	writeResults(w, data, failed)
+}
+
+// amendOrders 逐笔改单并返回结果，调用方需持有锁
+func (s *Server) amendOrders(reqs []amendRequest) ([]map[string]string, int) {
+	data := make([]map[string]string, 0, len(reqs))
+	failed := 0
+	for i := range reqs {
+		req := &reqs[i]
+		o, err := s.amendOrder(req)
+		if err != nil {
+			failed++
+			if req.CxlOnFail {
+				s.cancelOrder(&cancelRequest{InstId: req.InstId, OrdId: req.OrdId, ClOrdId: req.ClOrdId})
+			}
+			data = append(data, map[string]string{"ordId": req.OrdId, "clOrdId": req.ClOrdId, "reqId": req.ReqId, "sCode": err.code, "sMsg": err.msg})
+			continue
+		}
+		data = append(data, map[string]string{"ordId": o.ordId, "clOrdId": o.clOrdId, "reqId": req.ReqId, "sCode": "0", "sMsg": ""})
+	}
+	return data, failed
+}
+
+// amendOrder 修改限价挂单的数量或价格，修改后重新撮合，调用方需持有锁
+func (s *Server) amendOrder(req *amendRequest) (*order, *apiError) {
+	o := s.findOrder(req.InstId, req.OrdId, req.ClOrdId)
+	if o == nil || (o.state != "live" && o.state != "partially_filled") {
+		return nil, &apiError{code: "51503", msg: "Order modification failed as the order has been filled, canceled or does not exist."}
+	}
+	if req.NewSz == "" && req.NewPx == "" {
+		return nil, &apiError{code: "51000", msg: "Parameter newSz or newPx error"}
+	}
+	if o.ordType != "limit" {
+		return nil, &apiError{code: "51512", msg: "Only limit orders can be amended."}
+	}
+
+	sz, px := o.sz, o.px
+	if req.NewSz != "" {
+		sz = parseFloat(req.NewSz)
+		if sz <= o.accFillSz {
+			return nil, &apiError{code: "51510", msg: "New quantity must be greater than the filled quantity."}
+		}
+	}
+	if req.NewPx != "" {
+		px = parseFloat(req.NewPx)
+		if px <= 0 {
+			return nil, &apiError{code: "51000", msg: "Parameter newPx error"}
+		}
+	}
+
+	o.sz, o.px = sz, px
+	o.uTime = nowMillis()
+	s.pushOrder(o)
+	if inst, ok := s.instruments[o.instId]; ok {
+		s.matchOrders(inst)
+	}
+	return o, nil
+}
@@ -0,0 +1,102 @@
+package okxsim
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// billRecord 账单流水
+type billRecord struct {
+	billId   int64
+	instId   string
+	instType string
+	ccy      string
+	typ      string
+	subType  string
+	mgnMode  string
+	balChg   float64
+	bal      float64
+	sz       float64
+	px       float64
+	pnl      float64
+	fee      float64
+	ordId    string
+	tradeId  int64
+	ts       int64
+}
+
+// addTradeBill 记录一笔成交账单，fee为负数表示支出，调用方需持有锁
+func (s *Server) addTradeBill(inst *instrument, o *order, tradeId int64, px, sz, pnl, fee float64) {
+	subType := "1" // 买入
+	if o.side == "sell" {
+		subType = "2"
+	}
+	s.bills = append(s.bills, &billRecord{
+		billId:   int64(len(s.bills) + 1),
+		instId:   o.instId,
+		instType: inst.instType,
+		ccy:      "USDT",
+		typ:      "2",
+		subType:  subType,
+		mgnMode:  o.tdMode,
+		balChg:   pnl + fee,
+		bal:      s.cash["USDT"],
+		sz:       sz,
+		px:       px,
+		pnl:      pnl,
+		fee:      fee,
+		ordId:    o.ordId,
+		tradeId:  tradeId,
+		ts:       nowMillis(),
+	})
+}
+
+// handleBills GET /api/v5/account/bills 和 bills-archive，按billId倒序返回，
+// after返回更早的记录，before返回更新的记录
+func (s *Server) handleBills(w http.ResponseWriter, r *http.Request, body []byte) {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	after, _ := strconv.ParseInt(q.Get("after"), 10, 64)
+	before, _ := strconv.ParseInt(q.Get("before"), 10, 64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := make([]map[string]string, 0)
+	for i := len(s.bills) - 1; i >= 0 && len(data) < limit; i-- {
+		b := s.bills[i]
+		if after > 0 && b.billId >= after {
+			continue
+		}
+		if before > 0 && b.billId <= before {
+			continue
+		}
+		if (q.Get("instType") != "" && b.instType != q.Get("instType")) ||
+			(q.Get("ccy") != "" && b.ccy != q.Get("ccy")) ||
+			(q.Get("type") != "" && b.typ != q.Get("type")) {
+			continue
+		}
+		data = append(data, map[string]string{
+			"billId":   strconv.FormatInt(b.billId, 10),
+			"instId":   b.instId,
+			"instType": b.instType,
+			"ccy":      b.ccy,
+			"type":     b.typ,
+			"subType":  b.subType,
+			"mgnMode":  b.mgnMode,
+			"balChg":   formatFloat(b.balChg),
+			"bal":      formatFloat(b.bal),
+			"sz":       formatFloat(b.sz),
+			"px":       formatFloat(b.px),
+			"pnl":      formatFloat(b.pnl),
+			"fee":      formatFloat(b.fee),
+			"ordId":    b.ordId,
+			"tradeId":  strconv.FormatInt(b.tradeId, 10),
+			"ts":       strconv.FormatInt(b.ts, 10),
+		})
+	}
+	writeData(w, data)
+}
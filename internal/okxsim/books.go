@@ -0,0 +1,159 @@
+package okxsim
+
+import (
+	"hash/crc32"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 模拟深度的档位数
+const bookLevels = 20
+
+// depth 围绕最新价生成的模拟深度，价格变化时按差异推送增量
+type depth struct {
+	bids  map[string]string // 价格 -> 数量
+	asks  map[string]string
+	seqId int64
+	rnd   *rand.Rand
+}
+
+func newDepth() *depth {
+	return &depth{
+		bids: make(map[string]string),
+		asks: make(map[string]string),
+		rnd:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// bookLevel 深度档位 [价格, 数量, 强平单数, 订单数]
+func bookLevel(px, sz string) []string {
+	return []string{px, sz, "0", "1"}
+}
+
+// rebuild 按当前价格重新生成深度，返回与上一次相比变化的档位(数量为0表示删除)
+func (d *depth) rebuild(price float64) (bids, asks [][]string) {
+	exp := math.Floor(math.Log10(price)) - 4
+	tick := math.Pow(10, exp)
+	decimals := int(math.Max(0, -exp))
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', decimals, 64) }
+
+	best := math.Floor(price/tick) * tick
+	nextBids := make(map[string]string, bookLevels)
+	nextAsks := make(map[string]string, bookLevels)
+	for i := 0; i < bookLevels; i++ {
+		bidPx := format(best - float64(i)*tick)
+		askPx := format(best + float64(i+1)*tick)
+		nextBids[bidPx] = d.nextSize(d.bids[bidPx])
+		nextAsks[askPx] = d.nextSize(d.asks[askPx])
+	}
+
+	bids = diffLevels(d.bids, nextBids)
+	asks = diffLevels(d.asks, nextAsks)
+	d.bids, d.asks = nextBids, nextAsks
+	d.seqId++
+	return bids, asks
+}
+
+// nextSize 已有档位大概率保持数量不变
+func (d *depth) nextSize(prev string) string {
+	if prev != "" && d.rnd.Float64() < 0.7 {
+		return prev
+	}
+	return strconv.Itoa(d.rnd.Intn(50) + 1)
+}
+
+func diffLevels(prev, next map[string]string) [][]string {
+	var changes [][]string
+	for px, sz := range next {
+		if prev[px] != sz {
+			changes = append(changes, bookLevel(px, sz))
+		}
+	}
+	for px := range prev {
+		if _, ok := next[px]; !ok {
+			changes = append(changes, bookLevel(px, "0"))
+		}
+	}
+	return changes
+}
+
+// sorted 返回排序后的档位，买盘价格从高到低，卖盘从低到高
+func sorted(levels map[string]string, desc bool) [][]string {
+	rows := make([][]string, 0, len(levels))
+	for px, sz := range levels {
+		rows = append(rows, bookLevel(px, sz))
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := parseFloat(rows[i][0]), parseFloat(rows[j][0])
+		if desc {
+			return a > b
+		}
+		return a < b
+	})
+	return rows
+}
+
+// checksum 按OKX规则计算前25档的CRC32校验和
+func (d *depth) checksum() int32 {
+	bids, asks := sorted(d.bids, true), sorted(d.asks, false)
+	var parts []string
+	for i := 0; i < 25; i++ {
+		if i < len(bids) {
+			parts = append(parts, bids[i][0], bids[i][1])
+		}
+		if i < len(asks) {
+			parts = append(parts, asks[i][0], asks[i][1])
+		}
+	}
+	return int32(crc32.ChecksumIEEE([]byte(strings.Join(parts, ":"))))
+}
+
+// bookSnapshot 返回深度全量数据，首次调用时生成深度，调用方需持有锁
+func (s *Server) bookSnapshot(inst *instrument) map[string]interface{} {
+	if inst.depth == nil {
+		inst.depth = newDepth()
+		inst.depth.rebuild(inst.price)
+	}
+	d := inst.depth
+	return map[string]interface{}{
+		"bids":      sorted(d.bids, true),
+		"asks":      sorted(d.asks, false),
+		"ts":        strconv.FormatInt(nowMillis(), 10),
+		"checksum":  d.checksum(),
+		"seqId":     d.seqId,
+		"prevSeqId": -1,
+	}
+}
+
+// pushBook 价格变化后推送深度增量，无订阅时不维护深度，调用方需持有锁
+func (s *Server) pushBook(inst *instrument) {
+	if inst.depth == nil || !s.hasSubscriber("books", inst.instId) {
+		return
+	}
+	prevSeqId := inst.depth.seqId
+	bids, asks := inst.depth.rebuild(inst.price)
+	if bids == nil {
+		bids = [][]string{}
+	}
+	if asks == nil {
+		asks = [][]string{}
+	}
+	s.events = append(s.events, wsEvent{
+		channel:  "books",
+		instId:   inst.instId,
+		instType: inst.instType,
+		action:   "update",
+		data: []map[string]interface{}{{
+			"bids":      bids,
+			"asks":      asks,
+			"ts":        strconv.FormatInt(nowMillis(), 10),
+			"checksum":  inst.depth.checksum(),
+			"seqId":     inst.depth.seqId,
+			"prevSeqId": prevSeqId,
+		}},
+	})
+}
@@ -0,0 +1,110 @@
+package okxsim
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 模拟的资金费率和收取间隔
+const (
+	simFundingRate     = 0.0001
+	simFundingInterval = 8 * time.Hour
+)
+
+// swapInstrument 返回永续合约，不存在时写入错误响应
+func (s *Server) swapInstrument(w http.ResponseWriter, instId string) (*instrument, bool) {
+	inst, ok := s.instruments[instId]
+	if !ok || inst.instType != "SWAP" {
+		writeError(w, &apiError{code: "51001", msg: "Instrument ID does not exist"})
+		return nil, false
+	}
+	return inst, true
+}
+
+// handleFundingRate GET /api/v5/public/funding-rate
+func (s *Server) handleFundingRate(w http.ResponseWriter, r *http.Request) {
+	instId := r.URL.Query().Get("instId")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.swapInstrument(w, instId); !ok {
+		return
+	}
+
+	next := time.Now().Truncate(simFundingInterval).Add(simFundingInterval)
+	writeData(w, []map[string]string{{
+		"instId":          instId,
+		"instType":        "SWAP",
+		"fundingRate":     formatFloat(simFundingRate),
+		"nextFundingRate": formatFloat(simFundingRate),
+		"fundingTime":     strconv.FormatInt(next.UnixMilli(), 10),
+		"nextFundingTime": strconv.FormatInt(next.Add(simFundingInterval).UnixMilli(), 10),
+	}})
+}
+
+// handleMarkPrice GET /api/v5/public/mark-price，标记价格等于最新价
+func (s *Server) handleMarkPrice(w http.ResponseWriter, r *http.Request) {
+	instId := r.URL.Query().Get("instId")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, ok := s.swapInstrument(w, instId)
+	if !ok {
+		return
+	}
+
+	writeData(w, []map[string]string{{
+		"instId":   instId,
+		"instType": "SWAP",
+		"markPx":   formatFloat(inst.price),
+		"ts":       strconv.FormatInt(nowMillis(), 10),
+	}})
+}
+
+// handleIndexTickers GET /api/v5/market/index-tickers，指数价格等于对应合约的最新价
+func (s *Server) handleIndexTickers(w http.ResponseWriter, r *http.Request) {
+	indexId := r.URL.Query().Get("instId")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, ok := s.instruments[indexId+"-SWAP"]
+	if !ok {
+		writeError(w, &apiError{code: "51001", msg: "Instrument ID does not exist"})
+		return
+	}
+
+	writeData(w, []map[string]string{{
+		"instId": indexId,
+		"idxPx":  formatFloat(inst.price),
+		"ts":     strconv.FormatInt(nowMillis(), 10),
+	}})
+}
+
+// handleOpenInterest GET /api/v5/public/open-interest，持仓总量为模拟账户多空持仓之和
+func (s *Server) handleOpenInterest(w http.ResponseWriter, r *http.Request) {
+	instId := r.URL.Query().Get("instId")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	inst, ok := s.swapInstrument(w, instId)
+	if !ok {
+		return
+	}
+
+	var oi float64
+	for key, pos := range s.positions {
+		if strings.HasPrefix(key, instId+"|") {
+			oi += pos.pos
+		}
+	}
+
+	writeData(w, []map[string]string{{
+		"instId":   instId,
+		"instType": "SWAP",
+		"oi":       formatFloat(oi),
+		"oiCcy":    formatFloat(oi * inst.ctVal),
+		"ts":       strconv.FormatInt(nowMillis(), 10),
+	}})
+}
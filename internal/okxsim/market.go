@@ -0,0 +1,323 @@
+package okxsim
+
+import (
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// instrument 模拟交易产品
+type instrument struct {
+	instId   string
+	instType string
+	ctVal    float64 // 合约面值，现货为1
+	price    float64
+	candles  []*candle // 1分钟K线，按时间升序
+	depth    *depth    // 模拟深度，首次订阅books频道时生成
+}
+
+// candle 1分钟K线
+type candle struct {
+	ts                   int64
+	open, high, low, cls float64
+	vol                  float64
+}
+
+// 最多保留的1分钟K线数量
+const maxCandles = 1440
+
+// barDurations 支持的K线周期
+var barDurations = map[string]time.Duration{
+	"1m":  time.Minute,
+	"3m":  3 * time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1H":  time.Hour,
+	"2H":  2 * time.Hour,
+	"4H":  4 * time.Hour,
+	"1D":  24 * time.Hour,
+}
+
+// AddInstrument 添加交易产品，instId以-SWAP结尾视为永续合约，并生成历史K线
+func (s *Server) AddInstrument(instId string, price, ctVal float64) {
+	instType := "SPOT"
+	if strings.HasSuffix(instId, "-SWAP") {
+		instType = "SWAP"
+	}
+	if ctVal <= 0 {
+		ctVal = 1
+	}
+
+	inst := &instrument{
+		instId:   instId,
+		instType: instType,
+		ctVal:    ctVal,
+		price:    price,
+	}
+	inst.candles = seedCandles(price, 300)
+
+	s.mu.Lock()
+	s.instruments[instId] = inst
+	s.mu.Unlock()
+}
+
+// seedCandles 生成以price收尾的随机历史K线，使用固定种子保证结果可复现
+func seedCandles(price float64, n int) []*candle {
+	rnd := rand.New(rand.NewSource(1))
+	closes := make([]float64, n)
+	closes[n-1] = price
+	for i := n - 2; i >= 0; i-- {
+		closes[i] = closes[i+1] * (1 + (rnd.Float64()-0.5)*0.002)
+	}
+
+	start := time.Now().Truncate(time.Minute).Add(-time.Duration(n-1) * time.Minute)
+	candles := make([]*candle, n)
+	open := closes[0]
+	for i, cls := range closes {
+		high, low := open, cls
+		if cls > open {
+			high, low = cls, open
+		}
+		candles[i] = &candle{
+			ts:   start.Add(time.Duration(i)*time.Minute).UnixNano() / int64(time.Millisecond),
+			open: open,
+			high: high,
+			low:  low,
+			cls:  cls,
+			vol:  float64(rnd.Intn(1000) + 1),
+		}
+		open = cls
+	}
+	return candles
+}
+
+// Price 返回当前价格
+func (s *Server) Price(instId string) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inst, ok := s.instruments[instId]; ok {
+		return inst.price
+	}
+	return 0
+}
+
+// SetPrice 更新最新价格，撮合挂单并推送行情和账户变化
+func (s *Server) SetPrice(instId string, price float64) {
+	s.mu.Lock()
+	inst, ok := s.instruments[instId]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	inst.price = price
+	inst.updateCandle(price, 0)
+	s.matchOrders(inst)
+	s.matchAlgos(inst)
+	s.pushMarket(inst)
+	s.pushPositions(instId)
+	s.pushAccount()
+	s.unlockAndFlush()
+}
+
+// RunRandomWalk 按固定间隔随机波动所有产品价格，volatility为单步最大涨跌幅
+func (s *Server) RunRandomWalk(interval time.Duration, volatility float64) {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChan:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			prices := make(map[string]float64, len(s.instruments))
+			for id, inst := range s.instruments {
+				prices[id] = inst.price * (1 + (rnd.Float64()*2-1)*volatility)
+			}
+			s.mu.Unlock()
+
+			for id, price := range prices {
+				s.SetPrice(id, price)
+			}
+		}
+	}
+}
+
+// updateCandle 用最新价格和成交量更新当前1分钟K线
+func (inst *instrument) updateCandle(price, vol float64) {
+	ts := time.Now().Truncate(time.Minute).UnixNano() / int64(time.Millisecond)
+	n := len(inst.candles)
+	if n > 0 && inst.candles[n-1].ts == ts {
+		c := inst.candles[n-1]
+		if price > c.high {
+			c.high = price
+		}
+		if price < c.low {
+			c.low = price
+		}
+		c.cls = price
+		c.vol += vol
+		return
+	}
+
+	inst.candles = append(inst.candles, &candle{ts: ts, open: price, high: price, low: price, cls: price, vol: vol})
+	if len(inst.candles) > maxCandles {
+		inst.candles = inst.candles[len(inst.candles)-maxCandles:]
+	}
+}
+
+// aggregate 将1分钟K线聚合为指定周期，结果按时间降序
+func (inst *instrument) aggregate(bar time.Duration) []*candle {
+	barMs := int64(bar / time.Millisecond)
+	buckets := make(map[int64]*candle)
+	for _, c := range inst.candles {
+		ts := c.ts - c.ts%barMs
+		b, ok := buckets[ts]
+		if !ok {
+			cp := *c
+			cp.ts = ts
+			buckets[ts] = &cp
+			continue
+		}
+		if c.high > b.high {
+			b.high = c.high
+		}
+		if c.low < b.low {
+			b.low = c.low
+		}
+		b.cls = c.cls
+		b.vol += c.vol
+	}
+
+	result := make([]*candle, 0, len(buckets))
+	for _, c := range buckets {
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ts > result[j].ts })
+	return result
+}
+
+func (c *candle) row(confirm bool) []string {
+	confirmStr := "0"
+	if confirm {
+		confirmStr = "1"
+	}
+	return []string{
+		strconv.FormatInt(c.ts, 10),
+		formatFloat(c.open),
+		formatFloat(c.high),
+		formatFloat(c.low),
+		formatFloat(c.cls),
+		formatFloat(c.vol),
+		formatFloat(c.vol),
+		formatFloat(c.vol * c.cls),
+		confirmStr,
+	}
+}
+
+// handleInstruments GET /api/v5/public/instruments
+func (s *Server) handleInstruments(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	instType := q.Get("instType")
+	instId := q.Get("instId")
+
+	s.mu.Lock()
+	data := make([]map[string]string, 0, len(s.instruments))
+	for _, inst := range s.instruments {
+		if instType != "" && inst.instType != instType {
+			continue
+		}
+		if instId != "" && inst.instId != instId {
+			continue
+		}
+		data = append(data, inst.data())
+	}
+	s.mu.Unlock()
+
+	sort.Slice(data, func(i, j int) bool { return data[i]["instId"] < data[j]["instId"] })
+	writeData(w, data)
+}
+
+// data 产品信息，数量精度和最小下单数量均为1，价格精度为0.0001
+func (inst *instrument) data() map[string]string {
+	parts := strings.Split(inst.instId, "-")
+	base, quote := parts[0], ""
+	if len(parts) > 1 {
+		quote = parts[1]
+	}
+	d := map[string]string{
+		"instId":   inst.instId,
+		"instType": inst.instType,
+		"lotSz":    "1",
+		"minSz":    "1",
+		"tickSz":   "0.0001",
+		"maxMktSz": "1000000",
+		"state":    "live",
+	}
+	if inst.instType == "SPOT" {
+		d["baseCcy"] = base
+		d["quoteCcy"] = quote
+		d["lotSz"] = "0.0001"
+		d["minSz"] = "0.0001"
+		return d
+	}
+	d["ctVal"] = formatFloat(inst.ctVal)
+	d["ctMult"] = "1"
+	d["ctType"] = "linear"
+	d["ctValCcy"] = base
+	d["settleCcy"] = quote
+	return d
+}
+
+// handleCandles GET /api/v5/market/candles
+func (s *Server) handleCandles(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	instId := q.Get("instId")
+	bar := q.Get("bar")
+	if bar == "" {
+		bar = "1m"
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 300 {
+		limit = 100
+	}
+	after, _ := strconv.ParseInt(q.Get("after"), 10, 64)
+	before, _ := strconv.ParseInt(q.Get("before"), 10, 64)
+
+	duration, ok := barDurations[bar]
+	if !ok {
+		writeError(w, &apiError{code: "51000", msg: "Parameter bar error"})
+		return
+	}
+
+	s.mu.Lock()
+	inst, ok := s.instruments[instId]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, &apiError{code: "51001", msg: "Instrument ID does not exist"})
+		return
+	}
+	candles := inst.aggregate(duration)
+	s.mu.Unlock()
+
+	current := time.Now().Truncate(duration).UnixNano() / int64(time.Millisecond)
+	data := make([][]string, 0, limit)
+	for _, c := range candles {
+		if after > 0 && c.ts >= after {
+			continue
+		}
+		if before > 0 && c.ts <= before {
+			continue
+		}
+		data = append(data, c.row(c.ts < current))
+		if len(data) >= limit {
+			break
+		}
+	}
+	writeData(w, data)
+}
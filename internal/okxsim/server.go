@@ -0,0 +1,281 @@
+// Package okxsim 提供本地OKX模拟服务器，实现交易、账户和行情的REST与WebSocket接口，
+// 用于离线测试和CI中端到端运行交易程序。
+package okxsim
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Server 模拟OKX服务器
+type Server struct {
+	mu sync.Mutex
+
+	apiKey     string
+	secretKey  string
+	passphrase string
+
+	instruments map[string]*instrument
+	orders      map[string]*order
+	algos       map[string]*algoOrder
+	orderSeq    int64
+	fills       []*fillRecord
+	bills       []*billRecord
+	positions   map[string]*position // key: instId|posSide
+	levers      map[string]float64   // key: instId|mgnMode|posSide
+	cash        map[string]float64   // 币种现金余额
+	funding     map[string]float64   // 资金账户余额
+	spotAvgPx   map[string]float64   // 现货持仓均价，key: 交易货币
+	transferSeq int64
+	takerRate   float64
+	makerRate   float64
+	mmrRate     float64
+	events      []wsEvent
+	clockSkew   time.Duration // 服务器时间相对本地时间的偏差，用于测试客户端校时
+	posMode     string        // 持仓模式: net_mode/long_short_mode
+
+	httpServer *httptest.Server
+	mux        *http.ServeMux
+	upgrader   websocket.Upgrader
+	connsMu    sync.Mutex
+	conns      map[*wsConn]bool
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// New 创建模拟服务器，初始资金为balance USDT，需调用Start或Handler后使用
+func New(balance float64) *Server {
+	s := &Server{
+		instruments: make(map[string]*instrument),
+		orders:      make(map[string]*order),
+		algos:       make(map[string]*algoOrder),
+		positions:   make(map[string]*position),
+		levers:      make(map[string]float64),
+		cash:        map[string]float64{"USDT": balance},
+		funding:     make(map[string]float64),
+		spotAvgPx:   make(map[string]float64),
+		takerRate:   0.0005,
+		makerRate:   0.0002,
+		mmrRate:     0.004,
+		posMode:     posModeLongShort,
+		conns:       make(map[*wsConn]bool),
+		stopChan:    make(chan struct{}),
+	}
+	s.upgrader.CheckOrigin = func(r *http.Request) bool { return true }
+	s.routes()
+	return s
+}
+
+// SetCredentials 设置API密钥，设置后会校验请求签名和WebSocket登录
+func (s *Server) SetCredentials(apiKey, secretKey, passphrase string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiKey = apiKey
+	s.secretKey = secretKey
+	s.passphrase = passphrase
+}
+
+// Start 使用httptest启动服务器，返回REST地址
+func (s *Server) Start() string {
+	s.httpServer = httptest.NewServer(s.mux)
+	return s.httpServer.URL
+}
+
+// URL 返回REST地址
+func (s *Server) URL() string {
+	if s.httpServer == nil {
+		return ""
+	}
+	return s.httpServer.URL
+}
+
+// WSURLs 返回公共、私有和业务频道的WebSocket地址
+func (s *Server) WSURLs() (public, private, business string) {
+	base := "ws" + strings.TrimPrefix(s.URL(), "http")
+	return base + "/ws/v5/public", base + "/ws/v5/private", base + "/ws/v5/business"
+}
+
+// Handler 返回HTTP处理器，便于在固定端口上运行
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Close 关闭服务器和所有WebSocket连接
+func (s *Server) Close() {
+	s.stopOnce.Do(func() {
+		close(s.stopChan)
+		s.connsMu.Lock()
+		for c := range s.conns {
+			c.conn.Close()
+		}
+		s.connsMu.Unlock()
+		if s.httpServer != nil {
+			s.httpServer.Close()
+		}
+	})
+}
+
+func (s *Server) routes() {
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/api/v5/trade/order", s.auth(s.handleOrder))
+	s.mux.HandleFunc("/api/v5/trade/cancel-order", s.auth(s.handleCancelOrder))
+	s.mux.HandleFunc("/api/v5/trade/batch-orders", s.auth(s.handleBatchOrders))
+	s.mux.HandleFunc("/api/v5/trade/cancel-batch-orders", s.auth(s.handleCancelBatchOrders))
+	s.mux.HandleFunc("/api/v5/trade/amend-order", s.auth(s.handleAmendOrder))
+	s.mux.HandleFunc("/api/v5/trade/amend-batch-orders", s.auth(s.handleAmendBatchOrders))
+	s.mux.HandleFunc("/api/v5/trade/fills", s.auth(s.handleFills))
+	s.mux.HandleFunc("/api/v5/trade/order-algo", s.auth(s.handleOrderAlgo))
+	s.mux.HandleFunc("/api/v5/trade/cancel-algos", s.auth(s.handleCancelAlgos))
+	s.mux.HandleFunc("/api/v5/trade/amend-algos", s.auth(s.handleAmendAlgos))
+	s.mux.HandleFunc("/api/v5/trade/orders-algo-pending", s.auth(s.handleAlgosPending))
+	s.mux.HandleFunc("/api/v5/account/positions", s.auth(s.handlePositions))
+	s.mux.HandleFunc("/api/v5/account/balance", s.auth(s.handleBalance))
+	s.mux.HandleFunc("/api/v5/account/set-leverage", s.auth(s.handleSetLeverage))
+	s.mux.HandleFunc("/api/v5/account/position/margin-balance", s.auth(s.handleMarginBalance))
+	s.mux.HandleFunc("/api/v5/account/config", s.auth(s.handleAccountConfig))
+	s.mux.HandleFunc("/api/v5/account/set-position-mode", s.auth(s.handleSetPositionMode))
+	s.mux.HandleFunc("/api/v5/account/max-size", s.auth(s.handleMaxSize))
+	s.mux.HandleFunc("/api/v5/account/max-avail-size", s.auth(s.handleMaxAvailSize))
+	s.mux.HandleFunc("/api/v5/account/trade-fee", s.auth(s.handleTradeFee))
+	s.mux.HandleFunc("/api/v5/account/bills", s.auth(s.handleBills))
+	s.mux.HandleFunc("/api/v5/account/bills-archive", s.auth(s.handleBills))
+	s.mux.HandleFunc("/api/v5/asset/balances", s.auth(s.handleAssetBalances))
+	s.mux.HandleFunc("/api/v5/asset/transfer", s.auth(s.handleTransfer))
+	s.mux.HandleFunc("/api/v5/market/candles", s.handleCandles)
+	s.mux.HandleFunc("/api/v5/market/history-candles", s.handleCandles)
+	s.mux.HandleFunc("/api/v5/public/instruments", s.handleInstruments)
+	s.mux.HandleFunc("/api/v5/public/time", s.handleTime)
+	s.mux.HandleFunc("/api/v5/public/funding-rate", s.handleFundingRate)
+	s.mux.HandleFunc("/api/v5/public/mark-price", s.handleMarkPrice)
+	s.mux.HandleFunc("/api/v5/public/open-interest", s.handleOpenInterest)
+	s.mux.HandleFunc("/api/v5/market/index-tickers", s.handleIndexTickers)
+	s.mux.HandleFunc("/ws/v5/public", s.handleWS(false))
+	s.mux.HandleFunc("/ws/v5/business", s.handleWS(false))
+	s.mux.HandleFunc("/ws/v5/private", s.handleWS(true))
+}
+
+// apiResponse OKX通用响应结构
+type apiResponse struct {
+	Code string      `json:"code"`
+	Msg  string      `json:"msg"`
+	Data interface{} `json:"data"`
+}
+
+// apiError 带错误码的业务错误
+type apiError struct {
+	code string
+	msg  string
+}
+
+func (e *apiError) Error() string {
+	return e.msg
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeData(w http.ResponseWriter, data interface{}) {
+	if data == nil {
+		data = []interface{}{}
+	}
+	writeJSON(w, http.StatusOK, apiResponse{Code: "0", Data: data})
+}
+
+func writeError(w http.ResponseWriter, err *apiError) {
+	writeJSON(w, http.StatusOK, apiResponse{Code: err.code, Msg: err.msg, Data: []interface{}{}})
+}
+
+// auth 校验签名，未设置密钥时只检查请求头是否存在
+func (s *Server) auth(next func(w http.ResponseWriter, r *http.Request, body []byte)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, apiResponse{Code: "50000", Msg: "读取请求失败", Data: []interface{}{}})
+			return
+		}
+
+		s.mu.Lock()
+		apiKey, secretKey, passphrase := s.apiKey, s.secretKey, s.passphrase
+		s.mu.Unlock()
+
+		if ts, err := time.Parse("2006-01-02T15:04:05.000Z", r.Header.Get("OK-ACCESS-TIMESTAMP")); err == nil {
+			if d := s.now().Sub(ts); d > requestExpiry || d < -requestExpiry {
+				writeJSON(w, http.StatusUnauthorized, apiResponse{Code: "50102", Msg: "Timestamp request expired", Data: []interface{}{}})
+				return
+			}
+		}
+
+		if r.Header.Get("OK-ACCESS-KEY") == "" {
+			writeJSON(w, http.StatusUnauthorized, apiResponse{Code: "50103", Msg: "Request header OK-ACCESS-KEY can not be empty.", Data: []interface{}{}})
+			return
+		}
+		if secretKey != "" {
+			timestamp := r.Header.Get("OK-ACCESS-TIMESTAMP")
+			expected := sign(secretKey, timestamp+r.Method+r.URL.RequestURI()+string(body))
+			if r.Header.Get("OK-ACCESS-KEY") != apiKey ||
+				r.Header.Get("OK-ACCESS-PASSPHRASE") != passphrase ||
+				r.Header.Get("OK-ACCESS-SIGN") != expected {
+				writeJSON(w, http.StatusUnauthorized, apiResponse{Code: "50113", Msg: "Invalid Sign", Data: []interface{}{}})
+				return
+			}
+		}
+
+		next(w, r, body)
+	}
+}
+
+func sign(secretKey, message string) string {
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(message))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// formatFloat 按OKX习惯将数值格式化为字符串
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// requestExpiry 请求时间戳与服务器时间的最大允许偏差
+const requestExpiry = 30 * time.Second
+
+// SetClockSkew 设置服务器时间相对本地时间的偏差
+func (s *Server) SetClockSkew(skew time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clockSkew = skew
+}
+
+// now 返回服务器时间
+func (s *Server) now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Now().Add(s.clockSkew)
+}
+
+func (s *Server) handleTime(w http.ResponseWriter, r *http.Request) {
+	writeData(w, []map[string]string{{"ts": strconv.FormatInt(s.now().UnixMilli(), 10)}})
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,133 @@
+package okxsim
+
+import (
+	"math"
+	"strings"
+)
+
+// 现货数量精度，与产品信息中的lotSz一致
+const spotLotSz = 0.0001
+
+// spotCcys 返回现货的交易货币和计价货币
+func (inst *instrument) spotCcys() (base, quote string) {
+	parts := strings.Split(inst.instId, "-")
+	base = parts[0]
+	if len(parts) > 1 {
+		quote = parts[1]
+	}
+	return base, quote
+}
+
+// checkSpotOrder 现货只支持cash模式；市价买单默认按计价货币下单，数量换算为交易货币；
+// 买入检查计价货币余额，卖出检查交易货币余额，调用方需持有锁
+func (s *Server) checkSpotOrder(inst *instrument, o *order, tgtCcy string, execPx float64) *apiError {
+	if o.tdMode != "cash" {
+		return &apiError{code: "51000", msg: "Parameter tdMode error"}
+	}
+	o.posSide = ""
+
+	if tgtCcy == "" && o.ordType == "market" && o.side == "buy" {
+		tgtCcy = "quote_ccy"
+	}
+	if tgtCcy == "quote_ccy" {
+		if o.ordType != "market" {
+			return &apiError{code: "51000", msg: "Parameter tgtCcy error"}
+		}
+		o.sz = math.Floor(o.sz/execPx/spotLotSz+1e-9) * spotLotSz
+		if o.sz <= 0 {
+			return &apiError{code: "51020", msg: "Order amount should be greater than the min available amount."}
+		}
+	}
+
+	base, quote := inst.spotCcys()
+	if o.side == "buy" && o.sz*execPx > s.available(quote) {
+		return &apiError{code: "51008", msg: "Order failed. Insufficient " + quote + " balance in account"}
+	}
+	if o.side == "sell" && o.sz > s.available(base) {
+		return &apiError{code: "51008", msg: "Order failed. Insufficient " + base + " balance in account"}
+	}
+	return nil
+}
+
+// fillSpot 现货成交，买入手续费以交易货币收取，卖出以计价货币收取；余额不足时撤单
+func (s *Server) fillSpot(inst *instrument, o *order, px, sz, feeRate float64) {
+	base, quote := inst.spotCcys()
+	cost := sz * px
+	if (o.side == "buy" && cost > s.cash[quote]) || (o.side == "sell" && sz > s.cash[base]) {
+		o.state = "canceled"
+		o.uTime = nowMillis()
+		s.pushOrder(o)
+		return
+	}
+
+	var fee float64
+	if o.side == "buy" {
+		fee = sz * feeRate
+		received := sz - fee
+		// 开仓均价按含手续费的成本计算
+		if held := s.cash[base]; held+received > 0 {
+			s.spotAvgPx[base] = (s.spotAvgPx[base]*held + cost) / (held + received)
+		}
+		s.cash[quote] -= cost
+		s.cash[base] += received
+		o.feeCcy = base
+	} else {
+		fee = cost * feeRate
+		s.cash[base] -= sz
+		s.cash[quote] += cost - fee
+		if s.cash[base] < spotLotSz {
+			delete(s.spotAvgPx, base)
+		}
+		o.feeCcy = quote
+	}
+
+	tradeId := s.recordFill(inst, o, px, sz, fee, feeRate)
+	s.addSpotBills(inst, o, tradeId, px, sz, fee)
+
+	s.pushTrade(inst, o.side, px, sz)
+	s.pushOrder(o)
+	s.pushAccount()
+}
+
+// addSpotBills 现货成交在交易货币和计价货币上各记一笔账单，成交数量记在交易货币账单上，
+// fee为正数表示收取，调用方需持有锁
+func (s *Server) addSpotBills(inst *instrument, o *order, tradeId int64, px, sz, fee float64) {
+	base, quote := inst.spotCcys()
+	subType, baseChg, quoteChg, baseFee, quoteFee := "1", sz-fee, -sz*px, -fee, 0.0
+	if o.side == "sell" {
+		subType, baseChg, quoteChg, baseFee, quoteFee = "2", -sz, sz*px-fee, 0, -fee
+	}
+
+	add := func(ccy string, balChg, billSz, billFee float64) {
+		s.bills = append(s.bills, &billRecord{
+			billId:   int64(len(s.bills) + 1),
+			instId:   o.instId,
+			instType: inst.instType,
+			ccy:      ccy,
+			typ:      "2",
+			subType:  subType,
+			mgnMode:  "cash",
+			balChg:   balChg,
+			bal:      s.cash[ccy],
+			sz:       billSz,
+			px:       px,
+			fee:      billFee,
+			ordId:    o.ordId,
+			tradeId:  tradeId,
+			ts:       nowMillis(),
+		})
+	}
+	add(base, baseChg, sz, baseFee)
+	add(quote, quoteChg, 0, quoteFee)
+}
+
+// usdPrice 返回币种的USDT价格，没有对应现货时为0
+func (s *Server) usdPrice(ccy string) float64 {
+	if ccy == "USDT" {
+		return 1
+	}
+	if inst, ok := s.instruments[ccy+"-USDT"]; ok {
+		return inst.price
+	}
+	return 0
+}
@@ -0,0 +1,830 @@
+package okxsim
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// 默认杠杆倍数
+const defaultLever = 10
+
+// order 模拟订单
+type order struct {
+	ordId   string
+	clOrdId string
+	instId  string
+	tdMode  string
+	side    string
+	posSide string
+	ordType string
+	px      float64
+	sz      float64
+	lever   float64
+
+	state     string
+	accFillSz float64
+	avgPx     float64
+	fillPx    float64
+	fillSz    float64
+	fee       float64
+	feeCcy    string
+	frozen    float64 // 挂单冻结保证金
+	cTime     int64
+	uTime     int64
+}
+
+// fillRecord 成交明细
+type fillRecord struct {
+	tradeId  int64
+	instId   string
+	ordId    string
+	clOrdId  string
+	side     string
+	posSide  string
+	px       float64
+	sz       float64
+	fee      float64
+	feeCcy   string
+	execType string
+	ts       int64
+}
+
+// position 模拟持仓，net模式下pos为带符号数量
+type position struct {
+	instId  string
+	posSide string
+	mgnMode string
+	pos     float64
+	avgPx   float64
+	margin  float64
+	lever   float64
+}
+
+func positionKey(instId, posSide string) string {
+	return instId + "|" + posSide
+}
+
+// direction 持仓方向，多头为1，空头为-1
+func (p *position) direction() float64 {
+	if p.posSide == "short" || (p.posSide == "net" && p.pos < 0) {
+		return -1
+	}
+	return 1
+}
+
+func (p *position) upl(price, ctVal float64) float64 {
+	return (price - p.avgPx) * math.Abs(p.pos) * ctVal * p.direction()
+}
+
+type orderRequest struct {
+	InstId     string `json:"instId"`
+	TdMode     string `json:"tdMode"`
+	Side       string `json:"side"`
+	PosSide    string `json:"posSide"`
+	OrdType    string `json:"ordType"`
+	Sz         string `json:"sz"`
+	Px         string `json:"px"`
+	ClOrdId    string `json:"clOrdId"`
+	ReduceOnly bool   `json:"reduceOnly"`
+	TgtCcy     string `json:"tgtCcy"`
+}
+
+// handleOrder POST下单，GET查询订单
+func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request, body []byte) {
+	if r.Method == http.MethodGet {
+		s.handleGetOrder(w, r)
+		return
+	}
+
+	var req orderRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, &apiError{code: "50002", msg: "JSON syntax error"})
+		return
+	}
+
+	s.mu.Lock()
+	o, err := s.placeOrder(&req)
+	s.unlockAndFlush()
+
+	if err != nil {
+		writeJSON(w, http.StatusOK, apiResponse{
+			Code: "1",
+			Msg:  "All operations failed",
+			Data: []map[string]string{{
+				"ordId":   "",
+				"clOrdId": req.ClOrdId,
+				"tag":     "",
+				"sCode":   err.code,
+				"sMsg":    err.msg,
+			}},
+		})
+		return
+	}
+
+	writeData(w, []map[string]string{{
+		"ordId":   o.ordId,
+		"clOrdId": o.clOrdId,
+		"tag":     "",
+		"sCode":   "0",
+		"sMsg":    "Order placed",
+	}})
+}
+
+// placeOrder 校验并创建订单，市价单立即成交，调用方需持有锁
+func (s *Server) placeOrder(req *orderRequest) (*order, *apiError) {
+	inst, ok := s.instruments[req.InstId]
+	if !ok {
+		return nil, &apiError{code: "51001", msg: "Instrument ID does not exist"}
+	}
+	if req.Side != "buy" && req.Side != "sell" {
+		return nil, &apiError{code: "51000", msg: "Parameter side error"}
+	}
+	if req.OrdType != "market" && req.OrdType != "limit" {
+		return nil, &apiError{code: "51000", msg: "Parameter ordType error"}
+	}
+
+	sz := parseFloat(req.Sz)
+	if sz <= 0 {
+		return nil, &apiError{code: "51000", msg: "Parameter sz error"}
+	}
+	if inst.instType != "SPOT" && sz != math.Floor(sz) {
+		return nil, &apiError{code: "51121", msg: "Order quantity must be a multiple of the lot size"}
+	}
+	px := parseFloat(req.Px)
+	if req.OrdType == "limit" && px <= 0 {
+		return nil, &apiError{code: "51000", msg: "Parameter px error"}
+	}
+
+	if req.ClOrdId != "" && s.findOrder(req.InstId, "", req.ClOrdId) != nil {
+		return nil, &apiError{code: "51016", msg: "Duplicated clOrdId"}
+	}
+
+	if err := s.checkPosSide(inst, req.PosSide); err != nil {
+		return nil, err
+	}
+	posSide := req.PosSide
+	if posSide == "" {
+		posSide = "net"
+	}
+
+	o := &order{
+		clOrdId: req.ClOrdId,
+		instId:  req.InstId,
+		tdMode:  req.TdMode,
+		side:    req.Side,
+		posSide: posSide,
+		ordType: req.OrdType,
+		px:      px,
+		sz:      sz,
+		lever:   s.lever(req.InstId, req.TdMode, posSide),
+		feeCcy:  "USDT",
+		state:   "live",
+		cTime:   nowMillis(),
+	}
+	o.uTime = o.cTime
+
+	execPx := px
+	if req.OrdType == "market" {
+		execPx = inst.price
+	}
+
+	if inst.instType == "SPOT" {
+		if err := s.checkSpotOrder(inst, o, req.TgtCcy, execPx); err != nil {
+			return nil, err
+		}
+	} else if err := s.checkMargin(inst, o, req.ReduceOnly, execPx); err != nil {
+		return nil, err
+	}
+
+	s.orderSeq++
+	o.ordId = strconv.FormatInt(600000000000000000+s.orderSeq, 10)
+	s.orders[o.ordId] = o
+	s.pushOrder(o)
+
+	if req.OrdType == "market" {
+		s.fill(inst, o, inst.price, o.sz, s.takerRate)
+	} else {
+		s.matchOrders(inst)
+	}
+
+	return o, nil
+}
+
+// checkMargin 开仓检查保证金，平仓检查持仓数量，只减仓订单的数量缩减为可平数量
+func (s *Server) checkMargin(inst *instrument, o *order, reduceOnly bool, execPx float64) *apiError {
+	openSz, closeSz := s.splitOrder(o)
+	if closeSz > 0 && openSz == 0 && !s.hasPosition(o.instId, o.posSide) {
+		return &apiError{code: "51169", msg: "Order failed because you don't have any positions in this direction for this contract to reduce or close."}
+	}
+	if reduceOnly && openSz > 0 {
+		if closeSz == 0 {
+			return &apiError{code: "51169", msg: "Order failed because you don't have any positions in this direction for this contract to reduce or close."}
+		}
+		o.sz, openSz = closeSz, 0
+	}
+	if openSz > 0 {
+		required := openSz*inst.ctVal*execPx/o.lever + openSz*inst.ctVal*execPx*s.takerRate
+		if required > s.available("USDT") {
+			return &apiError{code: "51008", msg: "Order failed. Insufficient USDT margin in account"}
+		}
+		if o.ordType == "limit" {
+			o.frozen = openSz * inst.ctVal * execPx / o.lever
+		}
+	}
+	return nil
+}
+
+// splitOrder 计算订单中开仓和平仓的数量
+func (s *Server) splitOrder(o *order) (openSz, closeSz float64) {
+	switch o.posSide {
+	case "long":
+		if o.side == "buy" {
+			return o.sz, 0
+		}
+		return 0, o.sz
+	case "short":
+		if o.side == "sell" {
+			return o.sz, 0
+		}
+		return 0, o.sz
+	}
+
+	// net模式：与现有持仓方向相反的部分为平仓
+	pos := 0.0
+	if p, ok := s.positions[positionKey(o.instId, "net")]; ok {
+		pos = p.pos
+	}
+	if (o.side == "buy" && pos < 0) || (o.side == "sell" && pos > 0) {
+		closeSz = math.Min(o.sz, math.Abs(pos))
+		return o.sz - closeSz, closeSz
+	}
+	return o.sz, 0
+}
+
+func (s *Server) hasPosition(instId, posSide string) bool {
+	p, ok := s.positions[positionKey(instId, posSide)]
+	return ok && p.pos != 0
+}
+
+// matchOrders 撮合限价挂单，买单在价格不高于委托价时成交，卖单反之
+func (s *Server) matchOrders(inst *instrument) {
+	for _, o := range s.orders {
+		if o.instId != inst.instId || o.ordType != "limit" {
+			continue
+		}
+		if o.state != "live" && o.state != "partially_filled" {
+			continue
+		}
+		if (o.side == "buy" && inst.price <= o.px) || (o.side == "sell" && inst.price >= o.px) {
+			s.fill(inst, o, o.px, o.sz-o.accFillSz, s.makerRate)
+		}
+	}
+}
+
+// fill 成交订单并更新持仓、余额，平仓数量超过持仓时只成交持仓部分
+func (s *Server) fill(inst *instrument, o *order, px, sz, feeRate float64) {
+	if inst.instType == "SPOT" {
+		s.fillSpot(inst, o, px, sz, feeRate)
+		return
+	}
+
+	openSz, closeSz := s.splitOrder(o)
+	if closeSz > 0 {
+		p, ok := s.positions[positionKey(o.instId, o.posSide)]
+		if !ok {
+			closeSz = 0
+		} else if closeSz > math.Abs(p.pos) {
+			closeSz = math.Abs(p.pos)
+		}
+		closeSz = math.Min(closeSz, sz)
+	}
+	if openSz+closeSz < sz {
+		sz = openSz + closeSz
+	}
+	if sz <= 0 {
+		o.state = "canceled"
+		o.uTime = nowMillis()
+		s.pushOrder(o)
+		return
+	}
+
+	fee := sz * inst.ctVal * px * feeRate
+	s.cash["USDT"] -= fee
+
+	pnl := 0.0
+	if closeSz > 0 {
+		pnl = s.reducePosition(inst, o.instId, o.posSide, px, closeSz)
+	}
+	if openSz > 0 {
+		s.increasePosition(inst, o, px, math.Min(openSz, sz-closeSz))
+	}
+
+	tradeId := s.recordFill(inst, o, px, sz, fee, feeRate)
+	s.addTradeBill(inst, o, tradeId, px, sz, pnl, -fee)
+
+	s.pushTrade(inst, o.side, px, sz)
+	s.pushOrder(o)
+	s.pushPositions(o.instId)
+	s.pushAccount()
+}
+
+// recordFill 更新订单成交状态并记录成交明细，fee为正数表示收取，返回成交ID
+func (s *Server) recordFill(inst *instrument, o *order, px, sz, fee, feeRate float64) int64 {
+	o.avgPx = (o.avgPx*o.accFillSz + px*sz) / (o.accFillSz + sz)
+	o.accFillSz += sz
+	o.fillPx = px
+	o.fillSz = sz
+	o.fee -= fee
+	o.frozen = 0
+	execType := "T"
+	if feeRate == s.makerRate {
+		execType = "M"
+	}
+	tradeId := int64(len(s.fills) + 1)
+	s.fills = append(s.fills, &fillRecord{
+		tradeId:  tradeId,
+		instId:   o.instId,
+		ordId:    o.ordId,
+		clOrdId:  o.clOrdId,
+		side:     o.side,
+		posSide:  o.posSide,
+		px:       px,
+		sz:       sz,
+		fee:      -fee,
+		feeCcy:   o.feeCcy,
+		execType: execType,
+		ts:       nowMillis(),
+	})
+	o.uTime = nowMillis()
+	if o.accFillSz >= o.sz {
+		o.state = "filled"
+	} else {
+		o.state = "partially_filled"
+	}
+	inst.updateCandle(px, sz)
+	return tradeId
+}
+
+func (s *Server) increasePosition(inst *instrument, o *order, px, sz float64) {
+	if sz <= 0 {
+		return
+	}
+	key := positionKey(o.instId, o.posSide)
+	p, ok := s.positions[key]
+	if !ok {
+		p = &position{instId: o.instId, posSide: o.posSide, mgnMode: o.tdMode, lever: o.lever}
+		s.positions[key] = p
+	}
+
+	size := math.Abs(p.pos)
+	if size == 0 {
+		p.lever = o.lever
+		p.mgnMode = o.tdMode
+	}
+	p.avgPx = (p.avgPx*size + px*sz) / (size + sz)
+	p.margin += sz * inst.ctVal * px / p.lever
+	if o.posSide == "net" && o.side == "sell" {
+		p.pos -= sz
+	} else {
+		p.pos += sz
+	}
+}
+
+// reducePosition 减少持仓并结算已实现盈亏，返回盈亏金额
+func (s *Server) reducePosition(inst *instrument, instId, posSide string, px, sz float64) float64 {
+	key := positionKey(instId, posSide)
+	p, ok := s.positions[key]
+	if !ok {
+		return 0
+	}
+
+	size := math.Abs(p.pos)
+	pnl := (px - p.avgPx) * sz * inst.ctVal * p.direction()
+	released := p.margin * sz / size
+	s.cash["USDT"] += pnl
+	p.margin -= released
+
+	if p.pos > 0 {
+		p.pos -= sz
+	} else {
+		p.pos += sz
+	}
+	if math.Abs(p.pos) < 1e-12 {
+		p.pos = 0
+	}
+	return pnl
+}
+
+// lever 返回产品杠杆倍数，未设置时使用默认值
+func (s *Server) lever(instId, mgnMode, posSide string) float64 {
+	if lever, ok := s.levers[instId+"|"+mgnMode+"|"+posSide]; ok {
+		return lever
+	}
+	if lever, ok := s.levers[instId+"|"+mgnMode+"|"]; ok {
+		return lever
+	}
+	return defaultLever
+}
+
+// equity 返回币种权益(现金+未实现盈亏)
+func (s *Server) equity(ccy string) float64 {
+	eq := s.cash[ccy]
+	if ccy == "USDT" {
+		for _, p := range s.positions {
+			if inst, ok := s.instruments[p.instId]; ok && p.pos != 0 {
+				eq += p.upl(inst.price, inst.ctVal)
+			}
+		}
+	}
+	return eq
+}
+
+// available 返回可用余额(现金-占用保证金-挂单冻结)
+func (s *Server) available(ccy string) float64 {
+	avail := s.cash[ccy]
+	if ccy == "USDT" {
+		for _, p := range s.positions {
+			avail -= p.margin
+		}
+		for _, o := range s.orders {
+			if o.state == "live" || o.state == "partially_filled" {
+				avail -= o.frozen
+			}
+		}
+	}
+	return avail
+}
+
+// handleGetOrder GET /api/v5/trade/order
+func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, o := range s.orders {
+		if o.instId != q.Get("instId") {
+			continue
+		}
+		if (q.Get("ordId") != "" && o.ordId == q.Get("ordId")) ||
+			(q.Get("clOrdId") != "" && o.clOrdId == q.Get("clOrdId")) {
+			writeData(w, []map[string]string{s.orderData(o)})
+			return
+		}
+	}
+	writeError(w, &apiError{code: "51603", msg: "Order does not exist"})
+}
+
+// handleFills GET /api/v5/trade/fills，按时间倒序返回
+func (s *Server) handleFills(w http.ResponseWriter, r *http.Request, body []byte) {
+	q := r.URL.Query()
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := make([]map[string]string, 0)
+	for i := len(s.fills) - 1; i >= 0 && len(data) < limit; i-- {
+		f := s.fills[i]
+		if q.Get("instId") != "" && f.instId != q.Get("instId") {
+			continue
+		}
+		if q.Get("ordId") != "" && f.ordId != q.Get("ordId") {
+			continue
+		}
+		instType := "SWAP"
+		if inst, ok := s.instruments[f.instId]; ok {
+			instType = inst.instType
+		}
+		data = append(data, map[string]string{
+			"instType": instType,
+			"instId":   f.instId,
+			"tradeId":  strconv.FormatInt(f.tradeId, 10),
+			"ordId":    f.ordId,
+			"clOrdId":  f.clOrdId,
+			"billId":   strconv.FormatInt(f.tradeId, 10),
+			"side":     f.side,
+			"posSide":  f.posSide,
+			"fillPx":   formatFloat(f.px),
+			"fillSz":   formatFloat(f.sz),
+			"fee":      formatFloat(f.fee),
+			"feeCcy":   f.feeCcy,
+			"execType": f.execType,
+			"ts":       strconv.FormatInt(f.ts, 10),
+		})
+	}
+	writeData(w, data)
+}
+
+// handleCancelOrder POST /api/v5/trade/cancel-order
+func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req cancelRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, &apiError{code: "50002", msg: "JSON syntax error"})
+		return
+	}
+
+	s.mu.Lock()
+	target, err := s.cancelOrder(&req)
+	s.unlockAndFlush()
+
+	if err != nil {
+		writeJSON(w, http.StatusOK, apiResponse{
+			Code: "1",
+			Msg:  "All operations failed",
+			Data: []map[string]string{{"ordId": req.OrdId, "clOrdId": req.ClOrdId, "sCode": err.code, "sMsg": err.msg}},
+		})
+		return
+	}
+
+	writeData(w, []map[string]string{{"ordId": target.ordId, "clOrdId": target.clOrdId, "sCode": "0", "sMsg": ""}})
+}
+
+type cancelRequest struct {
+	InstId  string `json:"instId"`
+	OrdId   string `json:"ordId"`
+	ClOrdId string `json:"clOrdId"`
+}
+
+// findOrder 按ordId或clOrdId查找订单，调用方需持有锁
+func (s *Server) findOrder(instId, ordId, clOrdId string) *order {
+	for _, o := range s.orders {
+		if o.instId == instId && ((ordId != "" && o.ordId == ordId) || (clOrdId != "" && o.clOrdId == clOrdId)) {
+			return o
+		}
+	}
+	return nil
+}
+
+// cancelOrder 撤销未完成订单，调用方需持有锁
+func (s *Server) cancelOrder(req *cancelRequest) (*order, *apiError) {
+	target := s.findOrder(req.InstId, req.OrdId, req.ClOrdId)
+	if target == nil || (target.state != "live" && target.state != "partially_filled") {
+		return nil, &apiError{code: "51400", msg: "Cancellation failed as the order has been filled, canceled or does not exist."}
+	}
+	target.state = "canceled"
+	target.frozen = 0
+	target.uTime = nowMillis()
+	s.pushOrder(target)
+	s.pushAccount()
+	return target, nil
+}
+
+func (s *Server) orderData(o *order) map[string]string {
+	instType := "SWAP"
+	if inst, ok := s.instruments[o.instId]; ok {
+		instType = inst.instType
+	}
+	posSide := o.posSide
+	px := ""
+	if o.px > 0 {
+		px = formatFloat(o.px)
+	}
+	return map[string]string{
+		"instType":  instType,
+		"instId":    o.instId,
+		"ordId":     o.ordId,
+		"clOrdId":   o.clOrdId,
+		"side":      o.side,
+		"posSide":   posSide,
+		"ordType":   o.ordType,
+		"tdMode":    o.tdMode,
+		"state":     o.state,
+		"px":        px,
+		"sz":        formatFloat(o.sz),
+		"avgPx":     formatFloat(o.avgPx),
+		"accFillSz": formatFloat(o.accFillSz),
+		"fillPx":    formatFloat(o.fillPx),
+		"fillSz":    formatFloat(o.fillSz),
+		"fee":       formatFloat(o.fee),
+		"feeCcy":    o.feeCcy,
+		"lever":     formatFloat(o.lever),
+		"cTime":     strconv.FormatInt(o.cTime, 10),
+		"uTime":     strconv.FormatInt(o.uTime, 10),
+	}
+}
+
+// positionData 生成持仓数据，已平仓位输出数量为0
+func (s *Server) positionData(p *position) map[string]string {
+	inst := s.instruments[p.instId]
+	upl := p.upl(inst.price, inst.ctVal)
+	notional := math.Abs(p.pos) * inst.ctVal * inst.price
+	mmr := notional * s.mmrRate
+
+	uplRatio, mgnRatio := 0.0, 0.0
+	if p.margin > 0 {
+		uplRatio = upl / p.margin
+	}
+	if mmr > 0 {
+		mgnRatio = (p.margin + upl) / mmr
+	}
+
+	return map[string]string{
+		"instType":    inst.instType,
+		"instId":      p.instId,
+		"posSide":     p.posSide,
+		"pos":         formatFloat(p.pos),
+		"avgPx":       formatFloat(p.avgPx),
+		"upl":         formatFloat(upl),
+		"uplRatio":    formatFloat(uplRatio),
+		"lever":       formatFloat(p.lever),
+		"mgnMode":     p.mgnMode,
+		"margin":      formatFloat(p.margin),
+		"mgnRatio":    formatFloat(mgnRatio),
+		"mmr":         formatFloat(mmr),
+		"markPx":      formatFloat(inst.price),
+		"last":        formatFloat(inst.price),
+		"notionalUsd": formatFloat(notional),
+		"ccy":         "USDT",
+		"uTime":       strconv.FormatInt(nowMillis(), 10),
+	}
+}
+
+// handlePositions GET /api/v5/account/positions
+func (s *Server) handlePositions(w http.ResponseWriter, r *http.Request, body []byte) {
+	instId := r.URL.Query().Get("instId")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := make([]map[string]string, 0)
+	for _, p := range s.positions {
+		if p.pos == 0 || (instId != "" && p.instId != instId) {
+			continue
+		}
+		data = append(data, s.positionData(p))
+	}
+	writeData(w, data)
+}
+
+// balanceData 生成账户余额数据
+func (s *Server) balanceData() map[string]interface{} {
+	totalEq, imr, mmr, isoEq, notional := 0.0, 0.0, 0.0, 0.0, 0.0
+	for _, p := range s.positions {
+		if p.pos == 0 {
+			continue
+		}
+		inst := s.instruments[p.instId]
+		n := math.Abs(p.pos) * inst.ctVal * inst.price
+		notional += n
+		imr += n / p.lever
+		mmr += n * s.mmrRate
+		isoEq += p.margin + p.upl(inst.price, inst.ctVal)
+	}
+	// 合约均以USDT结算，保证金只计入USDT
+	details := make([]map[string]string, 0, len(s.cash))
+	for ccy, cash := range s.cash {
+		eq := s.equity(ccy)
+		eqUsd := eq * s.usdPrice(ccy)
+		totalEq += eqUsd
+		detail := map[string]string{
+			"ccy":       ccy,
+			"eq":        formatFloat(eq),
+			"eqUsd":     formatFloat(eqUsd),
+			"cashBal":   formatFloat(cash),
+			"availBal":  formatFloat(s.available(ccy)),
+			"availEq":   formatFloat(s.available(ccy)),
+			"frozenBal": formatFloat(cash - s.available(ccy)),
+			"upl":       formatFloat(eq - cash),
+			"disEq":     formatFloat(eq),
+			"imr":       "0",
+			"mmr":       "0",
+			"mgnRatio":  "",
+		}
+		// 现货持仓的均价和未实现盈亏
+		if avgPx, ok := s.spotAvgPx[ccy]; ok && avgPx > 0 {
+			upl := (s.usdPrice(ccy) - avgPx) * cash
+			detail["openAvgPx"] = formatFloat(avgPx)
+			detail["accAvgPx"] = formatFloat(avgPx)
+			detail["spotUpl"] = formatFloat(upl)
+			detail["spotUplRatio"] = formatFloat(upl / (avgPx * cash))
+		}
+		if ccy == "USDT" {
+			detail["imr"] = formatFloat(imr)
+			detail["mmr"] = formatFloat(mmr)
+			if mmr > 0 {
+				detail["mgnRatio"] = formatFloat(eq / mmr)
+			}
+		}
+		details = append(details, detail)
+	}
+	mgnRatio := ""
+	if mmr > 0 {
+		mgnRatio = formatFloat(totalEq / mmr)
+	}
+
+	return map[string]interface{}{
+		"totalEq":     formatFloat(totalEq),
+		"adjEq":       formatFloat(totalEq),
+		"isoEq":       formatFloat(isoEq),
+		"imr":         formatFloat(imr),
+		"mmr":         formatFloat(mmr),
+		"mgnRatio":    mgnRatio,
+		"notionalUsd": formatFloat(notional),
+		"details":     details,
+		"uTime":       strconv.FormatInt(nowMillis(), 10),
+	}
+}
+
+// handleBalance GET /api/v5/account/balance
+func (s *Server) handleBalance(w http.ResponseWriter, r *http.Request, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeData(w, []map[string]interface{}{s.balanceData()})
+}
+
+// handleSetLeverage POST /api/v5/account/set-leverage
+func (s *Server) handleSetLeverage(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req struct {
+		InstId  string `json:"instId"`
+		Lever   string `json:"lever"`
+		MgnMode string `json:"mgnMode"`
+		PosSide string `json:"posSide"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, &apiError{code: "50002", msg: "JSON syntax error"})
+		return
+	}
+
+	lever := parseFloat(req.Lever)
+	if lever < 1 || lever > 125 {
+		writeError(w, &apiError{code: "51000", msg: "Parameter lever error"})
+		return
+	}
+
+	s.mu.Lock()
+	if _, ok := s.instruments[req.InstId]; !ok {
+		s.mu.Unlock()
+		writeError(w, &apiError{code: "51001", msg: "Instrument ID does not exist"})
+		return
+	}
+	s.levers[req.InstId+"|"+req.MgnMode+"|"+req.PosSide] = lever
+	s.mu.Unlock()
+
+	writeData(w, []map[string]string{{
+		"instId":  req.InstId,
+		"lever":   req.Lever,
+		"mgnMode": req.MgnMode,
+		"posSide": req.PosSide,
+	}})
+}
+
+// handleMarginBalance POST /api/v5/account/position/margin-balance
+func (s *Server) handleMarginBalance(w http.ResponseWriter, r *http.Request, body []byte) {
+	var req struct {
+		InstId  string `json:"instId"`
+		PosSide string `json:"posSide"`
+		Type    string `json:"type"`
+		Amt     string `json:"amt"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, &apiError{code: "50002", msg: "JSON syntax error"})
+		return
+	}
+	if req.PosSide == "" {
+		req.PosSide = "net"
+	}
+
+	amt := parseFloat(req.Amt)
+	if amt <= 0 || (req.Type != "add" && req.Type != "reduce") {
+		writeError(w, &apiError{code: "51000", msg: "Parameter amt or type error"})
+		return
+	}
+
+	s.mu.Lock()
+	p, ok := s.positions[positionKey(req.InstId, req.PosSide)]
+	if !ok || p.pos == 0 {
+		s.mu.Unlock()
+		writeError(w, &apiError{code: "51169", msg: "Position does not exist"})
+		return
+	}
+	if req.Type == "add" {
+		if amt > s.available("USDT") {
+			s.mu.Unlock()
+			writeError(w, &apiError{code: "51008", msg: "Insufficient balance"})
+			return
+		}
+		p.margin += amt
+	} else {
+		if amt >= p.margin {
+			s.mu.Unlock()
+			writeError(w, &apiError{code: "51000", msg: "Parameter amt error"})
+			return
+		}
+		p.margin -= amt
+	}
+	s.pushPositions(req.InstId)
+	s.pushAccount()
+	s.unlockAndFlush()
+
+	writeData(w, []map[string]string{{
+		"instId":  req.InstId,
+		"posSide": req.PosSide,
+		"amt":     req.Amt,
+		"type":    req.Type,
+	}})
+}
@@ -0,0 +1,389 @@
+package okxsim
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsArg 订阅频道参数
+type wsArg struct {
+	Channel  string `json:"channel"`
+	InstId   string `json:"instId,omitempty"`
+	InstType string `json:"instType,omitempty"`
+	Ccy      string `json:"ccy,omitempty"`
+}
+
+// wsEvent 待推送的频道数据
+type wsEvent struct {
+	channel  string
+	instId   string
+	instType string
+	private  bool
+	action   string // 深度频道: snapshot/update
+	data     interface{}
+}
+
+// wsConn WebSocket连接及其订阅
+type wsConn struct {
+	conn     *websocket.Conn
+	private  bool
+	writeMu  sync.Mutex
+	mu       sync.Mutex
+	loggedIn bool
+	subs     map[wsArg]bool
+}
+
+func (c *wsConn) writeJSON(v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	return c.conn.WriteJSON(v)
+}
+
+// match 返回与推送匹配的订阅参数
+func (c *wsConn) match(ev *wsEvent) (wsArg, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ev.private && !c.loggedIn {
+		return wsArg{}, false
+	}
+	for arg := range c.subs {
+		if arg.Channel != ev.channel {
+			continue
+		}
+		if arg.InstId != "" && arg.InstId != ev.instId {
+			continue
+		}
+		if arg.InstType != "" && arg.InstType != "ANY" && arg.InstType != ev.instType {
+			continue
+		}
+		return arg, true
+	}
+	return wsArg{}, false
+}
+
+// handleWS 处理WebSocket连接，private为true时需要登录
+func (s *Server) handleWS(private bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := s.upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		c := &wsConn{conn: conn, private: private, subs: make(map[wsArg]bool)}
+		s.connsMu.Lock()
+		s.conns[c] = true
+		s.connsMu.Unlock()
+
+		defer func() {
+			s.connsMu.Lock()
+			delete(s.conns, c)
+			s.connsMu.Unlock()
+			conn.Close()
+		}()
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			if string(data) == "ping" {
+				c.writeMu.Lock()
+				conn.WriteMessage(websocket.TextMessage, []byte("pong"))
+				c.writeMu.Unlock()
+				continue
+			}
+
+			var req struct {
+				Op   string            `json:"op"`
+				Args []json.RawMessage `json:"args"`
+			}
+			if err := json.Unmarshal(data, &req); err != nil {
+				c.writeJSON(map[string]string{"event": "error", "code": "60012", "msg": "Invalid request"})
+				continue
+			}
+
+			switch req.Op {
+			case "login":
+				s.wsLogin(c, req.Args)
+			case "subscribe", "unsubscribe":
+				for _, raw := range req.Args {
+					var arg wsArg
+					if err := json.Unmarshal(raw, &arg); err != nil {
+						continue
+					}
+					s.wsSubscribe(c, req.Op, arg)
+				}
+			default:
+				c.writeJSON(map[string]string{"event": "error", "code": "60012", "msg": "Invalid request: " + req.Op})
+			}
+		}
+	}
+}
+
+func (s *Server) wsLogin(c *wsConn, args []json.RawMessage) {
+	if !c.private || len(args) == 0 {
+		c.writeJSON(map[string]string{"event": "error", "code": "60012", "msg": "Invalid request"})
+		return
+	}
+
+	var arg struct {
+		ApiKey     string `json:"apiKey"`
+		Passphrase string `json:"passphrase"`
+		Timestamp  string `json:"timestamp"`
+		Sign       string `json:"sign"`
+	}
+	json.Unmarshal(args[0], &arg)
+
+	s.mu.Lock()
+	apiKey, secretKey, passphrase := s.apiKey, s.secretKey, s.passphrase
+	s.mu.Unlock()
+
+	if secretKey != "" {
+		expected := sign(secretKey, arg.Timestamp+"GET/users/self/verify")
+		if arg.ApiKey != apiKey || arg.Passphrase != passphrase || arg.Sign != expected {
+			c.writeJSON(map[string]string{"event": "error", "code": "60009", "msg": "Login failed."})
+			return
+		}
+	}
+
+	c.mu.Lock()
+	c.loggedIn = true
+	c.mu.Unlock()
+	c.writeJSON(map[string]string{"event": "login", "code": "0", "msg": ""})
+}
+
+func (s *Server) wsSubscribe(c *wsConn, op string, arg wsArg) {
+	isPrivate := arg.Channel == "orders" || arg.Channel == "positions" ||
+		arg.Channel == "account" || arg.Channel == "balance_and_position"
+
+	c.mu.Lock()
+	loggedIn := c.loggedIn
+	c.mu.Unlock()
+	if isPrivate && (!c.private || !loggedIn) {
+		c.writeJSON(map[string]interface{}{"event": "error", "code": "60011", "msg": "Please log in"})
+		return
+	}
+
+	c.mu.Lock()
+	if op == "subscribe" {
+		c.subs[arg] = true
+	} else {
+		delete(c.subs, arg)
+	}
+	c.mu.Unlock()
+	c.writeJSON(map[string]interface{}{"event": op, "arg": arg})
+
+	if op != "subscribe" {
+		return
+	}
+
+	// 账户类频道订阅后推送一次快照
+	s.mu.Lock()
+	var snapshot interface{}
+	switch arg.Channel {
+	case "positions":
+		data := make([]map[string]string, 0)
+		for _, p := range s.positions {
+			if p.pos != 0 && (arg.InstId == "" || arg.InstId == p.instId) {
+				data = append(data, s.positionData(p))
+			}
+		}
+		snapshot = data
+	case "account":
+		snapshot = []map[string]interface{}{s.balanceData()}
+	case "balance_and_position":
+		snapshot = []map[string]interface{}{s.balanceAndPositionData("snapshot")}
+	case "books":
+		if inst, ok := s.instruments[arg.InstId]; ok {
+			snapshot = []map[string]interface{}{s.bookSnapshot(inst)}
+		}
+	}
+	s.mu.Unlock()
+
+	if snapshot != nil {
+		msg := map[string]interface{}{"arg": arg, "data": snapshot}
+		if arg.Channel == "books" {
+			msg["action"] = "snapshot"
+		}
+		c.writeJSON(msg)
+	}
+}
+
+// unlockAndFlush 释放锁后推送锁内产生的事件，避免持锁进行网络写入
+func (s *Server) unlockAndFlush() {
+	events := s.events
+	s.events = nil
+	s.mu.Unlock()
+
+	for i := range events {
+		s.broadcast(&events[i])
+	}
+}
+
+func (s *Server) broadcast(ev *wsEvent) {
+	s.connsMu.Lock()
+	conns := make([]*wsConn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.connsMu.Unlock()
+
+	for _, c := range conns {
+		if arg, ok := c.match(ev); ok {
+			msg := map[string]interface{}{"arg": arg, "data": ev.data}
+			if ev.action != "" {
+				msg["action"] = ev.action
+			}
+			c.writeJSON(msg)
+		}
+	}
+}
+
+// hasSubscriber 检查是否有连接订阅了指定频道
+func (s *Server) hasSubscriber(channel, instId string) bool {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+
+	for c := range s.conns {
+		if _, ok := c.match(&wsEvent{channel: channel, instId: instId}); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// pushMarket 推送行情和K线，调用方需持有锁
+func (s *Server) pushMarket(inst *instrument) {
+	ts := strconv.FormatInt(nowMillis(), 10)
+	s.events = append(s.events, wsEvent{
+		channel:  "tickers",
+		instId:   inst.instId,
+		instType: inst.instType,
+		data: []map[string]string{{
+			"instType": inst.instType,
+			"instId":   inst.instId,
+			"last":     formatFloat(inst.price),
+			"lastSz":   "0",
+			"askPx":    formatFloat(inst.price),
+			"bidPx":    formatFloat(inst.price),
+			"ts":       ts,
+		}},
+	})
+
+	s.pushBook(inst)
+
+	for bar, duration := range barDurations {
+		channel := "candle" + bar
+		if !s.hasSubscriber(channel, inst.instId) {
+			continue
+		}
+		candles := inst.aggregate(duration)
+		if len(candles) == 0 {
+			continue
+		}
+		s.events = append(s.events, wsEvent{
+			channel: channel,
+			instId:  inst.instId,
+			data:    [][]string{candles[0].row(false)},
+		})
+	}
+}
+
+// pushTrade 推送逐笔成交，调用方需持有锁
+func (s *Server) pushTrade(inst *instrument, side string, px, sz float64) {
+	s.events = append(s.events, wsEvent{
+		channel:  "trades",
+		instId:   inst.instId,
+		instType: inst.instType,
+		data: []map[string]string{{
+			"instId":  inst.instId,
+			"tradeId": strconv.FormatInt(nowMillis(), 10),
+			"px":      formatFloat(px),
+			"sz":      formatFloat(sz),
+			"side":    side,
+			"ts":      strconv.FormatInt(nowMillis(), 10),
+		}},
+	})
+}
+
+// pushOrder 推送订单状态，调用方需持有锁
+func (s *Server) pushOrder(o *order) {
+	data := s.orderData(o)
+	s.events = append(s.events, wsEvent{
+		channel:  "orders",
+		instId:   o.instId,
+		instType: data["instType"],
+		private:  true,
+		data:     []map[string]string{data},
+	})
+}
+
+// pushPositions 推送产品的全部持仓(含已平仓位)，调用方需持有锁
+func (s *Server) pushPositions(instId string) {
+	data := make([]map[string]string, 0)
+	instType := ""
+	for _, p := range s.positions {
+		if p.instId == instId {
+			d := s.positionData(p)
+			instType = d["instType"]
+			data = append(data, d)
+		}
+	}
+	if len(data) == 0 {
+		return
+	}
+	s.events = append(s.events, wsEvent{
+		channel:  "positions",
+		instId:   instId,
+		instType: instType,
+		private:  true,
+		data:     data,
+	})
+	s.events = append(s.events, wsEvent{
+		channel: "balance_and_position",
+		private: true,
+		data:    []map[string]interface{}{s.balanceAndPositionData("filled")},
+	})
+}
+
+// pushAccount 推送账户余额，调用方需持有锁
+func (s *Server) pushAccount() {
+	s.events = append(s.events, wsEvent{
+		channel: "account",
+		private: true,
+		data:    []map[string]interface{}{s.balanceData()},
+	})
+}
+
+func (s *Server) balanceAndPositionData(eventType string) map[string]interface{} {
+	balData := make([]map[string]string, 0, len(s.cash))
+	for ccy, cash := range s.cash {
+		balData = append(balData, map[string]string{"ccy": ccy, "cashBal": formatFloat(cash)})
+	}
+	posData := make([]map[string]string, 0, len(s.positions))
+	for _, p := range s.positions {
+		d := s.positionData(p)
+		posData = append(posData, map[string]string{
+			"instId":   d["instId"],
+			"instType": d["instType"],
+			"posSide":  d["posSide"],
+			"pos":      d["pos"],
+			"avgPx":    d["avgPx"],
+			"mgnMode":  d["mgnMode"],
+		})
+	}
+	return map[string]interface{}{
+		"pTime":     strconv.FormatInt(nowMillis(), 10),
+		"eventType": eventType,
+		"balData":   balData,
+		"posData":   posData,
+	}
+}
@@ -6,6 +6,9 @@ import (
 	"strconv"
 	"time"
 
+	"okxauto/internal/api"
+	"okxauto/internal/ledger"
+
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 )
@@ -44,8 +47,16 @@ func (s *Server) handleLogin(c *gin.Context) {
 	})
 }
 
-// 获取交易历史
+// 获取交易历史，指定account时只返回该账户的记录，否则返回所有账户
 func (s *Server) handleGetTradeHistory(c *gin.Context) {
+	if _, ok := s.selectEngines(c); !ok {
+		return
+	}
+	db := s.db
+	if account := c.Query("account"); account != "" {
+		db = db.ForAccount(account)
+	}
+
 	// 获取查询参数
 	limit := 100 // 默认返回最近100条记录
 	if limitStr := c.Query("limit"); limitStr != "" {
@@ -55,7 +66,7 @@ func (s *Server) handleGetTradeHistory(c *gin.Context) {
 	}
 
 	// 获取交易历史
-	trades, err := s.db.GetTradeHistory(limit)
+	trades, err := db.GetTradeHistory(limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取交易历史失败: %v", err),
@@ -68,54 +79,246 @@ func (s *Server) handleGetTradeHistory(c *gin.Context) {
 	})
 }
 
+// 获取历史K线，参数from/to为毫秒时间戳，缓存缺失的部分先从交易所补齐
+func (s *Server) handleGetCandles(c *gin.Context) {
+	symbol := c.Query("symbol")
+	bar := c.DefaultQuery("bar", "1m")
+	from, errFrom := strconv.ParseInt(c.Query("from"), 10, 64)
+	to, errTo := strconv.ParseInt(c.DefaultQuery("to", strconv.FormatInt(time.Now().UnixMilli(), 10)), 10, 64)
+	if symbol == "" || errFrom != nil || errTo != nil || from >= to {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "需要symbol以及毫秒时间戳from(<to)"})
+		return
+	}
+
+	engine, ok := s.selectEngine(c)
+	if !ok {
+		return
+	}
+	candles, err := engine.Candles().Load(c.Request.Context(), symbol, bar, time.UnixMilli(from), time.UnixMilli(to))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取K线失败: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"candles": candles,
+	})
+}
+
+// 对账，参数from/to为毫秒时间戳，默认最近7天；先同步账单再生成各账户的对账报告
+func (s *Server) handleReconcile(c *gin.Context) {
+	now := time.Now()
+	from, errFrom := strconv.ParseInt(c.DefaultQuery("from", strconv.FormatInt(now.AddDate(0, 0, -7).UnixMilli(), 10)), 10, 64)
+	to, errTo := strconv.ParseInt(c.DefaultQuery("to", strconv.FormatInt(now.UnixMilli(), 10)), 10, 64)
+	if errFrom != nil || errTo != nil || from >= to {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from/to需要为毫秒时间戳且from<to"})
+		return
+	}
+
+	engines, ok := s.selectEngines(c)
+	if !ok {
+		return
+	}
+
+	reports := make(map[string]*ledger.Report, len(engines))
+	for _, engine := range engines {
+		if _, err := engine.Ledger().Sync(c.Request.Context()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("账户 %s 同步账单失败: %v", engine.Account(), err)})
+			return
+		}
+		report, err := engine.Ledger().Reconcile(time.UnixMilli(from), time.UnixMilli(to))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("账户 %s 对账失败: %v", engine.Account(), err)})
+			return
+		}
+		reports[engine.Account()] = report
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reports": reports,
+	})
+}
+
+// accountStatus 单个账户的运行状态
+type accountStatus struct {
+	Name         string   `json:"name"`
+	Symbols      []string `json:"symbols"`
+	PositionMode string   `json:"position_mode"`
+}
+
 // 获取系统状态
 func (s *Server) handleGetSystemStatus(c *gin.Context) {
+	engines, ok := s.selectEngines(c)
+	if !ok {
+		return
+	}
+	accounts := make([]accountStatus, 0, len(engines))
+	for _, engine := range engines {
+		accounts = append(accounts, accountStatus{
+			Name:         engine.Account(),
+			Symbols:      engine.GetConfig().Symbols,
+			PositionMode: engine.PositionMode(),
+		})
+	}
+
 	status := struct {
-		Running    bool      `json:"running"`
-		StartTime  time.Time `json:"start_time"`
-		Strategies []string  `json:"strategies"`
+		Running    bool            `json:"running"`
+		StartTime  time.Time       `json:"start_time"`
+		Strategies []string        `json:"strategies"`
+		Accounts   []accountStatus `json:"accounts"`
 	}{
 		Running:    true,
 		StartTime:  time.Now(),
 		Strategies: []string{"Grid", "RSI"},
+		Accounts:   accounts,
 	}
 
 	c.JSON(http.StatusOK, status)
 }
 
-// 获取账户余额
+// 获取账户余额，返回各账户余额以及按币种汇总的合计
 func (s *Server) handleGetBalance(c *gin.Context) {
-	balances, err := s.engine.GetBalance()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	engines, ok := s.selectEngines(c)
+	if !ok {
 		return
 	}
 
+	accounts := make(map[string][]*api.Balance, len(engines))
+	totals := make(map[string]*api.Balance)
+	var currencies []string
+	for _, engine := range engines {
+		balances, err := engine.GetBalance(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("账户 %s: %v", engine.Account(), err)})
+			return
+		}
+		accounts[engine.Account()] = balances
+
+		for _, b := range balances {
+			total, ok := totals[b.Currency]
+			if !ok {
+				total = &api.Balance{Currency: b.Currency}
+				totals[b.Currency] = total
+				currencies = append(currencies, b.Currency)
+			}
+			total.Balance = addDecimal(total.Balance, b.Balance)
+			total.Available = addDecimal(total.Available, b.Available)
+			total.Frozen = addDecimal(total.Frozen, b.Frozen)
+		}
+	}
+
+	merged := make([]*api.Balance, 0, len(currencies))
+	for _, ccy := range currencies {
+		merged = append(merged, totals[ccy])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"balances": merged,
+		"accounts": accounts,
+	})
+}
+
+// 获取账户权益和保证金信息，多账户时附带权益和保证金合计
+func (s *Server) handleGetAccountSummary(c *gin.Context) {
+	engines, ok := s.selectEngines(c)
+	if !ok {
+		return
+	}
+
+	accounts := make(map[string]*api.AccountSummary, len(engines))
+	var totalEq, imr, mmr float64
+	for _, engine := range engines {
+		summary, err := engine.GetAccountSummary(c.Request.Context())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("账户 %s: %v", engine.Account(), err)})
+			return
+		}
+		accounts[engine.Account()] = summary
+		totalEq += summary.TotalEq
+		imr += summary.Imr
+		mmr += summary.Mmr
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accounts": accounts,
+		"totalEq":  totalEq,
+		"imr":      imr,
+		"mmr":      mmr,
+	})
+}
+
+// addDecimal 累加字符串形式的金额
+func addDecimal(a, b string) string {
+	x, _ := strconv.ParseFloat(a, 64)
+	y, _ := strconv.ParseFloat(b, 64)
+	return strconv.FormatFloat(x+y, 'f', -1, 64)
+}
+
+// 获取接口限速等待统计
+func (s *Server) handleGetRateLimit(c *gin.Context) {
+	engine, ok := s.selectEngine(c)
+	if !ok {
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"balances": balances,
+		"account": engine.Account(),
+		"groups":  engine.RateLimitStats(),
 	})
 }
 
-// 启用策略
+// 获取本地时钟与交易所服务器的偏差
+func (s *Server) handleGetClock(c *gin.Context) {
+	engine, ok := s.selectEngine(c)
+	if !ok {
+		return
+	}
+	status, ok := engine.ClockStatus()
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "交易所客户端不支持校时"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"account":   engine.Account(),
+		"offset_ms": status.Offset.Milliseconds(),
+		"rtt_ms":    status.RTT.Milliseconds(),
+		"last_sync": status.LastSync,
+		"samples":   status.Samples,
+		"error":     status.LastErr,
+	})
+}
+
+// 启用策略，未指定account时对所有账户生效
 func (s *Server) handleEnableStrategy(c *gin.Context) {
 	strategyName := c.Param("name")
-	err := s.engine.EnableStrategy(strategyName)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	engines, ok := s.selectEngines(c)
+	if !ok {
 		return
 	}
+	for _, engine := range engines {
+		if err := engine.EnableStrategy(c.Request.Context(), strategyName); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("账户 %s: %v", engine.Account(), err)})
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "策略已启用"})
 }
 
-// 禁用策略
+// 禁用策略，未指定account时对所有账户生效
 func (s *Server) handleDisableStrategy(c *gin.Context) {
 	strategyName := c.Param("name")
-	err := s.engine.DisableStrategy(strategyName)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	engines, ok := s.selectEngines(c)
+	if !ok {
 		return
 	}
+	for _, engine := range engines {
+		if err := engine.DisableStrategy(strategyName); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("账户 %s: %v", engine.Account(), err)})
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "策略已禁用"})
 }
@@ -130,11 +333,16 @@ func (s *Server) handleUpdateStrategyConfig(c *gin.Context) {
 		return
 	}
 
-	err := s.engine.UpdateStrategyConfig(strategyName, config)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	engines, ok := s.selectEngines(c)
+	if !ok {
 		return
 	}
+	for _, engine := range engines {
+		if err := engine.UpdateStrategyConfig(strategyName, config); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "配置已更新"})
 }
@@ -149,15 +357,24 @@ func (s *Server) handleGetActiveTrades(c *gin.Context) {
 
 // 获取策略列表
 func (s *Server) handleGetStrategies(c *gin.Context) {
-	// 从交易引擎配置中获取策略状态
-	engineConfig := s.engine.GetConfig()
-	
-	strategies := []struct {
+	engines, ok := s.selectEngines(c)
+	if !ok {
+		return
+	}
+
+	type strategyStatus struct {
+		Account string `json:"account"`
 		Name    string `json:"name"`
 		Enabled bool   `json:"enabled"`
-	}{
-		{Name: "Grid", Enabled: engineConfig.Grid.Enabled},
-		{Name: "RSI", Enabled: engineConfig.RSI.Enabled},
+	}
+	var strategies []strategyStatus
+	for _, engine := range engines {
+		// 从交易引擎配置中获取策略状态
+		engineConfig := engine.GetConfig()
+		strategies = append(strategies,
+			strategyStatus{Account: engine.Account(), Name: "Grid", Enabled: engineConfig.Grid.Enabled},
+			strategyStatus{Account: engine.Account(), Name: "RSI", Enabled: engineConfig.RSI.Enabled},
+		)
 	}
 
 	c.JSON(http.StatusOK, strategies)
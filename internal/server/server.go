@@ -3,7 +3,6 @@ package server
 import (
 	"context"
 	"net/http"
-	"time"
 
 	"okxauto/internal/database"
 	"okxauto/internal/trading"
@@ -12,11 +11,11 @@ import (
 )
 
 type Server struct {
-	config *Config
-	engine *trading.Engine
-	db     *database.Database
-	router *gin.Engine
-	server *http.Server
+	config  *Config
+	engines []*trading.Engine // 每个账户一个交易引擎，第一个为默认账户
+	db      *database.Database
+	router  *gin.Engine
+	server  *http.Server
 }
 
 // Config 定义服务器配置
@@ -33,15 +32,19 @@ type Config struct {
 	} `yaml:"rsi_strategy"`
 }
 
-func New(config *Config, db *database.Database, engine *trading.Engine) *Server {
+func New(config *Config, db *database.Database, engines []*trading.Engine) *Server {
 	s := &Server{
-		config: config,
-		engine: engine,
-		db:     db,
-		router: gin.Default(),
+		config:  config,
+		engines: engines,
+		db:      db,
+		router:  gin.Default(),
 	}
 
 	s.setupRoutes()
+	s.server = &http.Server{
+		Addr:    ":" + s.config.Port,
+		Handler: s.router,
+	}
 	return s
 }
 
@@ -63,6 +66,12 @@ func (s *Server) setupRoutes() {
 			trades.GET("/active", s.handleGetActiveTrades)
 		}
 
+		// 行情相关
+		market := api.Group("/market")
+		{
+			market.GET("/candles", s.handleGetCandles)
+		}
+
 		// 策略相关
 		strategies := api.Group("/strategies")
 		{
@@ -77,22 +86,48 @@ func (s *Server) setupRoutes() {
 		{
 			system.GET("/status", s.handleGetSystemStatus)
 			system.GET("/balance", s.handleGetBalance)
+			system.GET("/account", s.handleGetAccountSummary)
+			system.GET("/ratelimit", s.handleGetRateLimit)
+			system.GET("/clock", s.handleGetClock)
+			system.GET("/reconcile", s.handleReconcile)
 		}
 	}
 }
 
-func (s *Server) Start() error {
-	s.server = &http.Server{
-		Addr:    ":" + s.config.Port,
-		Handler: s.router,
+// selectEngines 按查询参数account选择交易引擎，未指定时返回所有账户；账户不存在时返回404
+func (s *Server) selectEngines(c *gin.Context) ([]*trading.Engine, bool) {
+	account := c.Query("account")
+	if account == "" {
+		return s.engines, true
+	}
+	for _, engine := range s.engines {
+		if engine.Account() == account {
+			return []*trading.Engine{engine}, true
+		}
 	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "账户不存在: " + account})
+	return nil, false
+}
 
-	return s.server.ListenAndServe()
+// selectEngine 按查询参数account选择单个交易引擎，未指定时使用默认账户
+func (s *Server) selectEngine(c *gin.Context) (*trading.Engine, bool) {
+	engines, ok := s.selectEngines(c)
+	if !ok {
+		return nil, false
+	}
+	if len(engines) == 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "没有运行中的账户"})
+		return nil, false
+	}
+	return engines[0], true
 }
 
-func (s *Server) Shutdown() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// Start 监听并处理请求，直到Shutdown被调用；正常关闭时返回http.ErrServerClosed
+func (s *Server) Start() error {
+	return s.server.ListenAndServe()
+}
 
+// Shutdown 停止接受新连接并等待进行中的请求完成，ctx结束时强制关闭
+func (s *Server) Shutdown(ctx context.Context) error {
 	return s.server.Shutdown(ctx)
 }
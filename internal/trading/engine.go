@@ -1,6 +1,7 @@
 package trading
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -11,31 +12,62 @@ import (
 	"time"
 
 	"okxauto/internal/api"
+	"okxauto/internal/candles"
 	"okxauto/internal/database"
 	dbmodels "okxauto/internal/database/models"
+	"okxauto/internal/ledger"
 	"okxauto/internal/models"
 	"okxauto/internal/trading/strategies"
 	"okxauto/internal/types"
 )
 
+// WebSocket行情超过该时间未更新时回退到REST轮询
+const wsStaleAfter = 5 * time.Second
+
+// 策略采样行情的最小间隔，与REST轮询间隔一致
+const strategySampleInterval = time.Second
+
 type Engine struct {
-	api        *api.OKXClient
+	api        api.Exchange
+	feed       *api.MarketFeed
+	account    *api.AccountFeed
+	orders     *orderTracker
+	futures    *futuresCache
+	fees       *feeCache
+	ledger     *ledger.Service
+	candles    *candles.Service
 	db         *database.Database
 	config     *Config
 	strategies []types.Strategy
 	signals    chan *types.Signal
-	stopChan   chan struct{}
 	wg         sync.WaitGroup
+
+	// fundsMu 串行化资金账户与交易账户之间的划转
+	fundsMu sync.Mutex
+
+	// posMode 账户持仓模式，Start时从交易所读取
+	posMode string
+
+	// ctx 引擎根ctx，Stop时取消，所有协程和交易所请求都从它派生
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
-func NewEngine(apiClient *api.OKXClient, db *database.Database, config Config) (*Engine, error) {
+func NewEngine(apiClient api.Exchange, db *database.Database, config Config) (*Engine, error) {
 	engine := &Engine{
-		api:      apiClient,
-		db:       db,
-		config:   &config,
-		signals:  make(chan *types.Signal, 100),
-		stopChan: make(chan struct{}),
+		api:     apiClient,
+		orders:  newOrderTracker(apiClient, db),
+		futures: newFuturesCache(apiClient),
+		fees:    newFeeCache(apiClient),
+		ledger:  ledger.NewService(apiClient, db),
+		candles: candles.NewService(apiClient, db),
+		db:      db,
+		config:  &config,
+		signals: make(chan *types.Signal, 100),
+		posMode: api.PosModeLongShort,
 	}
+	engine.ctx, engine.cancel = context.WithCancel(context.Background())
+	engine.orders.onFilled = engine.attachTPSL
 
 	// 根据交易类型选择合适的交易对
 	var symbols []string
@@ -77,6 +109,7 @@ func NewEngine(apiClient *api.OKXClient, db *database.Database, config Config) (
 				OverboughtThreshold: config.RSI.OverboughtThreshold,
 				OversoldThreshold:   config.RSI.OversoldThreshold,
 			})
+			strategy.SetCandleSource(engine.candles)
 			engine.strategies = append(engine.strategies, strategy)
 		}
 	}
@@ -84,14 +117,126 @@ func NewEngine(apiClient *api.OKXClient, db *database.Database, config Config) (
 	return engine, nil
 }
 
+// SetMarketFeed 设置WebSocket行情推送，需在Start之前调用；未设置时使用REST轮询
+func (e *Engine) SetMarketFeed(feed *api.MarketFeed) {
+	e.feed = feed
+}
+
+// SetAccountFeed 设置私有频道推送，持仓和余额优先读取推送缓存，缓存不可用时回退到REST
+func (e *Engine) SetAccountFeed(account *api.AccountFeed) {
+	e.account = account
+}
+
+// isSpot 按产品信息的instType判断是否为现货
+func (e *Engine) isSpot(ctx context.Context, symbol string) (bool, error) {
+	inst, err := e.api.GetInstrument(ctx, symbol)
+	if err != nil {
+		return false, fmt.Errorf("获取产品信息失败: %v", err)
+	}
+	return inst.IsSpot(), nil
+}
+
+// getPositions 获取持仓，优先使用私有频道缓存；现货持仓由程序自己的成交生成
+func (e *Engine) getPositions(ctx context.Context, symbol string) ([]*models.Position, error) {
+	spot, err := e.isSpot(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	if spot {
+		return e.spotPositions(ctx, symbol)
+	}
+	if e.account != nil {
+		if positions, ok := e.account.Positions(symbol); ok {
+			return positions, nil
+		}
+	}
+	return e.api.GetPositions(ctx, symbol)
+}
+
+// getBalances 获取余额，优先使用私有频道缓存
+func (e *Engine) getBalances(ctx context.Context) ([]*api.Balance, error) {
+	if e.account != nil {
+		if balances, ok := e.account.Balances(); ok {
+			return balances, nil
+		}
+	}
+	return e.api.GetBalances(ctx)
+}
+
+// getAccountSummary 获取账户权益和保证金信息，优先使用私有频道缓存
+func (e *Engine) getAccountSummary(ctx context.Context) (*api.AccountSummary, error) {
+	if e.account != nil {
+		if summary, ok := e.account.Summary(); ok {
+			return summary, nil
+		}
+	}
+	return e.api.GetAccountSummary(ctx)
+}
+
 func (e *Engine) Start() error {
+	ctx := e.ctx
+
+	log.Printf("启动账户 %s 的交易引擎，交易对: %v", e.config.Account, e.config.Symbols)
+	e.initPositionMode(ctx)
+
 	// 启动策略
 	for _, strategy := range e.strategies {
-		if err := strategy.Initialize(); err != nil {
+		if err := strategy.Initialize(ctx); err != nil {
 			return err
 		}
 	}
 
+	// 订阅WebSocket行情
+	if e.feed != nil {
+		for _, symbol := range e.config.Symbols {
+			if err := e.feed.SubscribeTickers(symbol); err != nil {
+				log.Printf("[%s] 订阅行情失败: %v", symbol, err)
+			}
+			if err := e.feed.SubscribeBooks(symbol); err != nil {
+				log.Printf("[%s] 订阅深度失败: %v", symbol, err)
+			}
+		}
+	}
+
+	// 启动订单跟踪，优先使用私有频道推送
+	if err := e.orders.restore(); err != nil {
+		log.Printf("恢复未完成订单失败: %v", err)
+	}
+	var orderUpdates <-chan *api.Order
+	if e.account != nil {
+		orderUpdates = e.account.Orders()
+	}
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.orders.run(ctx, orderUpdates)
+	}()
+
+	// 定期刷新永续合约的资金费率、标记价格和持仓总量，随行情推送给策略
+	if swaps := swapSymbols(e.config.Symbols); len(swaps) > 0 {
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			e.futures.run(ctx, swaps)
+		}()
+	}
+
+	// 定期同步账单流水，用于与本地交易记录对账
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		e.ledger.Run(ctx, e.config.BillsSyncInterval)
+	}()
+
+	// 交易账户余额超过上限时归集到资金账户，低于下限时从资金账户补充
+	if e.config.FundTransfer.Enabled {
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			e.runFundTransfer(ctx)
+		}()
+	}
+
 	// 启动信号处理
 	e.wg.Add(1)
 	go e.processSignals()
@@ -103,18 +248,20 @@ func (e *Engine) Start() error {
 	}
 
 	// 启动保证金检查定时器
+	e.wg.Add(1)
 	go func() {
+		defer e.wg.Done()
 		//ticker := time.NewTicker(1 * time.Minute)
 		ticker := time.NewTicker(5 * time.Second)
 		defer ticker.Stop()
 
 		for {
 			select {
-			case <-e.stopChan:
+			case <-ctx.Done():
 				return
 			case <-ticker.C:
 				for _, symbol := range e.config.Symbols {
-					if err := e.checkAndAdjustMargin(symbol); err != nil {
+					if err := e.checkAndAdjustMargin(ctx, symbol); err != nil {
 						log.Printf("检查保证金失败: %v", err)
 					}
 				}
@@ -125,8 +272,9 @@ func (e *Engine) Start() error {
 	return nil
 }
 
+// Stop 取消根ctx，进行中的请求、限速等待和重试退避立即返回，然后等待协程退出
 func (e *Engine) Stop() {
-	close(e.stopChan)
+	e.cancel()
 	e.wg.Wait()
 
 	for _, strategy := range e.strategies {
@@ -136,27 +284,64 @@ func (e *Engine) Stop() {
 
 func (e *Engine) processSignals() {
 	defer e.wg.Done()
+	ctx := e.ctx
 
 	for {
 		select {
-		case <-e.stopChan:
+		case <-ctx.Done():
 			return
 		case signal := <-e.signals:
-			if err := e.executeSignal(signal); err != nil {
+			if err := e.executeSignal(ctx, signal); err != nil {
 				log.Printf("执行信号失败: %v", err)
 			}
 		}
 	}
 }
 
-func (e *Engine) executeSignal(signal *types.Signal) error {
+func (e *Engine) executeSignal(ctx context.Context, signal *types.Signal) error {
 	log.Printf("[%s] 开始执行交易信号: %s %.2f@%.2f",
 		signal.Symbol, signal.Action, signal.Amount, signal.Price)
 
+	// 根据产品信息计算下单数量
+	inst, err := e.api.GetInstrument(ctx, signal.Symbol)
+	if err != nil {
+		return fmt.Errorf("获取产品信息失败: %v", err)
+	}
+	if !inst.IsDerivative() {
+		return e.executeSpotSignal(ctx, signal, inst)
+	}
+	sz := inst.SizeFor(signal.Amount, signal.Price)
+	if err := inst.ValidateSize(sz); err != nil {
+		log.Printf("[%s] 信号数量 %.4f 换算后无法下单: %v", signal.Symbol, signal.Amount, err)
+		return err
+	}
+	price := inst.RoundPrice(signal.Price)
+	log.Printf("[%s] 下单数量换算: %.4f -> %s (面值=%g, 最小数量=%g, 数量精度=%g)",
+		signal.Symbol, signal.Amount, inst.FormatSize(sz), inst.CtVal, inst.MinSz, inst.LotSz)
+
+	sz, err = e.fitMarketSize(inst, sz, price)
+	if err != nil {
+		log.Printf("[%s] 下单失败: %v", signal.Symbol, err)
+		return err
+	}
+
+	if err := e.checkSlippage(signal.Symbol, api.OrderSide(signal.Action), sz); err != nil {
+		log.Printf("[%s] 下单失败: %v", signal.Symbol, err)
+		return err
+	}
+
+	// 按交易所实际可下单数量缩减或拒绝
+	sz, err = e.fitMaxSize(ctx, inst, api.OrderSide(signal.Action), sz)
+	if err != nil {
+		log.Printf("[%s] 下单失败: %v", signal.Symbol, err)
+		return err
+	}
+	notional := inst.Notional(sz, price)
+
 	// 检查账户资金
 	if e.config.TradeType == "futures" {
 		// 获取持仓信息
-		positions, err := e.api.GetPositions(signal.Symbol)
+		positions, err := e.getPositions(ctx, signal.Symbol)
 		if err != nil {
 			log.Printf("[%s] 获取持仓信息失败: %v", signal.Symbol, err)
 		} else {
@@ -166,40 +351,33 @@ func (e *Engine) executeSignal(signal *types.Signal) error {
 			}
 		}
 
-		balances, err := e.api.GetBalances()
+		summary, err := e.getAccountSummary(ctx)
 		if err != nil {
 			return fmt.Errorf("获取余额失败: %v", err)
 		}
 
-		// 打印所有余额信息
-		log.Printf("[%s] 当前账户余额:", signal.Symbol)
-		for _, balance := range balances {
-			log.Printf("  币种: %s, 总额: %s, 可用: %s, 冻结: %s",
-				balance.Currency, balance.Balance, balance.Available, balance.Frozen)
+		// 打印账户权益和各币种余额
+		log.Printf("[%s] 当前账户权益: %.2f USD, 占用保证金: %.2f, 维持保证金: %.2f, 保证金率: %.2f",
+			signal.Symbol, summary.TotalEq, summary.Imr, summary.Mmr, summary.MgnRatio)
+		for _, detail := range summary.Details {
+			log.Printf("  币种: %s, 权益: %g, 可用: %g, 冻结: %g",
+				detail.Ccy, detail.Eq, detail.AvailEq, detail.FrozenBal)
 		}
 
-		// 检查USDT余额
-		hasUSDT := false
-		for _, balance := range balances {
-			if balance.Currency == "USDT" {
-				available, _ := strconv.ParseFloat(balance.Available, 64)
-				required := signal.Amount * signal.Price / float64(e.config.Leverage)
-				if available < required {
-					log.Printf("[%s] USDT余额不足，无法开仓: 需要 %.2f USDT (考虑%d倍杠杆), 可用 %.2f USDT",
-						signal.Symbol, required, e.config.Leverage, available)
-					return fmt.Errorf("USDT余额不足")
-				}
-				hasUSDT = true
-				log.Printf("[%s] USDT余额充足，可以开仓: 需要 %.2f USDT, 可用 %.2f USDT",
-					signal.Symbol, required, available)
-				break
-			}
+		// 检查该方向可用保证金
+		available, err := e.availableMargin(ctx, inst, api.OrderSide(signal.Action), summary)
+		if err != nil {
+			log.Printf("[%s] %v，无法开仓", signal.Symbol, err)
+			return err
 		}
-
-		if !hasUSDT {
-			log.Printf("[%s] 未找到USDT余额，无法开仓", signal.Symbol)
-			return fmt.Errorf("未找到USDT余额")
+		required := notional / float64(e.config.Leverage)
+		if available < required {
+			log.Printf("[%s] USDT余额不足，无法开仓: 需要 %.2f USDT (考虑%d倍杠杆), 可用 %.2f USDT",
+				signal.Symbol, required, e.config.Leverage, available)
+			return fmt.Errorf("USDT余额不足")
 		}
+		log.Printf("[%s] USDT余额充足，可以开仓: 需要 %.2f USDT, 可用 %.2f USDT",
+			signal.Symbol, required, available)
 	}
 
 	// 设置杠杆倍数
@@ -208,12 +386,10 @@ func (e *Engine) executeSignal(signal *types.Signal) error {
 		posSide = "short"
 	}
 
-	// 在这里声明err变量
-	var err error
-	err = e.api.SetLeverage(signal.Symbol,
+	err = e.api.SetLeverage(ctx, signal.Symbol,
 		fmt.Sprintf("%d", e.config.Leverage),
 		e.config.MarginMode,
-		posSide)
+		e.orderPosSide(posSide))
 
 	if err != nil {
 		log.Printf("[%s] 设置杠杆倍数失败: %v", signal.Symbol, err)
@@ -222,10 +398,10 @@ func (e *Engine) executeSignal(signal *types.Signal) error {
 	log.Printf("[%s] 设置杠杆倍数成功: %d", signal.Symbol, e.config.Leverage)
 
 	// 计算所需保证金
-	margin := signal.Price * signal.Amount / float64(e.config.Leverage)
+	margin := notional / float64(e.config.Leverage)
 
 	// 检查余额是否充足
-	if err := e.checkBalance(margin); err != nil {
+	if err := e.checkBalance(ctx, margin); err != nil {
 		log.Printf("[%s] 下单失败: %v", signal.Symbol, err)
 		return err
 	}
@@ -236,47 +412,50 @@ func (e *Engine) executeSignal(signal *types.Signal) error {
 		TdMode:  e.config.MarginMode,
 		Side:    api.OrderSide(signal.Action),
 		OrdType: api.Market,
-		Sz:      "200", // 固定为1张合约
+		Sz:      inst.FormatSize(sz),
 	}
 
 	// 设置合约特有参数
 	if e.config.TradeType == "futures" {
-		orderReq.PosSide = posSide
+		orderReq.PosSide = e.orderPosSide(posSide)
 		orderReq.Lever = fmt.Sprintf("%d", e.config.Leverage)
-		log.Printf("[%s] 合约交易模式: 杠杆=%d, 保证金模式=%s, 持仓方向=%s, 数量=%s张",
-			signal.Symbol, e.config.Leverage, e.config.MarginMode, orderReq.PosSide, orderReq.Sz)
+		log.Printf("[%s] 合约交易模式: 杠杆=%d, 保证金模式=%s, 持仓模式=%s, 持仓方向=%s, 数量=%s张",
+			signal.Symbol, e.config.Leverage, e.config.MarginMode, e.posMode, posSide, orderReq.Sz)
 	}
 
+	return e.submitOrder(ctx, signal, orderReq, price)
+}
+
+// submitOrder 发送下单请求，成功后保存交易记录并跟踪订单状态
+func (e *Engine) submitOrder(ctx context.Context, signal *types.Signal, orderReq *api.PlaceOrderRequest, price float64) error {
 	// 打印完整的订单请求
 	reqJSON, _ := json.MarshalIndent(orderReq, "", "  ")
 	log.Printf("[%s] 发送下单请求: %s", signal.Symbol, string(reqJSON))
 
-	// 执行订单
-	var resp *api.OrderResponse
-	err = api.RetryOperation(func() error {
-		var placeErr error
-		resp, placeErr = e.api.PlaceOrder(orderReq)
-		return placeErr
-	}, api.RetryConfig{
-		MaxRetries:  3,
-		DelayMillis: 1000,
-	})
-
+	// 执行订单，网络错误和限频由客户端按错误分类重试
+	resp, err := e.api.PlaceOrder(ctx, orderReq)
 	if err != nil {
-		log.Printf("[%s] 下单失败: %v", signal.Symbol, err)
+		switch {
+		case api.IsInsufficient(err):
+			log.Printf("[%s] 下单失败，余额或保证金不足: %v", signal.Symbol, err)
+		case api.IsInstrumentUnavailable(err):
+			log.Printf("[%s] 下单失败，产品当前不可交易: %v", signal.Symbol, err)
+		default:
+			log.Printf("[%s] 下单失败: %v", signal.Symbol, err)
+		}
 		return err
 	}
 
 	log.Printf("[%s] 下单成功 - OrderID: %s", signal.Symbol, resp.OrderId)
 
-	// 保存交易记录
+	// 保存交易记录，成交价格、数量和手续费由订单跟踪更新
 	trade := &dbmodels.Trade{
 		Symbol:    signal.Symbol,
 		Side:      signal.Action,
-		Price:     signal.Price,
+		Price:     price,
 		Amount:    signal.Amount,
 		Strategy:  signal.Strategy,
-		Status:    "filled",
+		Status:    "live",
 		OrderID:   resp.OrderId,
 		TradeType: e.config.TradeType,
 		CreatedAt: time.Now(),
@@ -285,16 +464,18 @@ func (e *Engine) executeSignal(signal *types.Signal) error {
 	if err := e.db.SaveTrade(trade); err != nil {
 		log.Printf("[%s] 保存交易记录失败: %v", signal.Symbol, err)
 	}
+	e.orders.track(signal.Symbol, resp.OrderId, signal.Strategy)
 
-	log.Printf("[%s] 交易完成: %s %.2f@%.2f",
+	log.Printf("[%s] 订单已提交，等待成交确认: %s %.2f@%.2f",
 		signal.Symbol, signal.Action, signal.Amount, signal.Price)
 	return nil
 }
 
 func (e *Engine) updateMarketData(symbol string) {
 	defer e.wg.Done()
+	ctx := e.ctx
 
-	// 市场数据更新间隔
+	// 市场数据更新间隔(WebSocket不可用时的REST轮询)
 	marketTicker := time.NewTicker(1 * time.Second)
 	// 止盈止损检查间隔
 	pnlTicker := time.NewTicker(1 * time.Second)
@@ -302,19 +483,41 @@ func (e *Engine) updateMarketData(symbol string) {
 	defer marketTicker.Stop()
 	defer pnlTicker.Stop()
 
-	log.Printf("[%s] 开始监控交易对，检查间隔: 5秒", symbol)
+	// 私有频道推送持仓变动时立即检查止盈止损
+	var positionUpdates <-chan *models.Position
+	if e.account != nil {
+		positionUpdates = e.account.PositionUpdates(symbol)
+	}
+
+	var ticks <-chan *types.Tick
+	if e.feed != nil {
+		ticks = e.feed.Ticks(symbol)
+		defer e.feed.StopTicks(symbol, ticks)
+		log.Printf("[%s] 开始监控交易对，使用WebSocket行情推送", symbol)
+	} else {
+		log.Printf("[%s] 开始监控交易对，检查间隔: 1秒", symbol)
+	}
 
 	// 记录上次开仓状态，避免重复开仓
-	var lastLongEntry, lastShortEntry bool
+	state := &entryState{}
+	var lastPush time.Time
 
 	for {
 		select {
-		case <-e.stopChan:
+		case <-ctx.Done():
 			log.Printf("[%s] 停止监控交易对", symbol)
 			return
+		case tick := <-ticks:
+			lastPush = time.Now()
+			e.handleTick(tick, state)
 		case <-marketTicker.C:
+			// WebSocket行情正常时不再轮询
+			if ticks != nil && time.Since(lastPush) < wsStaleAfter {
+				continue
+			}
+
 			// 获取最新价格
-			candles, err := e.api.GetKlines(symbol, "1m", 1)
+			candles, err := e.api.GetKlines(ctx, symbol, "1m", 1)
 			if err != nil {
 				log.Printf("[%s] 获取K线数据失败: %v", symbol, err)
 				continue
@@ -326,82 +529,113 @@ func (e *Engine) updateMarketData(symbol string) {
 			}
 
 			price, _ := strconv.ParseFloat(candles[0].Close, 64)
-
-			// 检查做多条件
-			if e.config.LongPosition.Enabled && !lastLongEntry {
-				if price >= e.config.LongPosition.EntryRange.Min &&
-					price <= e.config.LongPosition.EntryRange.Max {
-					// 触发做多信号
-					signal := &types.Signal{
-						Symbol:    symbol,
-						Strategy:  "LongPosition",
-						Action:    "buy",
-						Price:     price,
-						Amount:    float64(e.config.LongPosition.PositionSize),
-						Timestamp: time.Now().Unix(),
-					}
-					log.Printf("[%s] 价格 %.4f 在做多区间内，触发做多信号", symbol, price)
-					e.signals <- signal
-					lastLongEntry = true
-				}
+			e.handleTick(&types.Tick{
+				Symbol:    symbol,
+				Price:     price,
+				Timestamp: time.Now().Unix(),
+				Source:    "rest",
+			}, state)
+		case <-positionUpdates:
+			if err := e.checkPositionPnL(ctx, symbol); err != nil {
+				log.Printf("[%s] 检查止盈止损失败: %v", symbol, err)
 			}
-
-			// 检查做空条件
-			if e.config.ShortPosition.Enabled && !lastShortEntry {
-				if price >= e.config.ShortPosition.EntryRange.Min &&
-					price <= e.config.ShortPosition.EntryRange.Max {
-					// 触发做空信号
-					signal := &types.Signal{
-						Symbol:    symbol,
-						Strategy:  "ShortPosition",
-						Action:    "sell",
-						Price:     price,
-						Amount:    float64(e.config.ShortPosition.PositionSize),
-						Timestamp: time.Now().Unix(),
-					}
-					log.Printf("[%s] 价格 %.4f 在做空区间内，触发做空信号", symbol, price)
-					e.signals <- signal
-					lastShortEntry = true
-				}
+		case <-pnlTicker.C:
+			// 检查止盈止损
+			if err := e.checkPositionPnL(ctx, symbol); err != nil {
+				log.Printf("[%s] 检查止盈止损失败: %v", symbol, err)
 			}
+		}
+	}
+}
 
-			// 重置开仓状态的条件
-			if price < e.config.LongPosition.EntryRange.Min ||
-				price > e.config.LongPosition.EntryRange.Max {
-				lastLongEntry = false
-			}
-			if price < e.config.ShortPosition.EntryRange.Min ||
-				price > e.config.ShortPosition.EntryRange.Max {
-				lastShortEntry = false
-			}
+// entryState 记录交易对的开仓触发状态
+type entryState struct {
+	lastLongEntry  bool
+	lastShortEntry bool
+	lastStrategy   time.Time // 上次向策略提供行情的时间
+}
 
-			// 继续执行现有的策略处理...
-			for _, strategy := range e.strategies {
-				signal, err := strategy.ProcessTick(&types.Tick{
-					Symbol:    symbol,
-					Price:     price,
-					Timestamp: time.Now().Unix(),
-				})
-				if err != nil {
-					log.Printf("[%s-%s] 策略处理失败: %v", symbol, strategy.Name(), err)
-					continue
-				}
-				if signal != nil {
-					e.signals <- signal
-				}
+// handleTick 根据最新价格检查开仓区间并驱动策略
+func (e *Engine) handleTick(tick *types.Tick, state *entryState) {
+	symbol := tick.Symbol
+	price := tick.Price
+	if tick.Futures == nil {
+		// 推送的行情可能被多个订阅者共享，附带合约行情前先复制
+		withFutures := *tick
+		withFutures.Futures = e.futures.get(symbol)
+		tick = &withFutures
+	}
+
+	// 检查做多条件
+	if e.config.LongPosition.Enabled && !state.lastLongEntry {
+		if price >= e.config.LongPosition.EntryRange.Min &&
+			price <= e.config.LongPosition.EntryRange.Max {
+			// 触发做多信号
+			signal := &types.Signal{
+				Symbol:    symbol,
+				Strategy:  "LongPosition",
+				Action:    "buy",
+				Price:     price,
+				Amount:    e.config.LongPosition.PositionSize,
+				Timestamp: time.Now().Unix(),
 			}
-		case <-pnlTicker.C:
-			// 检查止盈止损
-			if err := e.checkPositionPnL(symbol); err != nil {
-				log.Printf("[%s] 检查止盈止损失败: %v", symbol, err)
+			log.Printf("[%s] 价格 %.4f 在做多区间内，触发做多信号", symbol, price)
+			e.signals <- signal
+			state.lastLongEntry = true
+		}
+	}
+
+	// 检查做空条件
+	if e.config.ShortPosition.Enabled && !state.lastShortEntry {
+		if price >= e.config.ShortPosition.EntryRange.Min &&
+			price <= e.config.ShortPosition.EntryRange.Max {
+			// 触发做空信号
+			signal := &types.Signal{
+				Symbol:    symbol,
+				Strategy:  "ShortPosition",
+				Action:    "sell",
+				Price:     price,
+				Amount:    e.config.ShortPosition.PositionSize,
+				Timestamp: time.Now().Unix(),
 			}
+			log.Printf("[%s] 价格 %.4f 在做空区间内，触发做空信号", symbol, price)
+			e.signals <- signal
+			state.lastShortEntry = true
+		}
+	}
+
+	// 重置开仓状态的条件
+	if price < e.config.LongPosition.EntryRange.Min ||
+		price > e.config.LongPosition.EntryRange.Max {
+		state.lastLongEntry = false
+	}
+	if price < e.config.ShortPosition.EntryRange.Min ||
+		price > e.config.ShortPosition.EntryRange.Max {
+		state.lastShortEntry = false
+	}
+
+	// 策略按固定间隔采样行情，指标周期不随推送频率变化
+	if time.Since(state.lastStrategy) < strategySampleInterval {
+		return
+	}
+	state.lastStrategy = time.Now()
+
+	// 继续执行现有的策略处理...
+	for _, strategy := range e.strategies {
+		signal, err := strategy.ProcessTick(tick)
+		if err != nil {
+			log.Printf("[%s-%s] 策略处理失败: %v", symbol, strategy.Name(), err)
+			continue
+		}
+		if signal != nil {
+			e.signals <- signal
 		}
 	}
 }
 
 // 修改 checkPositionPnL 方法使用 api.Position
-func (e *Engine) checkPositionPnL(symbol string) error {
-	positions, err := e.api.GetPositions(symbol)
+func (e *Engine) checkPositionPnL(ctx context.Context, symbol string) error {
+	positions, err := e.getPositions(ctx, symbol)
 	if err != nil {
 		log.Printf("[%s] 获取持仓信息失败: %v", symbol, err)
 		return fmt.Errorf("获取持仓信息失败: %v", err)
@@ -413,35 +647,37 @@ func (e *Engine) checkPositionPnL(symbol string) error {
 	}
 
 	for _, pos := range positions {
+		direction := positionDirection(pos)
 		log.Printf("[%s] 检查持仓: 方向=%s, 数量=%.4f, 收益率=%.2f%%",
-			symbol, pos.PosSide, pos.Position, pos.PnLRatio*100)
+			symbol, direction, pos.Position, pos.PnLRatio*100)
+		pnlRatio := e.pnlRatio(ctx, symbol, pos, direction)
 
 		// 检查多头持仓
-		if pos.PosSide == "long" && pos.Position > 0 {
+		if direction == "long" && pos.Position != 0 {
 			log.Printf("[%s] 多头持仓 - 止盈点=%.2f%%, 止损点=%.2f%%",
 				symbol, e.config.LongPosition.TakeProfit*100, e.config.LongPosition.StopLoss*100)
 
-			if pos.PnLRatio >= e.config.LongPosition.TakeProfit {
+			if pnlRatio >= e.config.LongPosition.TakeProfit {
 				log.Printf("[%s] 多头达到止盈点 %.2f%% >= %.2f%%, 执行平仓",
-					symbol, pos.PnLRatio*100, e.config.LongPosition.TakeProfit*100)
-				return e.closeLongPosition(symbol, pos)
+					symbol, pnlRatio*100, e.config.LongPosition.TakeProfit*100)
+				return e.closeLongPosition(ctx, symbol, pos)
 			}
-			if pos.PnLRatio <= -e.config.LongPosition.StopLoss {
+			if pnlRatio <= -e.config.LongPosition.StopLoss {
 				log.Printf("[%s] 多头达到止损点 %.2f%% <= -%.2f%%, 执行平仓",
-					symbol, pos.PnLRatio*100, e.config.LongPosition.StopLoss*100)
-				return e.closeLongPosition(symbol, pos)
+					symbol, pnlRatio*100, e.config.LongPosition.StopLoss*100)
+				return e.closeLongPosition(ctx, symbol, pos)
 			}
 		}
 
 		// 检查空头持仓
-		if pos.PosSide == "short" && pos.Position > 0 {
-			if pos.PnLRatio >= e.config.ShortPosition.TakeProfit {
-				log.Printf("[%s] 空头达到止盈点 %.2f%%, 执行平仓", symbol, pos.PnLRatio*100)
-				return e.closeShortPosition(symbol, pos)
+		if direction == "short" && pos.Position != 0 {
+			if pnlRatio >= e.config.ShortPosition.TakeProfit {
+				log.Printf("[%s] 空头达到止盈点 %.2f%%, 执行平仓", symbol, pnlRatio*100)
+				return e.closeShortPosition(ctx, symbol, pos)
 			}
-			if pos.PnLRatio <= -e.config.ShortPosition.StopLoss {
-				log.Printf("[%s] 空头达到止损点 %.2f%%, 执行平仓", symbol, pos.PnLRatio*100)
-				return e.closeShortPosition(symbol, pos)
+			if pnlRatio <= -e.config.ShortPosition.StopLoss {
+				log.Printf("[%s] 空头达到止损点 %.2f%%, 执行平仓", symbol, pnlRatio*100)
+				return e.closeShortPosition(ctx, symbol, pos)
 			}
 		}
 	}
@@ -449,75 +685,137 @@ func (e *Engine) checkPositionPnL(symbol string) error {
 }
 
 // 修改平仓方法使用正确的 Position 类型
-func (e *Engine) closeLongPosition(symbol string, pos *models.Position) error {
-	// 确保数量为整数
-	sz := int(math.Abs(pos.Position))
-	if sz < 200 {
-		sz = 200 // 确保至少为1
+func (e *Engine) closeLongPosition(ctx context.Context, symbol string, pos *models.Position) error {
+	// 平仓数量使用实际持仓数量
+	signal, inst, err := e.closeSignal(ctx, symbol, pos, "long")
+	if err != nil {
+		return err
 	}
+	sz := inst.FormatSize(math.Abs(pos.Position))
 
 	orderReq := &api.PlaceOrderRequest{
-		InstId:  symbol,
-		TdMode:  e.config.MarginMode,
-		Side:    "sell",           // 平多需要卖出
-		PosSide: "long",           // 平多仓
-		OrdType: "market",         // 使用市价单
-		Sz:      strconv.Itoa(sz), // 使用整数字符串
-		ClOrdId: fmt.Sprintf("close%d", time.Now().UnixNano()/1000000),
+		InstId:     symbol,
+		TdMode:     e.config.MarginMode,
+		Side:       "sell",                 // 平多需要卖出
+		PosSide:    e.orderPosSide("long"), // 平多仓
+		OrdType:    "market",               // 使用市价单
+		Sz:         sz,
+		ClOrdId:    fmt.Sprintf("close%d", time.Now().UnixNano()/1000000),
+		ReduceOnly: e.netMode(), // 买卖模式下避免反向开仓
+	}
+	if inst.IsSpot() {
+		// 现货卖出程序买入的交易货币
+		orderReq.TdMode, orderReq.PosSide, orderReq.ReduceOnly = api.TdModeCash, "", false
+		orderReq.TgtCcy = api.TgtCcyBase
 	}
 
 	log.Printf("[%s] 准备平多头仓位 - 订单参数: %+v", symbol, orderReq)
-	resp, err := e.api.PlaceOrder(orderReq)
+	err = e.submitOrder(ctx, signal, orderReq, signal.Price)
+	if api.IsPositionNotExist(err) {
+		log.Printf("[%s] 多头仓位已不存在(可能已被交易所止盈止损平仓): %v", symbol, err)
+		return nil
+	}
 	if err != nil {
 		log.Printf("[%s] 平多头仓位失败: %v", symbol, err)
 		return fmt.Errorf("平多头仓位失败: %v", err)
 	}
 
-	log.Printf("[%s] 平多头仓位成功 - OrderID: %s, 数量: %d, 收益率: %.2f%%",
-		symbol, resp.OrderId, sz, pos.PnLRatio*100)
+	log.Printf("[%s] 平多头仓位成功 - 数量: %s, 收益率: %.2f%%", symbol, sz, pos.PnLRatio*100)
+	if e.config.LongPosition.ExchangeTPSL {
+		e.cancelTPSL(ctx, symbol, "long")
+	}
 	return nil
 }
 
-func (e *Engine) closeShortPosition(symbol string, pos *models.Position) error {
-	// 确保数量为整数
-	sz := int(math.Abs(pos.Position))
-	if sz < 200 {
-		sz = 200 // 确保至少为1
+// closeSignal 返回平掉pos全部持仓的信号和产品信息。平仓单与开仓单一样
+// 经submitOrder保存交易记录并跟踪成交，平仓的成交价格和手续费才能计入已实现盈亏；
+// 信号价格按开仓均价和收益率估算，成交后由订单跟踪更新为实际成交均价
+func (e *Engine) closeSignal(ctx context.Context, symbol string, pos *models.Position, direction string) (*types.Signal, *api.Instrument, error) {
+	inst, err := e.api.GetInstrument(ctx, symbol)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取产品信息失败: %v", err)
+	}
+	sz := math.Abs(pos.Position)
+
+	dir, leverage := 1.0, e.leverage()
+	if direction == "short" {
+		dir = -1
+	}
+	if !inst.IsDerivative() {
+		leverage = 1
+	}
+	price := pos.AvgPrice * (1 + dir*pos.PnLRatio/leverage)
+
+	signal := &types.Signal{
+		Symbol:    symbol,
+		Strategy:  "CloseLong",
+		Action:    string(api.Sell),
+		Price:     price,
+		Amount:    inst.Amount(sz, price),
+		Timestamp: time.Now().Unix(),
+	}
+	if direction == "short" {
+		signal.Strategy, signal.Action = "CloseShort", string(api.Buy)
+	}
+	return signal, inst, nil
+}
+
+func (e *Engine) closeShortPosition(ctx context.Context, symbol string, pos *models.Position) error {
+	// 平仓数量使用实际持仓数量
+	signal, inst, err := e.closeSignal(ctx, symbol, pos, "short")
+	if err != nil {
+		return err
 	}
+	if inst.IsSpot() {
+		return fmt.Errorf("现货没有空头仓位，无法平空: %s", symbol)
+	}
+	sz := inst.FormatSize(math.Abs(pos.Position))
 
 	orderReq := &api.PlaceOrderRequest{
-		InstId:  symbol,
-		TdMode:  e.config.MarginMode,
-		Side:    "buy",            // 平空需要买入
-		PosSide: "short",          // 平空仓
-		OrdType: "market",         // 使用市价单
-		Sz:      strconv.Itoa(sz), // 使用整数字符串
-		ClOrdId: fmt.Sprintf("close%d", time.Now().UnixNano()/1000000),
+		InstId:     symbol,
+		TdMode:     e.config.MarginMode,
+		Side:       "buy",                   // 平空需要买入
+		PosSide:    e.orderPosSide("short"), // 平空仓
+		OrdType:    "market",                // 使用市价单
+		Sz:         sz,
+		ClOrdId:    fmt.Sprintf("close%d", time.Now().UnixNano()/1000000),
+		ReduceOnly: e.netMode(), // 买卖模式下避免反向开仓
 	}
 
 	log.Printf("[%s] 准备平空头仓位 - 订单参数: %+v", symbol, orderReq)
-	resp, err := e.api.PlaceOrder(orderReq)
+	err = e.submitOrder(ctx, signal, orderReq, signal.Price)
+	if api.IsPositionNotExist(err) {
+		log.Printf("[%s] 空头仓位已不存在(可能已被交易所止盈止损平仓): %v", symbol, err)
+		return nil
+	}
 	if err != nil {
 		log.Printf("[%s] 平空头仓位失败: %v", symbol, err)
 		return fmt.Errorf("平空头仓位失败: %v", err)
 	}
 
-	log.Printf("[%s] 平空头仓位成功 - OrderID: %s, 数量: %d, 收益率: %.2f%%",
-		symbol, resp.OrderId, sz, pos.PnLRatio*100)
+	log.Printf("[%s] 平空头仓位成功 - 数量: %s, 收益率: %.2f%%", symbol, sz, pos.PnLRatio*100)
+	if e.config.ShortPosition.ExchangeTPSL {
+		e.cancelTPSL(ctx, symbol, "short")
+	}
 	return nil
 }
 
 // GetBalance 获取账户余额
-func (e *Engine) GetBalance() ([]*api.Balance, error) {
+func (e *Engine) GetBalance(ctx context.Context) ([]*api.Balance, error) {
 	// 获取所有货币的余额
-	return e.api.GetBalances()
+	return e.getBalances(ctx)
+}
+
+// GetAccountSummary 获取账户权益、保证金和各币种余额
+func (e *Engine) GetAccountSummary(ctx context.Context) (*api.AccountSummary, error) {
+	return e.getAccountSummary(ctx)
 }
 
 // EnableStrategy 启用策略
-func (e *Engine) EnableStrategy(name string) error {
+func (e *Engine) EnableStrategy(ctx context.Context, name string) error {
 	for _, strategy := range e.strategies {
 		if strategy.Name() == name {
-			return strategy.Initialize()
+			return strategy.Initialize(ctx)
 		}
 	}
 	return fmt.Errorf("策略不存在: %s", name)
@@ -540,28 +838,57 @@ func (e *Engine) UpdateStrategyConfig(name string, config map[string]interface{}
 	return fmt.Errorf("暂不支持更新策略配置")
 }
 
+// RateLimitStats 返回交易所客户端的限速等待统计，客户端不支持时返回nil
+func (e *Engine) RateLimitStats() map[string]api.RateLimitStat {
+	if limited, ok := e.api.(interface {
+		RateLimitStats() map[string]api.RateLimitStat
+	}); ok {
+		return limited.RateLimitStats()
+	}
+	return nil
+}
+
+// ClockStatus 返回交易所客户端的时钟偏差，客户端不支持校时时ok为false
+func (e *Engine) ClockStatus() (status api.ClockStatus, ok bool) {
+	if synced, ok := e.api.(interface {
+		ClockStatus() api.ClockStatus
+	}); ok {
+		return synced.ClockStatus(), true
+	}
+	return api.ClockStatus{}, false
+}
+
+// Candles 返回带本地缓存的历史K线服务
+func (e *Engine) Candles() *candles.Service {
+	return e.candles
+}
+
+// Ledger 返回账单同步和对账服务
+func (e *Engine) Ledger() *ledger.Service {
+	return e.ledger
+}
+
+// Account 返回引擎所属账户名称
+func (e *Engine) Account() string {
+	return e.config.Account
+}
+
 // GetConfig 返回交易引擎配置
 func (e *Engine) GetConfig() *Config {
 	return e.config
 }
 
 // checkBalance 检查是否有足够的可用余额
-func (e *Engine) checkBalance(requiredAmount float64) error {
-	balances, err := e.api.GetBalances()
+func (e *Engine) checkBalance(ctx context.Context, requiredAmount float64) error {
+	summary, err := e.getAccountSummary(ctx)
 	if err != nil {
 		return fmt.Errorf("获取余额失败: %v", err)
 	}
 
-	// 查找USDT余额
+	// 查找USDT可用保证金
 	var usdtBalance float64
-	for _, balance := range balances {
-		if balance.Currency == "USDT" {
-			usdtBalance, err = strconv.ParseFloat(balance.Available, 64)
-			if err != nil {
-				return fmt.Errorf("解析USDT余额失败: %v", err)
-			}
-			break
-		}
+	if usdt := summary.Currency("USDT"); usdt != nil {
+		usdtBalance = usdt.AvailEq
 	}
 
 	// 计算实际可用余额
@@ -577,6 +904,39 @@ func (e *Engine) checkBalance(requiredAmount float64) error {
 	return nil
 }
 
+// checkSlippage 按本地深度估算市价单滑点，超过配置的最大滑点时拒绝下单；深度不可用时仅记录日志
+func (e *Engine) checkSlippage(symbol string, side api.OrderSide, sz float64) error {
+	if e.feed == nil {
+		return nil
+	}
+	book, ok := e.feed.OrderBook(symbol)
+	if !ok {
+		log.Printf("[%s] 深度数据不可用，跳过滑点检查", symbol)
+		return nil
+	}
+
+	if bid, ok := book.BestBid(); ok {
+		if ask, ok := book.BestAsk(); ok {
+			log.Printf("[%s] 当前盘口: 买一 %g (%g), 卖一 %g (%g)", symbol, bid.Price, bid.Size, ask.Price, ask.Size)
+		}
+	}
+
+	est, err := book.EstimateFill(side, sz)
+	if err != nil {
+		if e.config.MaxSlippage > 0 {
+			return fmt.Errorf("滑点检查失败: %v", err)
+		}
+		log.Printf("[%s] 滑点估算失败: %v", symbol, err)
+		return nil
+	}
+	log.Printf("[%s] 预估成交: 均价 %g, 最差价 %g, 滑点 %.4f%%", symbol, est.AvgPrice, est.WorstPrice, est.Slippage*100)
+
+	if e.config.MaxSlippage > 0 && est.Slippage > e.config.MaxSlippage {
+		return fmt.Errorf("预估滑点 %.4f%% 超过上限 %.4f%%", est.Slippage*100, e.config.MaxSlippage*100)
+	}
+	return nil
+}
+
 // 修改计算保证金率的方法，直接使用持仓的保证金率，保持百分比形式
 func (e *Engine) calculateMarginRatio(pos *models.Position) (float64, error) {
 	// 直接使用持仓的保证金率，API返回的值需要乘以100
@@ -589,9 +949,14 @@ func (e *Engine) calculateMarginRatio(pos *models.Position) (float64, error) {
 }
 
 // 新增保证金检查方法
-func (e *Engine) checkAndAdjustMargin(symbol string) error {
+func (e *Engine) checkAndAdjustMargin(ctx context.Context, symbol string) error {
+	// 现货没有保证金
+	if spot, err := e.isSpot(ctx, symbol); err != nil || spot {
+		return err
+	}
+
 	// 获取当前持仓
-	positions, err := e.api.GetPositions(symbol)
+	positions, err := e.getPositions(ctx, symbol)
 	if err != nil {
 		return fmt.Errorf("获取持仓失败: %v", err)
 	}
@@ -606,7 +971,7 @@ func (e *Engine) checkAndAdjustMargin(symbol string) error {
 		var marginAmount float64
 		var symbolMarginRatio float64
 
-		if pos.PosSide == "long" {
+		if positionDirection(pos) == "long" {
 			// 检查是否有针对该交易对的特定保证金率配置
 			if ratio, ok := e.config.LongPosition.SymbolMarginRatios[symbol]; ok {
 				symbolMarginRatio = ratio * 100
@@ -647,7 +1012,7 @@ func (e *Engine) checkAndAdjustMargin(symbol string) error {
 			addAmount := marginAmount
 
 			// 追加保证金
-			err = e.addMargin(symbol, pos.PosSide, addAmount)
+			err = e.addMargin(ctx, symbol, pos.PosSide, addAmount)
 			if err != nil {
 				return fmt.Errorf("追加保证金失败: %v", err)
 			}
@@ -664,10 +1029,16 @@ func (e *Engine) checkAndAdjustMargin(symbol string) error {
 }
 
 // 追加保证金
-func (e *Engine) addMargin(symbol, posSide string, amount float64) error {
-	// 检查可用余额
-	if err := e.checkBalance(amount); err != nil {
-		return err
+func (e *Engine) addMargin(ctx context.Context, symbol, posSide string, amount float64) error {
+	// 可用余额不足时先从资金账户补充，补足后推送缓存可能尚未更新，不再重复检查余额
+	if transferred, err := e.replenishFunds(ctx, amount); err != nil || transferred == 0 {
+		if err != nil {
+			log.Printf("从资金账户补充保证金失败: %v", err)
+		}
+		// 检查可用余额
+		if err := e.checkBalance(ctx, amount); err != nil {
+			return err
+		}
 	}
 
 	// 调用API追加保证金
@@ -678,6 +1049,6 @@ func (e *Engine) addMargin(symbol, posSide string, amount float64) error {
 		"type":    "add",
 	}
 
-	_, err := e.api.AddMargin(params)
+	_, err := e.api.AddMargin(ctx, params)
 	return err
 }
@@ -0,0 +1,164 @@
+package trading
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"okxauto/internal/api"
+	"okxauto/internal/database"
+	dbmodels "okxauto/internal/database/models"
+	"okxauto/internal/okxsim"
+	"okxauto/internal/types"
+)
+
+// newSimEngine 启动模拟服务器并返回连接到它的交易引擎，交易记录写入临时数据库。
+// 引擎不调用Start，测试直接调用下单和平仓方法，并通过settle驱动订单跟踪
+func newSimEngine(t *testing.T, sim *okxsim.Server, config Config) *Engine {
+	t.Helper()
+	sim.Start()
+	t.Cleanup(sim.Close)
+
+	db, err := database.New(filepath.Join(t.TempDir(), "trades.db"))
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+
+	client := api.NewOKXClient("key", "secret", "pass", "simulation")
+	client.SetBaseURL(sim.URL())
+	e, err := NewEngine(client, db.ForAccount("main"), config)
+	if err != nil {
+		t.Fatalf("创建交易引擎失败: %v", err)
+	}
+	t.Cleanup(e.cancel)
+	return e
+}
+
+// settle 轮询跟踪中的订单直到全部进入终态
+func settle(t *testing.T, e *Engine) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		e.orders.mu.Lock()
+		for _, tracked := range e.orders.orders {
+			tracked.lastSeen = time.Time{}
+		}
+		n := len(e.orders.orders)
+		e.orders.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("等待订单成交超时, 仍有 %d 个订单", n)
+		}
+		e.orders.poll(context.Background())
+	}
+}
+
+// tradesOf 按创建顺序返回引擎账户的交易记录
+func tradesOf(t *testing.T, e *Engine) []*dbmodels.Trade {
+	t.Helper()
+	trades, err := e.db.GetTrades(100)
+	if err != nil {
+		t.Fatalf("查询交易记录失败: %v", err)
+	}
+	for i, j := 0, len(trades)-1; i < j; i, j = i+1, j-1 {
+		trades[i], trades[j] = trades[j], trades[i]
+	}
+	return trades
+}
+
+// waitFor 轮询直到cond成立
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestEngineFillsOrderAgainstSim(t *testing.T) {
+	sim := okxsim.New(10000)
+	sim.AddInstrument("BTC-USDT-SWAP", 30000, 0.01)
+	sim.SetPositionMode(api.PosModeNet)
+
+	var config Config
+	config.TradeType = "futures"
+	config.Leverage = 10
+	config.MarginMode = "cross"
+	config.Symbols = []string{"BTC-USDT-SWAP"}
+	// 止盈止损设得足够宽，测试期间不触发程序平仓
+	config.LongPosition.TakeProfit, config.LongPosition.StopLoss = 0.5, 0.5
+	config.ShortPosition.TakeProfit, config.ShortPosition.StopLoss = 0.5, 0.5
+	e := newSimEngine(t, sim, config)
+
+	// 与main相同通过私有频道接收订单、持仓和余额推送
+	_, private, _ := sim.WSURLs()
+	accountFeed := api.NewAccountFeed(private, e.api.(*api.OKXClient))
+	if err := accountFeed.Start(); err != nil {
+		t.Fatalf("启动私有频道推送失败: %v", err)
+	}
+	t.Cleanup(accountFeed.Stop)
+	e.SetAccountFeed(accountFeed)
+	if err := e.Start(); err != nil {
+		t.Fatalf("启动交易引擎失败: %v", err)
+	}
+	t.Cleanup(e.Stop)
+	if e.PositionMode() != api.PosModeNet {
+		t.Fatalf("持仓模式 = %s", e.PositionMode())
+	}
+
+	ctx := context.Background()
+	netPosition := func() float64 {
+		positions, err := e.api.GetPositions(ctx, "BTC-USDT-SWAP")
+		if err != nil || len(positions) == 0 {
+			return 0
+		}
+		return positions[0].Position
+	}
+	filled := func(n int) func() bool {
+		return func() bool {
+			trades, err := e.db.GetTrades(100)
+			if err != nil || len(trades) != n {
+				return false
+			}
+			for _, trade := range trades {
+				if trade.Status != "filled" {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	// 买入0.1 BTC(10张)，经信号通道、下单、私有频道推送成交
+	e.signals <- &types.Signal{Symbol: "BTC-USDT-SWAP", Strategy: "rsi", Action: "buy", Price: 30000, Amount: 0.1}
+	waitFor(t, "买单成交", filled(1))
+	waitFor(t, "持仓增加", func() bool { return netPosition() == 10 })
+
+	trade := tradesOf(t, e)[0]
+	if trade.Side != "buy" || trade.FillPrice != 30000 || trade.FillAmount != 0.1 || trade.Fee >= 0 || trade.FeeCcy != "USDT" {
+		t.Fatalf("买单交易记录 = %+v", trade)
+	}
+
+	// 卖出0.04 BTC，买卖模式下减少净持仓
+	sim.SetPrice("BTC-USDT-SWAP", 30300)
+	e.signals <- &types.Signal{Symbol: "BTC-USDT-SWAP", Strategy: "rsi", Action: "sell", Price: 30300, Amount: 0.04}
+	waitFor(t, "卖单成交", filled(2))
+	waitFor(t, "持仓减少", func() bool { return netPosition() == 6 })
+
+	if trade := tradesOf(t, e)[1]; trade.Side != "sell" || trade.FillPrice != 30300 || trade.FillAmount != 0.04 {
+		t.Fatalf("卖单交易记录 = %+v", trade)
+	}
+	if positions, ok := accountFeed.Positions("BTC-USDT-SWAP"); !ok || len(positions) != 1 || positions[0].Position != 6 {
+		t.Fatalf("私有频道推送的持仓 = %+v", positions)
+	}
+}
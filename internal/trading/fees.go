@@ -0,0 +1,114 @@
+package trading
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"okxauto/internal/api"
+	"okxauto/internal/models"
+)
+
+// 手续费率刷新间隔，交易所按30天交易量每日调整费率等级
+const feeRateRefreshInterval = time.Hour
+
+// feeCache 按产品类型缓存账户手续费率
+type feeCache struct {
+	api api.Exchange
+
+	mu      sync.Mutex
+	rates   map[string]*api.FeeRate // key: instType
+	fetched map[string]time.Time
+}
+
+func newFeeCache(apiClient api.Exchange) *feeCache {
+	return &feeCache{
+		api:     apiClient,
+		rates:   make(map[string]*api.FeeRate),
+		fetched: make(map[string]time.Time),
+	}
+}
+
+// get 返回产品类型的手续费率，缓存过期时重新查询，查询失败时沿用上一次的值
+func (f *feeCache) get(ctx context.Context, instType string) (*api.FeeRate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rate, ok := f.rates[instType]
+	if ok && time.Since(f.fetched[instType]) < feeRateRefreshInterval {
+		return rate, nil
+	}
+
+	fresh, err := f.api.GetFeeRate(ctx, instType)
+	if err != nil {
+		if ok {
+			log.Printf("刷新%s手续费率失败，沿用上次的费率: %v", instType, err)
+			return rate, nil
+		}
+		return nil, err
+	}
+	if !ok || fresh.Level != rate.Level {
+		log.Printf("%s手续费等级: %s, 挂单费率: %.4f%%, 吃单费率: %.4f%%",
+			instType, fresh.Level, fresh.Maker*100, fresh.Taker*100)
+	}
+	f.rates[instType] = fresh
+	f.fetched[instType] = time.Now()
+	return fresh, nil
+}
+
+// takerRate 返回交易对的吃单费率，获取失败时返回0，即按毛收益计算
+func (e *Engine) takerRate(ctx context.Context, symbol string) float64 {
+	inst, err := e.api.GetInstrument(ctx, symbol)
+	if err != nil {
+		log.Printf("[%s] 获取产品信息失败，按毛收益率计算: %v", symbol, err)
+		return 0
+	}
+	rate, err := e.fees.get(ctx, inst.InstType)
+	if err != nil {
+		log.Printf("[%s] 获取手续费率失败，按毛收益率计算: %v", symbol, err)
+		return 0
+	}
+	return rate.Taker
+}
+
+// netRatio 由毛收益率计算扣除开平仓手续费后的净收益率，收益率均按保证金计算。
+// 开平仓都按吃单费率估算，开仓名义价值为保证金*杠杆，平仓名义价值为开仓的(1 + dir*gross/杠杆)倍，
+// dir多头为1、空头为-1，因此 net = gross*(1 - dir*taker) - 2*taker*杠杆
+func netRatio(gross, dir, taker, leverage float64) float64 {
+	return gross*(1-dir*taker) - 2*taker*leverage
+}
+
+// grossRatio 净收益率对应的毛收益率，netRatio的逆运算
+func grossRatio(net, dir, taker, leverage float64) float64 {
+	return (net + 2*taker*leverage) / (1 - dir*taker)
+}
+
+// pnlRatio 返回用于止盈止损判断的收益率，开启net_of_fee_pnl时扣除开平仓手续费
+func (e *Engine) pnlRatio(ctx context.Context, symbol string, pos *models.Position, direction string) float64 {
+	if !e.config.NetOfFeePnL {
+		return pos.PnLRatio
+	}
+	dir := 1.0
+	if direction == "short" {
+		dir = -1
+	}
+	leverage := e.leverage()
+	if spot, err := e.isSpot(ctx, symbol); err != nil {
+		log.Printf("[%s] %v，使用毛收益率", symbol, err)
+		return pos.PnLRatio
+	} else if spot {
+		leverage = 1
+	}
+	net := netRatio(pos.PnLRatio, dir, e.takerRate(ctx, symbol), leverage)
+	log.Printf("[%s] 扣除手续费后收益率: %.2f%% (毛收益率 %.2f%%)", symbol, net*100, pos.PnLRatio*100)
+	return net
+}
+
+// leverage 返回配置的杠杆倍数，未配置时为1
+func (e *Engine) leverage() float64 {
+	if e.config.Leverage <= 0 {
+		return 1
+	}
+	return float64(e.config.Leverage)
+}
@@ -0,0 +1,163 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"okxauto/internal/api"
+)
+
+// 默认的资金划转检查间隔
+const defaultFundTransferInterval = time.Minute
+
+// fundCcy 返回自动划转的币种
+func (e *Engine) fundCcy() string {
+	if e.config.FundTransfer.Ccy != "" {
+		return e.config.FundTransfer.Ccy
+	}
+	return "USDT"
+}
+
+// runFundTransfer 按间隔检查交易账户可用余额，超过上限的部分归集到资金账户，低于下限时从资金账户补足
+func (e *Engine) runFundTransfer(ctx context.Context) {
+	cfg := e.config.FundTransfer
+	if cfg.MaxBalance > 0 && cfg.MaxBalance <= math.Max(cfg.MinBalance, e.config.ReserveBalance) {
+		log.Printf("资金划转上限 %.2f 需大于下限 %.2f 和预留余额 %.2f，不启用自动划转",
+			cfg.MaxBalance, cfg.MinBalance, e.config.ReserveBalance)
+		return
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultFundTransferInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := e.rebalanceFunds(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("资金划转失败: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// rebalanceFunds 将交易账户可用余额调整到[MinBalance, MaxBalance]范围内
+func (e *Engine) rebalanceFunds(ctx context.Context) error {
+	e.fundsMu.Lock()
+	defer e.fundsMu.Unlock()
+
+	cfg := e.config.FundTransfer
+	ccy := e.fundCcy()
+	avail, err := e.tradingAvailable(ctx, ccy)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case cfg.MaxBalance > 0 && avail > cfg.MaxBalance:
+		amt := floorAmount(avail - cfg.MaxBalance)
+		if amt <= 0 {
+			return nil
+		}
+		if _, err := e.api.Transfer(ctx, ccy, amt, api.AccountTrading, api.AccountFunding, transferClientId("sweep")); err != nil {
+			return err
+		}
+		log.Printf("交易账户可用 %.4f %s 超过上限 %.2f，归集 %.2f %s 到资金账户", avail, ccy, cfg.MaxBalance, amt, ccy)
+	case cfg.MinBalance > 0 && avail < cfg.MinBalance:
+		amt, err := e.transferIn(ctx, ccy, cfg.MinBalance-avail)
+		if err != nil {
+			return err
+		}
+		if amt > 0 {
+			log.Printf("交易账户可用 %.4f %s 低于下限 %.2f，从资金账户补充 %.2f %s", avail, ccy, cfg.MinBalance, amt, ccy)
+		}
+	}
+	return nil
+}
+
+// replenishFunds 追加保证金前检查交易账户可用余额(扣除预留)，不足amount时从资金账户补足差额，
+// 返回划转的数量；未启用自动划转或余额充足时返回0
+func (e *Engine) replenishFunds(ctx context.Context, amount float64) (float64, error) {
+	if !e.config.FundTransfer.Enabled {
+		return 0, nil
+	}
+	e.fundsMu.Lock()
+	defer e.fundsMu.Unlock()
+
+	ccy := e.fundCcy()
+	avail, err := e.tradingAvailable(ctx, ccy)
+	if err != nil {
+		return 0, err
+	}
+	shortfall := amount + e.config.ReserveBalance - avail
+	if shortfall <= 0 {
+		return 0, nil
+	}
+
+	amt, err := e.transferIn(ctx, ccy, shortfall)
+	if err != nil {
+		return 0, err
+	}
+	if amt < shortfall {
+		return amt, fmt.Errorf("资金账户余额不足，需要补充 %.4f %s，实际划入 %.2f %s", shortfall, ccy, amt, ccy)
+	}
+	log.Printf("追加保证金需要 %.4f %s，交易账户可用 %.4f %s (预留 %.2f)，从资金账户补充 %.2f %s",
+		amount, ccy, avail, ccy, e.config.ReserveBalance, amt, ccy)
+	return amt, nil
+}
+
+// transferIn 从资金账户向交易账户划入want，资金账户余额不足时划入全部可用余额，返回划入的数量
+func (e *Engine) transferIn(ctx context.Context, ccy string, want float64) (float64, error) {
+	balances, err := e.api.GetAssetBalances(ctx, ccy)
+	if err != nil {
+		return 0, fmt.Errorf("获取资金账户余额失败: %v", err)
+	}
+	var funding float64
+	for _, b := range balances {
+		if b.Ccy == ccy {
+			funding = b.AvailBal
+		}
+	}
+
+	amt := math.Min(ceilAmount(want), floorAmount(funding))
+	if amt <= 0 {
+		return 0, nil
+	}
+	if _, err := e.api.Transfer(ctx, ccy, amt, api.AccountFunding, api.AccountTrading, transferClientId("fund")); err != nil {
+		return 0, err
+	}
+	return amt, nil
+}
+
+// tradingAvailable 查询交易账户币种可用余额，划转前后需要最新数据，不使用推送缓存
+func (e *Engine) tradingAvailable(ctx context.Context, ccy string) (float64, error) {
+	summary, err := e.api.GetAccountSummary(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("获取交易账户余额失败: %v", err)
+	}
+	if d := summary.Currency(ccy); d != nil {
+		return d.AvailBal, nil
+	}
+	return 0, nil
+}
+
+// floorAmount/ceilAmount 划转数量保留两位小数
+func floorAmount(v float64) float64 {
+	return math.Floor(v*100) / 100
+}
+
+func ceilAmount(v float64) float64 {
+	return math.Ceil(v*100) / 100
+}
+
+func transferClientId(prefix string) string {
+	return fmt.Sprintf("%s%d", prefix, time.Now().UnixNano()/1000000)
+}
@@ -0,0 +1,249 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"okxauto/internal/api"
+)
+
+// stubFunds 模拟交易账户和资金账户的USDT余额。failTransfer非nil时下一次划转失败，
+// applied为true表示划转已在交易所完成但响应丢失
+type stubFunds struct {
+	api.Exchange
+	trading, funding float64
+
+	failTransfer error
+	applied      bool
+
+	transfers    []float64 // 划入交易账户为正，划出为负
+	assetQueries int
+	margins      []map[string]string
+}
+
+func (s *stubFunds) GetAccountSummary(ctx context.Context) (*api.AccountSummary, error) {
+	return &api.AccountSummary{Details: []*api.CurrencySummary{{Ccy: "USDT", AvailBal: s.trading, AvailEq: s.trading}}}, nil
+}
+
+func (s *stubFunds) GetAssetBalances(ctx context.Context, ccy string) ([]*api.AssetBalance, error) {
+	s.assetQueries++
+	if s.funding == 0 {
+		return nil, nil
+	}
+	return []*api.AssetBalance{{Ccy: "USDT", Bal: s.funding, AvailBal: s.funding}}, nil
+}
+
+func (s *stubFunds) Transfer(ctx context.Context, ccy string, amt float64, from, to, clientId string) (*api.TransferResult, error) {
+	if from == api.AccountTrading {
+		amt = -amt
+	}
+	if err := s.failTransfer; err != nil {
+		s.failTransfer = nil
+		if !s.applied {
+			return nil, err
+		}
+		s.move(amt)
+		return nil, err
+	}
+	if amt > s.funding || -amt > s.trading {
+		return nil, errors.New("Insufficient balance")
+	}
+	s.move(amt)
+	return &api.TransferResult{Ccy: ccy, Amt: math.Abs(amt), From: from, To: to, ClientId: clientId}, nil
+}
+
+func (s *stubFunds) move(amt float64) {
+	s.funding -= amt
+	s.trading += amt
+	s.transfers = append(s.transfers, amt)
+}
+
+func (s *stubFunds) AddMargin(ctx context.Context, params map[string]string) (map[string]interface{}, error) {
+	s.margins = append(s.margins, params)
+	return nil, nil
+}
+
+func newFundsEngine(stub *stubFunds, reserve, min, max float64) *Engine {
+	config := &Config{ReserveBalance: reserve}
+	config.FundTransfer.Enabled = true
+	config.FundTransfer.MinBalance = min
+	config.FundTransfer.MaxBalance = max
+	return &Engine{api: stub, config: config}
+}
+
+func sameTransfers(got, want []float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestReplenishFunds(t *testing.T) {
+	tests := []struct {
+		name             string
+		disabled         bool
+		trading, funding float64
+		reserve, amount  float64
+		want             float64
+		wantErr          bool
+		wantQueries      int
+	}{
+		{name: "未启用自动划转", disabled: true, trading: 0, funding: 100, amount: 50},
+		{name: "余额充足", trading: 100, funding: 100, reserve: 10, amount: 50, wantQueries: 0},
+		{name: "补足差额含预留", trading: 30, funding: 100, reserve: 10, amount: 50, want: 30, wantQueries: 1},
+		{name: "差额向上取整到分", trading: 30, funding: 100, reserve: 10, amount: 50.001, want: 30.01, wantQueries: 1},
+		{name: "资金账户不足时划入全部可用", trading: 30, funding: 12.345, reserve: 10, amount: 50, want: 12.34, wantErr: true, wantQueries: 1},
+		{name: "资金账户不足一分", trading: 30, funding: 0.009, reserve: 10, amount: 50, wantErr: true, wantQueries: 1},
+		{name: "资金账户为空", trading: 30, reserve: 10, amount: 50, wantErr: true, wantQueries: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubFunds{trading: tt.trading, funding: tt.funding}
+			e := newFundsEngine(stub, tt.reserve, 0, 0)
+			e.config.FundTransfer.Enabled = !tt.disabled
+
+			got, err := e.replenishFunds(context.Background(), tt.amount)
+			if (err != nil) != tt.wantErr || math.Abs(got-tt.want) > 1e-9 {
+				t.Fatalf("划入 %v, 错误 %v; 期望划入 %v, 出错 %v", got, err, tt.want, tt.wantErr)
+			}
+			var want []float64
+			if tt.want > 0 {
+				want = []float64{tt.want}
+			}
+			if !sameTransfers(stub.transfers, want) {
+				t.Fatalf("划转 = %v, 期望 %v", stub.transfers, want)
+			}
+			if stub.assetQueries != tt.wantQueries {
+				t.Fatalf("查询资金账户 %d 次, 期望 %d 次", stub.assetQueries, tt.wantQueries)
+			}
+		})
+	}
+}
+
+func TestRebalanceFunds(t *testing.T) {
+	tests := []struct {
+		name             string
+		trading, funding float64
+		min, max         float64
+		want             []float64
+	}{
+		{name: "超过上限归集，向下取整到分", trading: 1500.129, min: 100, max: 1000, want: []float64{-500.12}},
+		{name: "低于下限补足，向上取整到分", trading: 49.995, funding: 1000, min: 100, max: 1000, want: []float64{50.01}},
+		{name: "资金账户不足时划入全部可用", trading: 50, funding: 20, min: 100, max: 1000, want: []float64{20}},
+		{name: "资金账户为空", trading: 50, min: 100, max: 1000},
+		{name: "在范围内", trading: 500, funding: 1000, min: 100, max: 1000},
+		{name: "只补充不归集", trading: 5000, funding: 1000, min: 100},
+		{name: "超出部分不足一分", trading: 1000.004, min: 100, max: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubFunds{trading: tt.trading, funding: tt.funding}
+			e := newFundsEngine(stub, 0, tt.min, tt.max)
+
+			if err := e.rebalanceFunds(context.Background()); err != nil {
+				t.Fatalf("资金划转失败: %v", err)
+			}
+			if !sameTransfers(stub.transfers, tt.want) {
+				t.Fatalf("划转 = %v, 期望 %v", stub.transfers, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplenishFundsTransferFailsPartway(t *testing.T) {
+	tests := []struct {
+		name    string
+		applied bool
+		// 重试后的划转记录，响应丢失的划转已在交易所完成，不应重复划入
+		want []float64
+	}{
+		{"划转未完成", false, []float64{30}},
+		{"划转完成但响应丢失", true, []float64{30}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubFunds{trading: 30, funding: 100, failTransfer: errors.New("i/o timeout"), applied: tt.applied}
+			e := newFundsEngine(stub, 10, 0, 0)
+			ctx := context.Background()
+
+			if got, err := e.replenishFunds(ctx, 50); err == nil || got != 0 {
+				t.Fatalf("划转失败时应返回错误且划入为0, 实际划入 %v, 错误 %v", got, err)
+			}
+			// 下一次检查按最新余额计算差额
+			if _, err := e.replenishFunds(ctx, 50); err != nil {
+				t.Fatalf("重试失败: %v", err)
+			}
+			if !sameTransfers(stub.transfers, tt.want) {
+				t.Fatalf("划转 = %v, 期望 %v", stub.transfers, tt.want)
+			}
+			if stub.trading != 60 || stub.funding != 70 {
+				t.Fatalf("交易账户 %v, 资金账户 %v; 期望 60 和 70", stub.trading, stub.funding)
+			}
+		})
+	}
+}
+
+func TestRebalanceFundsTransferFails(t *testing.T) {
+	stub := &stubFunds{trading: 1500, failTransfer: errors.New("Insufficient balance")}
+	e := newFundsEngine(stub, 0, 100, 1000)
+
+	if err := e.rebalanceFunds(context.Background()); err == nil {
+		t.Fatal("归集失败时应返回错误")
+	}
+	if stub.trading != 1500 || len(stub.transfers) != 0 {
+		t.Fatalf("归集失败后余额不应变化: 交易账户 %v, 划转 %v", stub.trading, stub.transfers)
+	}
+}
+
+func TestAddMarginWithFunding(t *testing.T) {
+	tests := []struct {
+		name             string
+		trading, funding float64
+		wantTransfers    []float64
+		wantErr          string
+	}{
+		{"资金账户补足后追加", 5, 100, []float64{15}, ""},
+		{"交易账户余额充足", 50, 100, nil, ""},
+		{"资金账户不足", 5, 10, []float64{10}, "可用USDT余额不足"},
+		// 资金账户为空时直接按余额检查失败，不重复查询或划转
+		{"资金账户为空", 5, 0, nil, "可用USDT余额不足"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubFunds{trading: tt.trading, funding: tt.funding}
+			e := newFundsEngine(stub, 0, 0, 0)
+
+			err := e.addMargin(context.Background(), "BTC-USDT-SWAP", "long", 20)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("追加保证金失败: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("错误 = %v, 期望包含 %q", err, tt.wantErr)
+			}
+			if !sameTransfers(stub.transfers, tt.wantTransfers) {
+				t.Fatalf("划转 = %v, 期望 %v", stub.transfers, tt.wantTransfers)
+			}
+			wantMargins := 0
+			if tt.wantErr == "" {
+				wantMargins = 1
+			}
+			if len(stub.margins) != wantMargins {
+				t.Fatalf("追加保证金请求 %d 次, 期望 %d 次", len(stub.margins), wantMargins)
+			}
+			if wantMargins == 1 && stub.margins[0]["amt"] != "20.0000" {
+				t.Fatalf("追加保证金参数 = %v", stub.margins[0])
+			}
+			if tt.trading < 20 && stub.assetQueries != 1 {
+				t.Fatalf("查询资金账户 %d 次, 期望 1 次", stub.assetQueries)
+			}
+		})
+	}
+}
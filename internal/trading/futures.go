@@ -0,0 +1,120 @@
+package trading
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"okxauto/internal/api"
+	"okxauto/internal/types"
+)
+
+// 资金费率、标记价格、指数价格和持仓总量的刷新间隔
+const futuresRefreshInterval = 15 * time.Second
+
+// futuresCache 缓存各永续合约的最新合约行情，行情推送给策略时附带快照
+type futuresCache struct {
+	api api.Exchange
+
+	mu   sync.RWMutex
+	data map[string]*types.FuturesData
+}
+
+func newFuturesCache(apiClient api.Exchange) *futuresCache {
+	return &futuresCache{
+		api:  apiClient,
+		data: make(map[string]*types.FuturesData),
+	}
+}
+
+// get 返回合约行情的副本，尚未获取到时返回nil
+func (f *futuresCache) get(symbol string) *types.FuturesData {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	d, ok := f.data[symbol]
+	if !ok {
+		return nil
+	}
+	copied := *d
+	return &copied
+}
+
+// run 定期刷新合约行情，ctx结束时退出
+func (f *futuresCache) run(ctx context.Context, symbols []string) {
+	ticker := time.NewTicker(futuresRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		for _, symbol := range symbols {
+			if ctx.Err() != nil {
+				return
+			}
+			f.refresh(ctx, symbol)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh 刷新单个合约的行情，部分接口失败时保留上一次的值
+func (f *futuresCache) refresh(ctx context.Context, symbol string) {
+	d := f.get(symbol)
+	if d == nil {
+		d = &types.FuturesData{}
+	}
+
+	failed := 0
+	if rate, err := f.api.GetFundingRate(ctx, symbol); err == nil {
+		d.FundingRate = rate.FundingRate
+		d.NextFundingRate = rate.NextFundingRate
+		d.FundingTime = rate.FundingTime.Unix()
+		d.NextFundingTime = rate.NextFundingTime.Unix()
+	} else {
+		failed++
+		log.Printf("[%s] 获取资金费率失败: %v", symbol, err)
+	}
+	if mark, err := f.api.GetMarkPrice(ctx, symbol); err == nil {
+		d.MarkPrice = mark.MarkPx
+	} else {
+		failed++
+		log.Printf("[%s] 获取标记价格失败: %v", symbol, err)
+	}
+	if index, err := f.api.GetIndexPrice(ctx, api.IndexId(symbol)); err == nil {
+		d.IndexPrice = index.IdxPx
+	} else {
+		failed++
+		log.Printf("[%s] 获取指数价格失败: %v", symbol, err)
+	}
+	if oi, err := f.api.GetOpenInterest(ctx, symbol); err == nil {
+		d.OpenInterest = oi.Oi
+		d.OpenInterestCcy = oi.OiCcy
+	} else {
+		failed++
+		log.Printf("[%s] 获取持仓总量失败: %v", symbol, err)
+	}
+	if failed == 4 {
+		return
+	}
+	d.UpdatedAt = time.Now().Unix()
+
+	f.mu.Lock()
+	f.data[symbol] = d
+	f.mu.Unlock()
+}
+
+// swapSymbols 返回需要刷新合约行情的永续合约
+func swapSymbols(symbols []string) []string {
+	var result []string
+	for _, symbol := range symbols {
+		if strings.HasSuffix(symbol, "-SWAP") {
+			result = append(result, symbol)
+		}
+	}
+	return result
+}
@@ -0,0 +1,238 @@
+package trading
+
+import (
+	"context"
+	"log"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"okxauto/internal/api"
+	"okxauto/internal/database"
+	dbmodels "okxauto/internal/database/models"
+)
+
+const (
+	// 订单状态轮询间隔，私有频道推送丢失时由轮询兜底
+	orderPollInterval = 2 * time.Second
+	// 连续查询失败超过该次数后停止跟踪
+	orderMaxFailures = 10
+)
+
+// trackedOrder 跟踪中的订单
+type trackedOrder struct {
+	symbol   string
+	ordId    string
+	strategy string
+	state    string
+	lastSeen time.Time // 最近一次收到推送或查询成功的时间
+	failures int
+}
+
+// orderTracker 跟踪订单从live到filled/canceled的生命周期，
+// 并将实际成交均价、成交数量和手续费写回交易记录
+type orderTracker struct {
+	api api.Exchange
+	db  *database.Database
+
+	mu     sync.Mutex
+	orders map[string]*trackedOrder // key: ordId
+
+	// onFilled 订单进入终态且有成交时回调，参数为成交均价和成交数量
+	onFilled func(ctx context.Context, tracked *trackedOrder, avgPx, fillSz float64)
+}
+
+func newOrderTracker(apiClient api.Exchange, db *database.Database) *orderTracker {
+	return &orderTracker{
+		api:    apiClient,
+		db:     db,
+		orders: make(map[string]*trackedOrder),
+	}
+}
+
+// track 开始跟踪订单，下一次轮询时查询订单状态
+func (t *orderTracker) track(symbol, ordId, strategy string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.orders[ordId] = &trackedOrder{symbol: symbol, ordId: ordId, strategy: strategy, state: "live"}
+}
+
+// restore 从数据库恢复尚未终结的订单，用于程序重启后继续跟踪
+func (t *orderTracker) restore() error {
+	trades, err := t.db.GetOpenTrades()
+	if err != nil {
+		return err
+	}
+	for _, trade := range trades {
+		if trade.OrderID == "" {
+			continue
+		}
+		t.track(trade.Symbol, trade.OrderID, trade.Strategy)
+	}
+	if len(trades) > 0 {
+		log.Printf("恢复跟踪未完成订单 %d 个", len(trades))
+	}
+	return nil
+}
+
+// run 处理私有频道订单推送并定期轮询订单状态，updates为nil时仅使用轮询，ctx结束时退出
+func (t *orderTracker) run(ctx context.Context, updates <-chan *api.Order) {
+	ticker := time.NewTicker(orderPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case o := <-updates:
+			t.mu.Lock()
+			tracked, ok := t.orders[o.OrdId]
+			t.mu.Unlock()
+			if ok {
+				t.apply(ctx, tracked, o)
+			}
+		case <-ticker.C:
+			t.poll(ctx)
+		}
+	}
+}
+
+// poll 查询超过轮询间隔未更新的订单
+func (t *orderTracker) poll(ctx context.Context) {
+	t.mu.Lock()
+	due := make([]*trackedOrder, 0, len(t.orders))
+	for _, tracked := range t.orders {
+		if time.Since(tracked.lastSeen) >= orderPollInterval {
+			due = append(due, tracked)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, tracked := range due {
+		o, err := t.api.GetOrder(ctx, tracked.symbol, tracked.ordId)
+		if ctx.Err() != nil {
+			return
+		}
+		if api.IsOrderNotExist(err) {
+			log.Printf("[%s] 订单 %s 不存在，停止跟踪: %v", tracked.symbol, tracked.ordId, err)
+			t.remove(tracked.ordId)
+			continue
+		}
+		if err != nil {
+			tracked.failures++
+			log.Printf("[%s] 查询订单 %s 失败(%d/%d): %v",
+				tracked.symbol, tracked.ordId, tracked.failures, orderMaxFailures, err)
+			if tracked.failures >= orderMaxFailures {
+				log.Printf("[%s] 订单 %s 多次查询失败，停止跟踪", tracked.symbol, tracked.ordId)
+				t.remove(tracked.ordId)
+			}
+			continue
+		}
+		t.apply(ctx, tracked, o)
+	}
+}
+
+// apply 根据订单最新状态更新交易记录，终态时核对成交明细并停止跟踪
+func (t *orderTracker) apply(ctx context.Context, tracked *trackedOrder, o *api.Order) {
+	tracked.lastSeen = time.Now()
+	tracked.failures = 0
+
+	avgPx, _ := strconv.ParseFloat(o.AvgPx, 64)
+	fillSz, _ := strconv.ParseFloat(o.AccFillSz, 64)
+	fee, _ := strconv.ParseFloat(o.Fee, 64)
+	feeCcy := o.FeeCcy
+
+	if o.IsFinal() && fillSz > 0 {
+		avgPx, fillSz, fee, feeCcy = t.reconcileFills(ctx, tracked, avgPx, fillSz, fee, feeCcy)
+	}
+
+	// 成交数量换算为币数量，与交易记录的数量单位一致
+	amount := fillSz
+	if inst, err := t.api.GetInstrument(ctx, tracked.symbol); err == nil {
+		amount = inst.Amount(fillSz, avgPx)
+	}
+
+	if o.State != tracked.state || o.IsFinal() {
+		log.Printf("[%s] 订单 %s 状态: %s -> %s, 成交均价=%.4f, 成交数量=%.4f, 手续费=%.6f %s",
+			tracked.symbol, tracked.ordId, tracked.state, o.State, avgPx, fillSz, fee, feeCcy)
+	}
+	tracked.state = o.State
+
+	if err := t.db.UpdateTradeFill(tracked.ordId, o.State, avgPx, amount, fee, feeCcy); err != nil {
+		log.Printf("[%s] 更新订单 %s 成交信息失败: %v", tracked.symbol, tracked.ordId, err)
+		return
+	}
+
+	if o.IsFinal() {
+		t.remove(tracked.ordId)
+		if fillSz > 0 && t.onFilled != nil {
+			t.onFilled(ctx, tracked, avgPx, fillSz)
+		}
+	}
+}
+
+// reconcileFills 汇总成交明细核对订单成交信息，明细与订单一致时以明细计算的均价和手续费为准
+func (t *orderTracker) reconcileFills(ctx context.Context, tracked *trackedOrder, avgPx, fillSz, fee float64, feeCcy string) (float64, float64, float64, string) {
+	fills, err := t.api.GetFills(ctx, tracked.symbol, tracked.ordId)
+	if err != nil {
+		log.Printf("[%s] 查询订单 %s 成交明细失败，使用订单汇总信息: %v", tracked.symbol, tracked.ordId, err)
+		return avgPx, fillSz, fee, feeCcy
+	}
+	if len(fills) == 0 {
+		return avgPx, fillSz, fee, feeCcy
+	}
+	t.saveFills(tracked, fills)
+
+	var sumSz, sumValue, sumFee float64
+	for _, f := range fills {
+		px, _ := strconv.ParseFloat(f.FillPx, 64)
+		sz, _ := strconv.ParseFloat(f.FillSz, 64)
+		fillFee, _ := strconv.ParseFloat(f.Fee, 64)
+		sumSz += sz
+		sumValue += px * sz
+		sumFee += fillFee
+		if f.FeeCcy != "" {
+			feeCcy = f.FeeCcy
+		}
+	}
+
+	if math.Abs(sumSz-fillSz) > 1e-9 {
+		log.Printf("[%s] 订单 %s 成交明细数量 %.4f 与订单累计成交数量 %.4f 不一致，使用订单汇总信息",
+			tracked.symbol, tracked.ordId, sumSz, fillSz)
+		return avgPx, fillSz, fee, feeCcy
+	}
+
+	return sumValue / sumSz, sumSz, sumFee, feeCcy
+}
+
+// saveFills 保存每笔成交的价格、数量和手续费
+func (t *orderTracker) saveFills(tracked *trackedOrder, fills []*api.Fill) {
+	records := make([]*dbmodels.Fill, 0, len(fills))
+	for _, f := range fills {
+		ts, _ := strconv.ParseInt(f.Ts, 10, 64)
+		record := &dbmodels.Fill{
+			Symbol:   f.InstId,
+			TradeID:  f.TradeId,
+			OrderID:  f.OrdId,
+			Side:     f.Side,
+			PosSide:  f.PosSide,
+			FeeCcy:   f.FeeCcy,
+			ExecType: f.ExecType,
+			Ts:       ts,
+		}
+		record.FillPrice, _ = strconv.ParseFloat(f.FillPx, 64)
+		record.FillSize, _ = strconv.ParseFloat(f.FillSz, 64)
+		record.Fee, _ = strconv.ParseFloat(f.Fee, 64)
+		records = append(records, record)
+	}
+	if err := t.db.SaveFills(records); err != nil {
+		log.Printf("[%s] 保存订单 %s 成交明细失败: %v", tracked.symbol, tracked.ordId, err)
+	}
+}
+
+func (t *orderTracker) remove(ordId string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.orders, ordId)
+}
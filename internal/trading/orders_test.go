@@ -0,0 +1,262 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"okxauto/internal/api"
+	"okxauto/internal/database"
+	dbmodels "okxauto/internal/database/models"
+)
+
+// stubOrders 依次返回预设的订单查询结果，最后一个结果保持不变
+type stubOrders struct {
+	api.Exchange
+
+	mu      sync.Mutex
+	results []stubResult
+	fills   []*api.Fill
+	queries int
+}
+
+type stubResult struct {
+	order *api.Order
+	err   error
+}
+
+func (s *stubOrders) GetOrder(ctx context.Context, instId, ordId string) (*api.Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries++
+	r := s.results[0]
+	if len(s.results) > 1 {
+		s.results = s.results[1:]
+	}
+	return r.order, r.err
+}
+
+func (s *stubOrders) GetFills(ctx context.Context, instId, ordId string) ([]*api.Fill, error) {
+	return s.fills, nil
+}
+
+func (s *stubOrders) GetInstrument(ctx context.Context, instId string) (*api.Instrument, error) {
+	return &api.Instrument{InstId: instId, InstType: "SWAP", CtType: "linear", CtVal: 0.01, LotSz: 1, MinSz: 1}, nil
+}
+
+func order(state, avgPx, accFillSz, fee string) *api.Order {
+	return &api.Order{InstId: "BTC-USDT-SWAP", OrdId: "o1", State: state, AvgPx: avgPx, AccFillSz: accFillSz, Fee: fee, FeeCcy: "USDT"}
+}
+
+func fill(tradeId, px, sz, fee string) *api.Fill {
+	return &api.Fill{InstId: "BTC-USDT-SWAP", OrdId: "o1", TradeId: tradeId, FillPx: px, FillSz: sz, Fee: fee, FeeCcy: "USDT", Ts: "1700000000000"}
+}
+
+// filledCall 记录onFilled回调的参数
+type filledCall struct {
+	avgPx, fillSz float64
+}
+
+// newTestTracker 返回跟踪订单o1的订单跟踪器，交易记录已保存为live
+func newTestTracker(t *testing.T, stub *stubOrders) (*orderTracker, *database.Database, *[]filledCall) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "trades.db"))
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Initialize(); err != nil {
+		t.Fatalf("初始化数据库失败: %v", err)
+	}
+	view := db.ForAccount("main")
+	trade := &dbmodels.Trade{Symbol: "BTC-USDT-SWAP", Side: "buy", Price: 100, Amount: 0.03, Strategy: "LongPosition",
+		Status: "live", OrderID: "o1", TradeType: "futures", CreatedAt: time.Now()}
+	if err := view.SaveTrade(trade); err != nil {
+		t.Fatalf("保存交易记录失败: %v", err)
+	}
+
+	tracker := newOrderTracker(stub, view)
+	var calls []filledCall
+	tracker.onFilled = func(ctx context.Context, tracked *trackedOrder, avgPx, fillSz float64) {
+		calls = append(calls, filledCall{avgPx, fillSz})
+	}
+	tracker.track("BTC-USDT-SWAP", "o1", "LongPosition")
+	return tracker, view, &calls
+}
+
+// pollDue 把订单的最近更新时间提前一个轮询间隔后轮询，模拟私有频道没有推送
+func pollDue(tracker *orderTracker) {
+	tracker.mu.Lock()
+	for _, tracked := range tracker.orders {
+		tracked.lastSeen = time.Now().Add(-orderPollInterval)
+	}
+	tracker.mu.Unlock()
+	tracker.poll(context.Background())
+}
+
+func tradeOf(t *testing.T, db *database.Database) *dbmodels.Trade {
+	t.Helper()
+	trades, err := db.GetTrades(10)
+	if err != nil || len(trades) != 1 {
+		t.Fatalf("查询交易记录失败: %v, %d 笔", err, len(trades))
+	}
+	return trades[0]
+}
+
+func tracking(tracker *orderTracker) bool {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	_, ok := tracker.orders["o1"]
+	return ok
+}
+
+func TestOrderTrackerPartialFill(t *testing.T) {
+	stub := &stubOrders{
+		results: []stubResult{
+			{order: order("partially_filled", "100", "1", "-0.0005")},
+			{order: order("filled", "101", "3", "-0.0015")},
+		},
+		// 成交明细与订单汇总一致时以明细计算均价和手续费
+		fills: []*api.Fill{fill("t1", "100", "1", "-0.0005"), fill("t2", "101.5", "2", "-0.001")},
+	}
+	tracker, db, calls := newTestTracker(t, stub)
+
+	pollDue(tracker)
+	trade := tradeOf(t, db)
+	if trade.Status != "partially_filled" || trade.FillAmount != 0.01 || !tracking(tracker) {
+		t.Fatalf("部分成交后应更新成交数量并继续跟踪: %+v", trade)
+	}
+	if len(*calls) != 0 {
+		t.Fatal("部分成交时不应回调onFilled")
+	}
+
+	pollDue(tracker)
+	trade = tradeOf(t, db)
+	avgPx := (100 + 101.5*2) / 3
+	if trade.Status != "filled" || math.Abs(trade.FillPrice-avgPx) > 1e-9 || math.Abs(trade.FillAmount-0.03) > 1e-12 ||
+		math.Abs(trade.Fee+0.0015) > 1e-12 || trade.FeeCcy != "USDT" {
+		t.Fatalf("完全成交后的交易记录 = %+v", trade)
+	}
+	if tracking(tracker) {
+		t.Fatal("终态订单应停止跟踪")
+	}
+	if len(*calls) != 1 || (*calls)[0].fillSz != 3 || math.Abs((*calls)[0].avgPx-avgPx) > 1e-9 {
+		t.Fatalf("onFilled回调 = %+v", *calls)
+	}
+	if fills, _ := db.GetFillsByOrder("o1"); len(fills) != 2 {
+		t.Fatalf("成交明细 %d 笔, 期望 2 笔", len(fills))
+	}
+}
+
+func TestOrderTrackerCancel(t *testing.T) {
+	tests := []struct {
+		name       string
+		order      *api.Order
+		fills      []*api.Fill
+		wantAmount float64
+		wantFilled int
+	}{
+		{"未成交撤单", order("canceled", "", "0", "0"), nil, 0, 0},
+		{"部分成交后撤单", order("canceled", "100", "2", "-0.001"),
+			[]*api.Fill{fill("t1", "100", "2", "-0.001")}, 0.02, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubOrders{results: []stubResult{{order: tt.order}}, fills: tt.fills}
+			tracker, db, calls := newTestTracker(t, stub)
+
+			pollDue(tracker)
+			trade := tradeOf(t, db)
+			if trade.Status != "canceled" || math.Abs(trade.FillAmount-tt.wantAmount) > 1e-12 {
+				t.Fatalf("撤单后的交易记录 = %+v", trade)
+			}
+			if tracking(tracker) {
+				t.Fatal("撤单后应停止跟踪")
+			}
+			if len(*calls) != tt.wantFilled {
+				t.Fatalf("onFilled回调 %d 次, 期望 %d 次", len(*calls), tt.wantFilled)
+			}
+		})
+	}
+}
+
+func TestOrderTrackerReconnectDuringPolling(t *testing.T) {
+	netErr := errors.New("dial tcp: connection refused")
+	stub := &stubOrders{
+		results: []stubResult{
+			{err: netErr},
+			{err: netErr},
+			{order: order("filled", "100", "1", "-0.0005")},
+		},
+	}
+	tracker, db, calls := newTestTracker(t, stub)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates := make(chan *api.Order)
+	done := make(chan struct{})
+	go func() {
+		tracker.run(ctx, updates)
+		close(done)
+	}()
+
+	// 私有频道推送部分成交后断开
+	updates <- order("partially_filled", "100", "0.5", "-0.00025")
+	updates <- order("partially_filled", "100", "0.5", "-0.00025") // 等待上一条处理完成
+	cancel()
+	<-done
+	if trade := tradeOf(t, db); trade.Status != "partially_filled" {
+		t.Fatalf("推送后的交易记录 = %+v", trade)
+	}
+
+	// 重连期间查询失败，未超过失败次数时继续跟踪
+	pollDue(tracker)
+	pollDue(tracker)
+	tracker.mu.Lock()
+	failures := tracker.orders["o1"].failures
+	tracker.mu.Unlock()
+	if failures != 2 || !tracking(tracker) {
+		t.Fatalf("查询失败 %d 次后应继续跟踪", failures)
+	}
+
+	// 网络恢复后轮询补上推送丢失的成交
+	pollDue(tracker)
+	if trade := tradeOf(t, db); trade.Status != "filled" || trade.FillAmount != 0.01 {
+		t.Fatalf("恢复后的交易记录 = %+v", trade)
+	}
+	if len(*calls) != 1 {
+		t.Fatalf("onFilled回调 %d 次, 期望 1 次", len(*calls))
+	}
+
+	// 重连后补推的旧状态不应再处理已终结的订单
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go tracker.run(ctx, updates)
+	updates <- order("partially_filled", "100", "0.5", "-0.00025")
+	updates <- order("partially_filled", "100", "0.5", "-0.00025")
+	if trade := tradeOf(t, db); trade.Status != "filled" {
+		t.Fatalf("终结后的旧推送不应覆盖交易记录: %+v", trade)
+	}
+	if stub.queries != 3 {
+		t.Fatalf("订单查询 %d 次, 期望 3 次", stub.queries)
+	}
+}
+
+func TestOrderTrackerGivesUpAfterMaxFailures(t *testing.T) {
+	stub := &stubOrders{results: []stubResult{{err: errors.New("i/o timeout")}}}
+	tracker, _, _ := newTestTracker(t, stub)
+
+	for i := 0; i < orderMaxFailures; i++ {
+		if !tracking(tracker) {
+			t.Fatalf("第%d次查询失败前已停止跟踪", i+1)
+		}
+		pollDue(tracker)
+	}
+	if tracking(tracker) {
+		t.Fatal("连续查询失败达到上限后应停止跟踪")
+	}
+}
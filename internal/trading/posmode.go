@@ -0,0 +1,66 @@
+package trading
+
+import (
+	"context"
+	"log"
+
+	"okxauto/internal/api"
+	"okxauto/internal/models"
+)
+
+// initPositionMode 读取账户持仓模式，配置了position_mode且与账户不一致时尝试切换。
+// 读取失败时按开平仓模式下单，与未支持买卖模式前的行为一致
+func (e *Engine) initPositionMode(ctx context.Context) {
+	e.posMode = api.PosModeLongShort
+	if e.config.TradeType != "futures" {
+		return
+	}
+
+	cfg, err := e.api.GetAccountConfig(ctx)
+	if err != nil {
+		log.Printf("获取账户配置失败，按开平仓模式下单: %v", err)
+		return
+	}
+	e.posMode = cfg.PosMode
+	log.Printf("账户持仓模式: %s (账户等级: %s)", cfg.PosMode, cfg.AcctLv)
+
+	want := e.config.PositionMode
+	if want == "" || want == cfg.PosMode {
+		return
+	}
+	if err := e.api.SetPositionMode(ctx, want); err != nil {
+		log.Printf("切换持仓模式为 %s 失败，继续使用 %s: %v", want, cfg.PosMode, err)
+		return
+	}
+	e.posMode = want
+	log.Printf("已切换持仓模式: %s -> %s", cfg.PosMode, want)
+}
+
+// PositionMode 返回当前使用的持仓模式
+func (e *Engine) PositionMode() string {
+	return e.posMode
+}
+
+// netMode 账户是否为买卖模式
+func (e *Engine) netMode() bool {
+	return e.posMode == api.PosModeNet
+}
+
+// orderPosSide 返回下单时的posSide参数，买卖模式下不填
+func (e *Engine) orderPosSide(direction string) string {
+	if e.netMode() {
+		return ""
+	}
+	return direction
+}
+
+// positionDirection 返回持仓方向long/short，买卖模式的net持仓按数量正负判断
+func positionDirection(pos *models.Position) string {
+	if pos.PosSide == "net" {
+		if pos.Position < 0 {
+			return "short"
+		}
+		return "long"
+	}
+	return pos.PosSide
+}
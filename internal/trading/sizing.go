@@ -0,0 +1,73 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"okxauto/internal/api"
+)
+
+// fitMaxSize 按交易所返回的最大可下单数量检查下单数量，该数量已考虑手续费、档位和已有持仓。
+// 超出时按clamp_max_size缩减到最大数量或拒绝；查询失败时不做限制，由后续的余额检查兜底
+func (e *Engine) fitMaxSize(ctx context.Context, inst *api.Instrument, side api.OrderSide, sz float64) (float64, error) {
+	maxSize, err := e.api.GetMaxSize(ctx, inst.InstId, e.config.MarginMode, e.config.Leverage, 0)
+	if err != nil {
+		log.Printf("[%s] 查询最大可下单数量失败，跳过检查: %v", inst.InstId, err)
+		return sz, nil
+	}
+
+	max := maxSize.Max(side)
+	if sz <= max {
+		log.Printf("[%s] 最大可下单数量: %g, 本次下单: %s", inst.InstId, max, inst.FormatSize(sz))
+		return sz, nil
+	}
+	if !e.config.ClampMaxSize {
+		return 0, fmt.Errorf("下单数量 %s 超过交易所最大可下单数量 %g", inst.FormatSize(sz), max)
+	}
+
+	clamped := inst.RoundSize(max)
+	if err := inst.ValidateSize(clamped); err != nil {
+		return 0, fmt.Errorf("最大可下单数量 %g 无法下单: %v", max, err)
+	}
+	log.Printf("[%s] 下单数量 %s 超过最大可下单数量 %g，缩减为 %s",
+		inst.InstId, inst.FormatSize(sz), max, inst.FormatSize(clamped))
+	return clamped, nil
+}
+
+// fitMarketSize 市价单数量不能超过产品的单笔最大数量maxMktSz，超出时按clamp_max_size缩减或拒绝
+func (e *Engine) fitMarketSize(inst *api.Instrument, sz, price float64) (float64, error) {
+	max := inst.MaxMarketSize(price)
+	if max <= 0 || sz <= max {
+		return sz, nil
+	}
+	if !e.config.ClampMaxSize {
+		return 0, fmt.Errorf("下单数量 %s 超过市价单最大数量 %s", inst.FormatSize(sz), inst.FormatSize(max))
+	}
+	if err := inst.ValidateSize(max); err != nil {
+		return 0, fmt.Errorf("市价单最大数量 %s 无法下单: %v", inst.FormatSize(max), err)
+	}
+	log.Printf("[%s] 下单数量 %s 超过市价单最大数量，缩减为 %s",
+		inst.InstId, inst.FormatSize(sz), inst.FormatSize(max))
+	return max, nil
+}
+
+// availableMargin 返回指定方向开仓可用的保证金，优先使用交易所的最大可用数量，
+// 查询失败时使用账户中该币种的可用保证金
+func (e *Engine) availableMargin(ctx context.Context, inst *api.Instrument, side api.OrderSide, summary *api.AccountSummary) (float64, error) {
+	avail, err := e.api.GetMaxAvailSize(ctx, inst.InstId, e.config.MarginMode, false)
+	if err == nil {
+		return avail.Avail(side), nil
+	}
+	log.Printf("[%s] 查询最大可用保证金失败，使用账户余额: %v", inst.InstId, err)
+
+	ccy := inst.SettleCcy
+	if ccy == "" {
+		ccy = "USDT"
+	}
+	detail := summary.Currency(ccy)
+	if detail == nil {
+		return 0, fmt.Errorf("未找到%s余额", ccy)
+	}
+	return detail.AvailEq, nil
+}
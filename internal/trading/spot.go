@@ -0,0 +1,178 @@
+package trading
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"okxauto/internal/api"
+	"okxauto/internal/models"
+	"okxauto/internal/types"
+)
+
+// spotTgtCcy 返回现货市价单的数量单位，未配置时按交易货币
+func (e *Engine) spotTgtCcy() string {
+	if e.config.SpotTgtCcy == api.TgtCcyQuote {
+		return api.TgtCcyQuote
+	}
+	return api.TgtCcyBase
+}
+
+// executeSpotSignal 现货下单：使用cash模式，不设置杠杆和持仓方向。spot_tgt_ccy为quote_ccy时信号数量为
+// 计价货币金额，否则为交易货币数量；买入检查计价货币余额，卖出检查交易货币余额
+func (e *Engine) executeSpotSignal(ctx context.Context, signal *types.Signal, inst *api.Instrument) error {
+	side := api.OrderSide(signal.Action)
+	price := inst.RoundPrice(signal.Price)
+	if price <= 0 {
+		return fmt.Errorf("信号价格无效: %g", signal.Price)
+	}
+
+	// base为交易货币数量，cost为计价货币金额，按信号价格互相换算
+	tgtCcy := e.spotTgtCcy()
+	var sz string
+	var base, cost float64
+	if tgtCcy == api.TgtCcyQuote {
+		sz = inst.FormatQuoteAmount(signal.Amount)
+		cost, _ = strconv.ParseFloat(sz, 64)
+		base = inst.RoundSize(cost / price)
+	} else {
+		base = inst.SizeFor(signal.Amount, price)
+		cost = base * price
+		sz = inst.FormatSize(base)
+	}
+	if err := inst.ValidateSize(base); err != nil {
+		log.Printf("[%s] 信号数量 %g 换算后无法下单: %v", signal.Symbol, signal.Amount, err)
+		return err
+	}
+	if fitted, err := e.fitMarketSize(inst, base, price); err != nil {
+		log.Printf("[%s] 下单失败: %v", signal.Symbol, err)
+		return err
+	} else if fitted != base {
+		base, cost = fitted, fitted*price
+		if tgtCcy == api.TgtCcyQuote {
+			sz = inst.FormatQuoteAmount(cost)
+			cost, _ = strconv.ParseFloat(sz, 64)
+		} else {
+			sz = inst.FormatSize(base)
+		}
+	}
+	log.Printf("[%s] 现货下单数量: %s (%s), 约 %s %s / %.4f %s",
+		signal.Symbol, sz, tgtCcy, inst.FormatSize(base), inst.BaseCcy, cost, inst.QuoteCcy)
+
+	if err := e.checkSlippage(signal.Symbol, side, base); err != nil {
+		log.Printf("[%s] 下单失败: %v", signal.Symbol, err)
+		return err
+	}
+
+	if err := e.checkSpotBalance(ctx, inst, side, base, cost); err != nil {
+		log.Printf("[%s] 下单失败: %v", signal.Symbol, err)
+		return err
+	}
+
+	orderReq := &api.PlaceOrderRequest{
+		InstId:  signal.Symbol,
+		TdMode:  api.TdModeCash,
+		Side:    side,
+		OrdType: api.Market,
+		Sz:      sz,
+		TgtCcy:  tgtCcy, // 市价买单默认按计价货币，需明确指定
+	}
+	return e.submitOrder(ctx, signal, orderReq, price)
+}
+
+// checkSpotBalance 买入检查计价货币、卖出检查交易货币的可用余额，计价货币为USDT时扣除预留余额；
+// 卖出数量不能超过程序自己买入并持有的数量，避免卖出账户中原有的交易货币
+func (e *Engine) checkSpotBalance(ctx context.Context, inst *api.Instrument, side api.OrderSide, base, cost float64) error {
+	if side == api.Sell {
+		held, _, err := e.db.SpotHolding(inst.InstId, inst.BaseCcy)
+		if err != nil {
+			return err
+		}
+		if base > inst.RoundSize(held)+1e-12 {
+			return fmt.Errorf("程序持有的%s不足: 需要 %s, 持有 %s", inst.BaseCcy, inst.FormatSize(base), inst.FormatSize(held))
+		}
+	}
+
+	summary, err := e.getAccountSummary(ctx)
+	if err != nil {
+		return fmt.Errorf("获取余额失败: %v", err)
+	}
+
+	ccy, required := inst.QuoteCcy, cost
+	if side == api.Sell {
+		ccy, required = inst.BaseCcy, base
+	}
+	var balance, reserve float64
+	if d := summary.Currency(ccy); d != nil {
+		balance = d.AvailBal
+	}
+	if ccy == "USDT" {
+		reserve = e.config.ReserveBalance
+	}
+
+	if balance-reserve < required {
+		return fmt.Errorf("可用%s余额不足: 需要 %.4f, 实际可用 %.4f (总余额: %.4f, 预留: %.2f)",
+			ccy, required, balance-reserve, balance, reserve)
+	}
+	log.Printf("[%s] %s余额充足: 需要 %.4f, 实际可用 %.4f (总余额: %.4f, 预留: %.2f)",
+		inst.InstId, ccy, required, balance-reserve, balance, reserve)
+	return nil
+}
+
+// spotPositions 由程序自己的现货成交生成持仓，方向固定为多头。数量为已成交的买单(扣除以交易货币收取的
+// 手续费)减去已成交的卖单，不包括账户中原有或手动买入的交易货币，止盈止损平仓只卖出这部分；
+// 交易货币被手动卖出或划走时按可用余额平仓。均价为买入成本均价，收益率按最新价格计算；
+// 数量低于最小下单数量时视为无持仓
+func (e *Engine) spotPositions(ctx context.Context, symbol string) ([]*models.Position, error) {
+	inst, err := e.api.GetInstrument(ctx, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取产品信息失败: %v", err)
+	}
+	qty, avgPx, err := e.db.SpotHolding(symbol, inst.BaseCcy)
+	if err != nil {
+		return nil, err
+	}
+	qty = inst.RoundSize(qty)
+	if qty <= 0 || qty < inst.MinSz {
+		return nil, nil
+	}
+
+	summary, err := e.getAccountSummary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var avail float64
+	if d := summary.Currency(inst.BaseCcy); d != nil {
+		avail = d.AvailBal
+	}
+	if avail < qty {
+		log.Printf("[%s] 程序买入的%s数量 %s 超过可用余额 %g，按可用余额计算持仓",
+			symbol, inst.BaseCcy, inst.FormatSize(qty), avail)
+		qty = inst.RoundSize(avail)
+		if qty <= 0 || qty < inst.MinSz {
+			return nil, nil
+		}
+	}
+
+	candles, err := e.api.GetKlines(ctx, symbol, "1m", 1)
+	if err != nil {
+		return nil, fmt.Errorf("获取最新价格失败: %v", err)
+	}
+	if len(candles) == 0 {
+		return nil, fmt.Errorf("未获取到%s的K线数据", symbol)
+	}
+	price, _ := strconv.ParseFloat(candles[0].Close, 64)
+
+	pos := &models.Position{
+		Symbol:    symbol,
+		PosSide:   "long",
+		Position:  qty,
+		AvgPrice:  avgPx,
+		UnrealPnL: (price - avgPx) * qty,
+	}
+	if avgPx > 0 {
+		pos.PnLRatio = (price - avgPx) / avgPx
+	}
+	return []*models.Position{pos}, nil
+}
@@ -0,0 +1,83 @@
+package trading
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"okxauto/internal/okxsim"
+	"okxauto/internal/types"
+)
+
+func TestSpotEntryAndCloseAgainstSim(t *testing.T) {
+	sim := okxsim.New(10000)
+	sim.AddInstrument("BTC-USDT", 30000, 0)
+	sim.SetBalance("BTC", 1) // 账户中原有的交易货币，不属于程序的持仓
+
+	var config Config
+	config.TradeType = "spot"
+	config.Leverage = 1
+	config.Symbols = []string{"BTC-USDT"}
+	config.LongPosition.TakeProfit = 0.01
+	config.LongPosition.StopLoss = 0.01
+	e := newSimEngine(t, sim, config)
+	ctx := context.Background()
+
+	inst, err := e.api.GetInstrument(ctx, "BTC-USDT")
+	if err != nil {
+		t.Fatalf("获取产品信息失败: %v", err)
+	}
+	if !inst.IsSpot() {
+		t.Fatalf("产品类型 = %s", inst.InstType)
+	}
+
+	// 开仓: 买入0.1 BTC，手续费以BTC收取
+	buy := &types.Signal{Symbol: "BTC-USDT", Strategy: "LongPosition", Action: "buy", Price: 30000, Amount: 0.1}
+	if err := e.executeSpotSignal(ctx, buy, inst); err != nil {
+		t.Fatalf("现货买入失败: %v", err)
+	}
+	settle(t, e)
+
+	positions, err := e.getPositions(ctx, "BTC-USDT")
+	if err != nil || len(positions) != 1 {
+		t.Fatalf("获取持仓失败: %v, %+v", err, positions)
+	}
+	if pos := positions[0]; pos.Position != 0.0999 || pos.PosSide != "long" || math.Abs(pos.AvgPrice-30000*0.1/0.09995) > 1e-6 {
+		t.Fatalf("持仓应只包括程序买入并扣除手续费后的数量: %+v", pos)
+	}
+
+	// 超过程序持有数量的卖出信号应被拒绝，不能卖出账户中原有的BTC
+	sell := &types.Signal{Symbol: "BTC-USDT", Strategy: "rsi", Action: "sell", Price: 30000, Amount: 0.5}
+	if err := e.executeSpotSignal(ctx, sell, inst); err == nil || !strings.Contains(err.Error(), "程序持有的BTC不足") {
+		t.Fatalf("超过持有数量的卖出应被拒绝, 实际: %v", err)
+	}
+
+	// 价格上涨2%触发止盈，只卖出程序持有的数量
+	sim.SetPrice("BTC-USDT", 30600)
+	if err := e.checkPositionPnL(ctx, "BTC-USDT"); err != nil {
+		t.Fatalf("检查止盈止损失败: %v", err)
+	}
+	settle(t, e)
+
+	trades := tradesOf(t, e)
+	if len(trades) != 2 {
+		t.Fatalf("交易记录 %d 笔, 期望开仓和平仓各1笔", len(trades))
+	}
+	closeTrade := trades[1]
+	if closeTrade.Strategy != "CloseLong" || closeTrade.Side != "sell" || closeTrade.Status != "filled" ||
+		closeTrade.FillAmount != 0.0999 || closeTrade.Fee >= 0 || closeTrade.FeeCcy != "USDT" {
+		t.Fatalf("平仓交易记录 = %+v", closeTrade)
+	}
+
+	summary, err := e.api.GetAccountSummary(ctx)
+	if err != nil {
+		t.Fatalf("获取余额失败: %v", err)
+	}
+	if btc := summary.Currency("BTC").AvailBal; math.Abs(btc-1.00005) > 1e-9 {
+		t.Fatalf("平仓后BTC余额 = %v, 原有的1 BTC不应被卖出", btc)
+	}
+	if positions, err := e.getPositions(ctx, "BTC-USDT"); err != nil || len(positions) != 0 {
+		t.Fatalf("平仓后剩余不足最小下单数量的手续费零头应视为无持仓: %v, %+v", err, positions)
+	}
+}
@@ -1,6 +1,7 @@
 package strategies
 
 import (
+	"context"
 	"log"
 	"sync"
 	"time"
@@ -10,7 +11,7 @@ import (
 )
 
 type GridStrategy struct {
-	api         *api.OKXClient
+	api         api.Exchange
 	symbol      string
 	config      GridConfig
 	gridLevels []float64
@@ -26,7 +27,7 @@ type GridConfig struct {
 	TotalAmount float64 `yaml:"total_amount"`
 }
 
-func NewGridStrategy(api *api.OKXClient, symbol string, config GridConfig) *GridStrategy {
+func NewGridStrategy(api api.Exchange, symbol string, config GridConfig) *GridStrategy {
 	return &GridStrategy{
 		api:        api,
 		symbol:     symbol,
@@ -39,7 +40,7 @@ func (s *GridStrategy) Name() string {
 	return "Grid"
 }
 
-func (s *GridStrategy) Initialize() error {
+func (s *GridStrategy) Initialize(ctx context.Context) error {
 	s.gridLevels = s.calculateGridLevels()
 	return nil
 }
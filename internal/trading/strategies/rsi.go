@@ -1,6 +1,7 @@
 package strategies
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math"
@@ -9,19 +10,31 @@ import (
 	"time"
 
 	"okxauto/internal/api"
+	dbmodels "okxauto/internal/database/models"
 	"okxauto/internal/types"
 )
 
 type RSIStrategy struct {
-	api         *api.OKXClient
+	api         api.Exchange
 	symbol      string
 	config      RSIConfig
 	prices      []float64
 	lastRSI     float64    // 记录上一次的RSI值
 	signalCount int        // 信号计数器
+	candles     CandleSource
 	mu          sync.RWMutex
 }
 
+// CandleSource 带缓存的历史K线来源，按时间升序返回最近n根已完结K线
+type CandleSource interface {
+	Recent(ctx context.Context, symbol, bar string, n int) ([]*dbmodels.Candle, error)
+}
+
+// SetCandleSource 设置历史K线来源，设置后预热优先从本地缓存读取
+func (s *RSIStrategy) SetCandleSource(source CandleSource) {
+	s.candles = source
+}
+
 type RSIConfig struct {
 	Enabled             bool    `yaml:"enabled"`
 	Period              int     `yaml:"period"`              // RSI周期
@@ -31,7 +44,7 @@ type RSIConfig struct {
 	MinChange           float64 `yaml:"min_change"`           // 最小变化幅度
 }
 
-func NewRSIStrategy(api *api.OKXClient, symbol string, config RSIConfig) *RSIStrategy {
+func NewRSIStrategy(api api.Exchange, symbol string, config RSIConfig) *RSIStrategy {
 	return &RSIStrategy{
 		api:     api,
 		symbol:  symbol,
@@ -45,7 +58,7 @@ func (s *RSIStrategy) Name() string {
 }
 
 // Initialize 初始化策略,获取足够的历史数据
-func (s *RSIStrategy) Initialize() error {
+func (s *RSIStrategy) Initialize(ctx context.Context) error {
 	log.Printf("[RSI-%s] 开始初始化策略...", s.symbol)
 	
 	// 获取足够的历史数据用于计算RSI
@@ -53,26 +66,16 @@ func (s *RSIStrategy) Initialize() error {
 	
 	// 使用K线数据API获取历史数据
 	// 注意：这里不再需要endTime变量，因为我们默认获取最新的数据
-	candles, err := s.api.GetKlines(s.symbol, "1m", lookback)
+	closes, err := s.warmupCloses(ctx, lookback)
 	if err != nil {
-		return fmt.Errorf("获取K线数据失败: %v", err)
+		return err
 	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// 清空旧数据
-	s.prices = s.prices[:0]
-	
-	// 处理K线数据
-	for _, candle := range candles {
-		price, err := strconv.ParseFloat(candle.Close, 64)
-		if err != nil {
-			log.Printf("[RSI-%s] 解析价格数据失败: %v", s.symbol, err)
-			continue
-		}
-		s.prices = append(s.prices, price)
-	}
+	s.prices = append(s.prices[:0], closes...)
 
 	// 计算初始RSI值
 	if len(s.prices) >= s.config.Period {
@@ -84,13 +87,42 @@ func (s *RSIStrategy) Initialize() error {
 	return nil
 }
 
+// warmupCloses 获取预热用的收盘价，优先读取K线缓存，缓存不可用时直接请求交易所
+func (s *RSIStrategy) warmupCloses(ctx context.Context, n int) ([]float64, error) {
+	if s.candles != nil {
+		candles, err := s.candles.Recent(ctx, s.symbol, "1m", n)
+		if err == nil {
+			closes := make([]float64, 0, len(candles))
+			for _, candle := range candles {
+				closes = append(closes, candle.Close)
+			}
+			return closes, nil
+		}
+		log.Printf("[RSI-%s] 从K线缓存预热失败，直接请求交易所: %v", s.symbol, err)
+	}
+
+	candles, err := s.api.GetKlines(ctx, s.symbol, "1m", n)
+	if err != nil {
+		return nil, fmt.Errorf("获取K线数据失败: %v", err)
+	}
+
+	closes := make([]float64, 0, len(candles))
+	for _, candle := range candles {
+		price, err := strconv.ParseFloat(candle.Close, 64)
+		if err != nil {
+			log.Printf("[RSI-%s] 解析价格数据失败: %v", s.symbol, err)
+			continue
+		}
+		closes = append(closes, price)
+	}
+	return closes, nil
+}
+
 // ProcessTick 处理新的价格数据
 func (s *RSIStrategy) ProcessTick(tick *types.Tick) (*types.Signal, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	log.Printf("[RSI-%s] 当前价格: %.2f", s.symbol, tick.Price)
-
 	// 更新价格数据
 	s.prices = append(s.prices, tick.Price)
 	if len(s.prices) > s.config.Period*3 {
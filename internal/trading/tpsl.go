@@ -0,0 +1,125 @@
+package trading
+
+import (
+	"context"
+	"log"
+
+	"okxauto/internal/api"
+)
+
+// attachTPSL 开仓订单成交后在交易所挂止盈止损委托，程序退出或断网时持仓仍受保护。
+// 止盈止损率与checkPositionPnL一致按保证金收益率计算，换算为价格需除以杠杆倍数；
+// 开启net_of_fee_pnl时先换算为扣除开平仓手续费前的毛收益率
+func (e *Engine) attachTPSL(ctx context.Context, tracked *trackedOrder, avgPx, fillSz float64) {
+	if e.config.TradeType != "futures" {
+		return
+	}
+
+	var takeProfit, stopLoss, direction float64
+	var posSide string
+	var side api.OrderSide
+	switch tracked.strategy {
+	case "LongPosition":
+		if !e.config.LongPosition.ExchangeTPSL {
+			return
+		}
+		takeProfit, stopLoss = e.config.LongPosition.TakeProfit, e.config.LongPosition.StopLoss
+		direction, posSide, side = 1, "long", api.Sell
+	case "ShortPosition":
+		if !e.config.ShortPosition.ExchangeTPSL {
+			return
+		}
+		takeProfit, stopLoss = e.config.ShortPosition.TakeProfit, e.config.ShortPosition.StopLoss
+		direction, posSide, side = -1, "short", api.Buy
+	default:
+		return
+	}
+
+	inst, err := e.api.GetInstrument(ctx, tracked.symbol)
+	if err != nil {
+		log.Printf("[%s] 挂止盈止损失败，获取产品信息失败: %v", tracked.symbol, err)
+		return
+	}
+	if !inst.IsDerivative() {
+		return
+	}
+
+	leverage := e.leverage()
+	if e.config.NetOfFeePnL {
+		taker := e.takerRate(ctx, tracked.symbol)
+		if takeProfit > 0 {
+			takeProfit = grossRatio(takeProfit, direction, taker, leverage)
+		}
+		if stopLoss > 0 {
+			// 净亏损包含手续费，对应的毛亏损更小；手续费已超过止损率时无法满足，保持原止损率
+			if gross := -grossRatio(-stopLoss, direction, taker, leverage); gross > 0 {
+				stopLoss = gross
+			} else {
+				log.Printf("[%s] 开平仓手续费已超过止损率 %.2f%%，按毛收益率止损", tracked.symbol, stopLoss*100)
+			}
+		}
+	}
+
+	req := &api.AlgoOrderRequest{
+		InstId:      tracked.symbol,
+		TdMode:      e.config.MarginMode,
+		Side:        side,
+		PosSide:     e.orderPosSide(posSide),
+		Sz:          inst.FormatSize(fillSz),
+		ReduceOnly:  e.netMode(),
+		AlgoClOrdId: "tpsl" + tracked.ordId,
+	}
+	if takeProfit > 0 {
+		req.TpTriggerPx = inst.FormatPrice(avgPx * (1 + direction*takeProfit/leverage))
+		req.TpTriggerPxType = "mark"
+		req.TpOrdPx = "-1"
+	}
+	// 止损价格需为正数，空头无此限制
+	if stopLoss > 0 && (direction < 0 || stopLoss < leverage) {
+		req.SlTriggerPx = inst.FormatPrice(avgPx * (1 - direction*stopLoss/leverage))
+		req.SlTriggerPxType = "mark"
+		req.SlOrdPx = "-1"
+	}
+
+	switch {
+	case req.TpTriggerPx != "" && req.SlTriggerPx != "":
+		req.OrdType = api.AlgoOCO
+	case req.TpTriggerPx != "" || req.SlTriggerPx != "":
+		req.OrdType = api.AlgoConditional
+	default:
+		return
+	}
+
+	resp, err := e.api.PlaceAlgoOrder(ctx, req)
+	if err != nil {
+		log.Printf("[%s] 挂止盈止损失败: %v", tracked.symbol, err)
+		return
+	}
+	log.Printf("[%s] 已挂交易所止盈止损 - AlgoID: %s, 方向: %s, 数量: %s, 止盈触发价: %s, 止损触发价: %s",
+		tracked.symbol, resp.AlgoId, posSide, req.Sz, req.TpTriggerPx, req.SlTriggerPx)
+}
+
+// cancelTPSL 撤销指定持仓方向的止盈止损委托，用于程序主动平仓后清理。
+// 买卖模式下每个合约只有一个net持仓，撤销该合约的全部委托
+func (e *Engine) cancelTPSL(ctx context.Context, symbol, posSide string) {
+	if e.netMode() {
+		posSide = "net"
+	}
+	for _, ordType := range []string{api.AlgoOCO, api.AlgoConditional} {
+		algos, err := e.api.GetAlgoOrders(ctx, ordType, symbol)
+		if err != nil {
+			log.Printf("[%s] 获取止盈止损委托失败: %v", symbol, err)
+			continue
+		}
+		for _, algo := range algos {
+			if algo.PosSide != posSide {
+				continue
+			}
+			if err := e.api.CancelAlgoOrder(ctx, symbol, algo.AlgoId); err != nil {
+				log.Printf("[%s] 撤销止盈止损委托 %s 失败: %v", symbol, algo.AlgoId, err)
+				continue
+			}
+			log.Printf("[%s] 已撤销止盈止损委托 %s", symbol, algo.AlgoId)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"okxauto/internal/api"
+	"okxauto/internal/models"
+	"okxauto/internal/okxsim"
+	"okxauto/internal/types"
+)
+
+// newTPSLEngine 返回连接到模拟服务器的合约引擎，多空均开启交易所止盈止损：
+// 10倍杠杆下止盈10%、止损5%对应价格变动1%和0.5%
+func newTPSLEngine(t *testing.T, posMode string) (*okxsim.Server, *Engine) {
+	t.Helper()
+	sim := okxsim.New(10000)
+	sim.AddInstrument("BTC-USDT-SWAP", 30000, 0.01)
+	sim.SetPositionMode(posMode)
+
+	var config Config
+	config.TradeType = "futures"
+	config.Leverage = 10
+	config.MarginMode = "cross"
+	config.Symbols = []string{"BTC-USDT-SWAP"}
+	config.LongPosition.TakeProfit, config.LongPosition.StopLoss = 0.1, 0.05
+	config.LongPosition.ExchangeTPSL = true
+	config.ShortPosition.TakeProfit, config.ShortPosition.StopLoss = 0.1, 0.05
+	config.ShortPosition.ExchangeTPSL = true
+	e := newSimEngine(t, sim, config)
+	e.initPositionMode(context.Background())
+	return sim, e
+}
+
+// openPosition 按策略开仓0.1 BTC(10张)并等待成交，返回开仓后的持仓
+func openPosition(t *testing.T, e *Engine, strategy, action string) *models.Position {
+	t.Helper()
+	ctx := context.Background()
+	signal := &types.Signal{Symbol: "BTC-USDT-SWAP", Strategy: strategy, Action: action, Price: 30000, Amount: 0.1}
+	if err := e.executeSignal(ctx, signal); err != nil {
+		t.Fatalf("开仓失败: %v", err)
+	}
+	settle(t, e)
+	positions, err := e.getPositions(ctx, "BTC-USDT-SWAP")
+	if err != nil || len(positions) != 1 {
+		t.Fatalf("开仓后持仓: %v, %+v", err, positions)
+	}
+	return positions[0]
+}
+
+func pendingAlgos(t *testing.T, e *Engine) []*api.AlgoOrder {
+	t.Helper()
+	algos, err := e.api.GetAlgoOrders(context.Background(), api.AlgoOCO, "BTC-USDT-SWAP")
+	if err != nil {
+		t.Fatalf("获取止盈止损委托失败: %v", err)
+	}
+	return algos
+}
+
+func TestAttachTPSLOnEntry(t *testing.T) {
+	tests := []struct {
+		name      string
+		posMode   string
+		strategy  string
+		action    string
+		wantSide  string
+		wantPos   string
+		wantTp    string
+		wantSl    string
+		wantAlgos int
+	}{
+		{"开平仓模式多头", api.PosModeLongShort, "LongPosition", "buy", "sell", "long", "30300", "29850", 1},
+		{"开平仓模式空头", api.PosModeLongShort, "ShortPosition", "sell", "buy", "short", "29700", "30150", 1},
+		{"买卖模式多头", api.PosModeNet, "LongPosition", "buy", "sell", "net", "30300", "29850", 1},
+		// 其他策略的开仓不挂止盈止损
+		{"非止盈止损策略", api.PosModeLongShort, "rsi", "buy", "", "", "", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, e := newTPSLEngine(t, tt.posMode)
+			openPosition(t, e, tt.strategy, tt.action)
+
+			algos := pendingAlgos(t, e)
+			if len(algos) != tt.wantAlgos {
+				t.Fatalf("止盈止损委托 %d 个, 期望 %d 个", len(algos), tt.wantAlgos)
+			}
+			if tt.wantAlgos == 0 {
+				return
+			}
+			a := algos[0]
+			got := []string{a.Side, a.PosSide, a.Sz, a.TpTriggerPx, a.SlTriggerPx}
+			want := []string{tt.wantSide, tt.wantPos, "10", tt.wantTp, tt.wantSl}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("止盈止损委托 [side posSide sz tp sl] = %v, 期望 %v", got, want)
+			}
+			if a.AlgoClOrdId != "tpsl"+tradesOf(t, e)[0].OrderID {
+				t.Fatalf("委托的algoClOrdId = %s", a.AlgoClOrdId)
+			}
+		})
+	}
+}
+
+func TestCancelTPSLOnClose(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		action   string
+	}{
+		{"平多", "LongPosition", "buy"},
+		{"平空", "ShortPosition", "sell"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, e := newTPSLEngine(t, api.PosModeLongShort)
+			ctx := context.Background()
+			pos := openPosition(t, e, tt.strategy, tt.action)
+			if len(pendingAlgos(t, e)) != 1 {
+				t.Fatal("开仓后应挂止盈止损委托")
+			}
+
+			var err error
+			if tt.action == "buy" {
+				err = e.closeLongPosition(ctx, "BTC-USDT-SWAP", pos)
+			} else {
+				err = e.closeShortPosition(ctx, "BTC-USDT-SWAP", pos)
+			}
+			if err != nil {
+				t.Fatalf("平仓失败: %v", err)
+			}
+			settle(t, e)
+
+			if algos := pendingAlgos(t, e); len(algos) != 0 {
+				t.Fatalf("程序平仓后应撤销止盈止损委托, 仍有 %d 个", len(algos))
+			}
+			if positions, _ := e.getPositions(ctx, "BTC-USDT-SWAP"); len(positions) != 0 {
+				t.Fatalf("平仓后仍有持仓: %+v", positions)
+			}
+			// 平仓单不挂止盈止损
+			if trades := tradesOf(t, e); len(trades) != 2 || trades[1].Status != "filled" {
+				t.Fatalf("交易记录 = %+v", trades)
+			}
+		})
+	}
+}
+
+func TestCloseAfterTPSLTriggered(t *testing.T) {
+	sim, e := newTPSLEngine(t, api.PosModeLongShort)
+	ctx := context.Background()
+	pos := openPosition(t, e, "LongPosition", "buy")
+
+	// 价格达到止盈触发价，交易所已平仓，程序用检查前的持仓平仓
+	sim.SetPrice("BTC-USDT-SWAP", 30300)
+	if positions, _ := e.getPositions(ctx, "BTC-USDT-SWAP"); len(positions) != 0 {
+		t.Fatalf("止盈触发后交易所应已平仓: %+v", positions)
+	}
+	if err := e.closeLongPosition(ctx, "BTC-USDT-SWAP", pos); err != nil {
+		t.Fatalf("持仓已被止盈止损平仓时不应返回错误: %v", err)
+	}
+	if trades := tradesOf(t, e); len(trades) != 1 {
+		t.Fatalf("平仓失败时不应保存平仓记录, 实际 %d 笔", len(trades))
+	}
+	if algos := pendingAlgos(t, e); len(algos) != 0 {
+		t.Fatalf("已触发的委托不应再处于挂单状态: %+v", algos)
+	}
+}
+
+func TestCloseAfterTPSLCanceled(t *testing.T) {
+	_, e := newTPSLEngine(t, api.PosModeLongShort)
+	ctx := context.Background()
+	pos := openPosition(t, e, "LongPosition", "buy")
+
+	// 委托已在交易所被撤销(例如手动撤单)，程序平仓不受影响
+	algos := pendingAlgos(t, e)
+	if err := e.api.CancelAlgoOrder(ctx, "BTC-USDT-SWAP", algos[0].AlgoId); err != nil {
+		t.Fatalf("撤销委托失败: %v", err)
+	}
+	if err := e.closeLongPosition(ctx, "BTC-USDT-SWAP", pos); err != nil {
+		t.Fatalf("平仓失败: %v", err)
+	}
+	settle(t, e)
+	if positions, _ := e.getPositions(ctx, "BTC-USDT-SWAP"); len(positions) != 0 {
+		t.Fatalf("平仓后仍有持仓: %+v", positions)
+	}
+}
+
+// stubAlgos 返回预设的止盈止损委托，gone中的委托撤销时已触发或已撤销
+type stubAlgos struct {
+	api.Exchange
+	algos    []*api.AlgoOrder
+	gone     map[string]bool
+	canceled []string
+}
+
+func (s *stubAlgos) GetAlgoOrders(ctx context.Context, ordType, instId string) ([]*api.AlgoOrder, error) {
+	var algos []*api.AlgoOrder
+	for _, a := range s.algos {
+		if a.OrdType == ordType {
+			algos = append(algos, a)
+		}
+	}
+	return algos, nil
+}
+
+func (s *stubAlgos) CancelAlgoOrder(ctx context.Context, instId, algoId string) error {
+	if s.gone[algoId] {
+		return errors.New("Cancellation failed as the order does not exist.")
+	}
+	s.canceled = append(s.canceled, algoId)
+	return nil
+}
+
+func TestCancelTPSL(t *testing.T) {
+	algo := func(id, ordType, posSide string) *api.AlgoOrder {
+		return &api.AlgoOrder{AlgoId: id, OrdType: ordType, PosSide: posSide}
+	}
+	tests := []struct {
+		name    string
+		posMode string
+		posSide string
+		algos   []*api.AlgoOrder
+		gone    []string
+		want    []string
+	}{
+		{
+			name: "只撤销指定方向", posMode: api.PosModeLongShort, posSide: "long",
+			algos: []*api.AlgoOrder{algo("a1", api.AlgoOCO, "long"), algo("a2", api.AlgoOCO, "short"), algo("a3", api.AlgoConditional, "long")},
+			want:  []string{"a1", "a3"},
+		},
+		{
+			name: "买卖模式撤销net委托", posMode: api.PosModeNet, posSide: "short",
+			algos: []*api.AlgoOrder{algo("a1", api.AlgoOCO, "net"), algo("a2", api.AlgoConditional, "net")},
+			want:  []string{"a1", "a2"},
+		},
+		{
+			// 列出委托后、撤销前已触发的委托撤销失败，继续撤销其余委托
+			name: "委托已触发", posMode: api.PosModeLongShort, posSide: "long",
+			algos: []*api.AlgoOrder{algo("a1", api.AlgoOCO, "long"), algo("a2", api.AlgoOCO, "long")},
+			gone:  []string{"a1"},
+			want:  []string{"a2"},
+		},
+		{
+			name: "没有委托", posMode: api.PosModeLongShort, posSide: "long",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubAlgos{algos: tt.algos, gone: make(map[string]bool)}
+			for _, id := range tt.gone {
+				stub.gone[id] = true
+			}
+			e := &Engine{api: stub, config: &Config{}, posMode: tt.posMode}
+
+			e.cancelTPSL(context.Background(), "BTC-USDT-SWAP", tt.posSide)
+			if !reflect.DeepEqual(stub.canceled, tt.want) {
+				t.Fatalf("撤销的委托 = %v, 期望 %v", stub.canceled, tt.want)
+			}
+		})
+	}
+}
@@ -1,13 +1,16 @@
 package trading
 
 import (
+	"context"
+	"time"
+
 	"okxauto/internal/types"
 )
 
 // 策略接口
 type Strategy interface {
 	Name() string
-	Initialize() error
+	Initialize(ctx context.Context) error
 	ProcessTick(tick *types.Tick) (*types.Signal, error)
 	Stop()
 }
@@ -38,12 +41,28 @@ type StrategyConfig struct {
 
 // Config 定义交易引擎配置
 type Config struct {
+	Account        string   `yaml:"-"`           // 账户名称，多账户时区分交易记录和日志
 	Mode           string   `yaml:"mode"`        // simulation or live
 	TradeType      string   `yaml:"trade_type"`  // spot或futures
 	Leverage       int      `yaml:"leverage"`    // 合约杠杆倍数
 	MarginMode     string   `yaml:"margin_mode"` // 合约保证金模式
+	PositionMode   string   `yaml:"position_mode"` // 持仓模式net_mode/long_short_mode，为空时沿用账户设置
 	ReserveBalance float64  `yaml:"reserve_balance"` // 添加预留余额字段
 	Symbols        []string `yaml:"symbols"`
+	MaxSlippage    float64  `yaml:"max_slippage"` // 按深度估算的市价单最大滑点比例，0表示不限制
+	ClampMaxSize   bool     `yaml:"clamp_max_size"` // 超过交易所最大可下单数量时缩减到最大数量，否则拒绝下单
+	NetOfFeePnL    bool     `yaml:"net_of_fee_pnl"` // 止盈止损按扣除开平仓手续费后的收益率判断
+	BillsSyncInterval time.Duration `yaml:"bills_sync_interval"` // 账单同步间隔，为0时使用默认的10分钟
+	SpotTgtCcy     string   `yaml:"spot_tgt_ccy"` // 现货下单数量单位: base_ccy交易货币(默认)/quote_ccy计价货币
+
+	// 资金账户与交易账户之间的自动划转
+	FundTransfer struct {
+		Enabled    bool          `yaml:"enabled"`
+		Ccy        string        `yaml:"ccy"`         // 划转币种，默认USDT
+		MinBalance float64       `yaml:"min_balance"` // 交易账户可用余额低于该值时从资金账户补足，0表示只在追加保证金时补充
+		MaxBalance float64       `yaml:"max_balance"` // 交易账户可用余额超过该值的部分划到资金账户，0表示不归集
+		Interval   time.Duration `yaml:"interval"`    // 检查间隔，默认1分钟
+	} `yaml:"fund_transfer"`
 
 	// 添加做多配置
 	LongPosition struct {
@@ -54,7 +73,8 @@ type Config struct {
 		} `yaml:"entry_range"`
 		TakeProfit   float64 `yaml:"take_profit"`
 		StopLoss     float64 `yaml:"stop_loss"`
-		PositionSize int     `yaml:"position_size"`
+		ExchangeTPSL bool    `yaml:"exchange_tpsl"` // 开仓成交后在交易所挂止盈止损委托
+		PositionSize float64 `yaml:"position_size"`
 		MarginRatio  float64 `yaml:"margin_ratio"`
 		AutoMargin   bool    `yaml:"auto_margin"`
 		MarginAmount float64 `yaml:"margin_amount"`
@@ -70,7 +90,8 @@ type Config struct {
 		} `yaml:"entry_range"`
 		TakeProfit   float64 `yaml:"take_profit"`
 		StopLoss     float64 `yaml:"stop_loss"`
-		PositionSize int     `yaml:"position_size"`
+		ExchangeTPSL bool    `yaml:"exchange_tpsl"` // 开仓成交后在交易所挂止盈止损委托
+		PositionSize float64 `yaml:"position_size"`
 		MarginRatio  float64 `yaml:"margin_ratio"`
 		AutoMargin   bool    `yaml:"auto_margin"`
 		MarginAmount float64 `yaml:"margin_amount"`
@@ -1,5 +1,7 @@
 package types
 
+import "context"
+
 // 交易信号
 type Signal struct {
 	Symbol    string
@@ -16,12 +18,29 @@ type Tick struct {
 	Price     float64
 	Volume    float64
 	Timestamp int64
+	Source    string // 数据来源: tickers/trades/candle1m/rest
+
+	// 永续合约的资金费率、标记价格等数据，现货或尚未获取到时为nil
+	Futures *FuturesData
+}
+
+// 合约行情数据，时间均为Unix秒
+type FuturesData struct {
+	FundingRate     float64
+	NextFundingRate float64
+	FundingTime     int64
+	NextFundingTime int64
+	MarkPrice       float64
+	IndexPrice      float64
+	OpenInterest    float64 // 持仓量(张)
+	OpenInterestCcy float64 // 持仓量(币)
+	UpdatedAt       int64
 }
 
 // 策略接口
 type Strategy interface {
 	Name() string
-	Initialize() error
+	Initialize(ctx context.Context) error
 	ProcessTick(tick *Tick) (*Signal, error)
 	Stop()
 } 
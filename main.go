@@ -11,11 +11,15 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"okxauto/internal/api"
 	"okxauto/internal/api/config"
@@ -28,61 +32,60 @@ func main() {
 	configFile := flag.String("config", "config/config.yaml", "配置文件路径")
 	flag.Parse()
 
+	if err := run(*configFile); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run 启动所有账户和HTTP服务器并等待中断信号。出错返回前通过defer关闭已启动的账户、
+// 行情推送和数据库，避免log.Fatal跳过清理
+func run(configFile string) error {
 	// 加载配置
-	cfg, err := config.Load(*configFile)
+	cfg, err := config.Load(configFile)
 	if err != nil {
-		log.Fatalf("加载配置失败: %v", err)
+		return fmt.Errorf("加载配置失败: %v", err)
 	}
 
 	// 初始化数据库
 	db, err := database.New("data/trades.db")
 	if err != nil {
-		log.Fatalf("初始化数据库失败: %v", err)
+		return fmt.Errorf("初始化数据库失败: %v", err)
 	}
 	defer db.Close()
 
 	// 初始化数据库表结构
 	if err := db.Initialize(); err != nil {
-		log.Fatalf("初始化数据库表结构失败: %v", err)
+		return fmt.Errorf("初始化数据库表结构失败: %v", err)
 	}
-
-	// 创建API客户端
-	apiClient := api.NewOKXClient(
-		cfg.API.Key,
-		cfg.API.Secret,
-		cfg.API.Passphrase,
-		cfg.API.Mode,
-	)
-
-	// 创建交易引擎
-	tradingConfig := trading.Config{
-		Mode:           cfg.Trading.Mode,
-		TradeType:      cfg.Trading.TradeType,
-		Leverage:       cfg.Trading.Leverage,
-		MarginMode:     cfg.Trading.MarginMode,
-		ReserveBalance: cfg.Trading.ReserveBalance,
-		Symbols:        cfg.Trading.Symbols,
-		LongPosition:   cfg.Trading.LongPosition,
-		ShortPosition:  cfg.Trading.ShortPosition,
-		Grid:           cfg.Trading.Grid,
-		RSI:            cfg.Trading.RSI,
-	}
-
-	engine, err := trading.NewEngine(apiClient, db, tradingConfig)
-	if err != nil {
-		log.Fatalf("创建交易引擎失败: %v", err)
+	accountNames := make([]string, 0, len(cfg.Accounts))
+	for _, account := range cfg.Accounts {
+		accountNames = append(accountNames, account.Name)
+	}
+	if err := db.MigrateLegacyAccount(accountNames); err != nil {
+		return fmt.Errorf("迁移升级前的交易记录失败: %v", err)
 	}
 
-	// 启动交易引擎
-	if err := engine.Start(); err != nil {
-		log.Fatalf("启动交易引擎失败: %v", err)
+	// 每个账户使用独立的API客户端、私有频道和交易引擎，行情推送按地址共享
+	feeds := make(map[string]*api.MarketFeed)
+	defer func() {
+		for _, feed := range feeds {
+			feed.Stop()
+		}
+	}()
+	var engines []*trading.Engine
+	for _, account := range cfg.Accounts {
+		engine, stop, err := startAccount(account, db, feeds)
+		defer stop() // 启动失败时关闭该账户已创建的部分
+		if err != nil {
+			return fmt.Errorf("启动账户 %s 失败: %v", account.Name, err)
+		}
+		engines = append(engines, engine)
 	}
-	defer engine.Stop()
 
 	// 创建并启动HTTP服务器
-	srv := server.New(&cfg.Server, db, engine)
+	srv := server.New(&cfg.Server, db, engines)
 	go func() {
-		if err := srv.Start(); err != nil {
+		if err := srv.Start(); err != nil && err != http.ErrServerClosed {
 			log.Printf("HTTP服务器停止: %v", err)
 		}
 	}()
@@ -93,4 +96,124 @@ func main() {
 	<-sigChan
 
 	log.Println("正在关闭服务...")
+
+	// 先关闭HTTP服务器并等待进行中的请求完成，再由defer停止引擎、推送和数据库
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("关闭HTTP服务器失败: %v", err)
+	}
+	return nil
+}
+
+// startAccount 创建账户的API客户端、推送和交易引擎并启动，返回的stop按相反顺序关闭
+func startAccount(account config.Account, db *database.Database, feeds map[string]*api.MarketFeed) (*trading.Engine, func(), error) {
+	log.Printf("初始化账户 %s", account.Name)
+	var stops []func()
+	stop := func() {
+		for i := len(stops) - 1; i >= 0; i-- {
+			stops[i]()
+		}
+	}
+
+	// 创建API客户端
+	apiClient := api.NewOKXClient(
+		account.API.Key,
+		account.API.Secret,
+		account.API.Passphrase,
+		account.API.Mode,
+	)
+	if account.API.BaseURL != "" {
+		apiClient.SetBaseURL(account.API.BaseURL)
+	}
+	proxy, err := api.ParseProxy(account.API.Proxy)
+	if err != nil {
+		return nil, stop, fmt.Errorf("代理配置错误: %v", err)
+	}
+	apiClient.SetProxy(proxy)
+
+	// 记录或回放REST请求，用于离线复现问题
+	replay := account.API.ReplayFile != ""
+	if replay {
+		if err := apiClient.EnableReplay(account.API.ReplayFile); err != nil {
+			return nil, stop, err
+		}
+	} else if account.API.RecordFile != "" {
+		recorder, err := apiClient.EnableRecording(account.API.RecordFile)
+		if err != nil {
+			return nil, stop, err
+		}
+		stops = append(stops, func() { recorder.Close() })
+	}
+
+	// 定期与服务器校时，签名时间戳按偏差修正
+	clockCtx, stopClock := context.WithCancel(context.Background())
+	stops = append(stops, stopClock)
+	apiClient.StartClockSync(clockCtx, account.API.ClockSyncInterval)
+
+	// 预加载产品信息，失败时下单前按需获取
+	loadCtx, cancelLoad := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := apiClient.LoadInstruments(loadCtx, "SWAP", "FUTURES", "SPOT"); err != nil {
+		log.Printf("警告: 预加载产品信息失败: %v", err)
+	}
+	cancelLoad()
+
+	// 创建交易引擎
+	tradingConfig := trading.Config{
+		Account:           account.Name,
+		Mode:              account.Trading.Mode,
+		TradeType:         account.Trading.TradeType,
+		Leverage:          account.Trading.Leverage,
+		MarginMode:        account.Trading.MarginMode,
+		PositionMode:      account.Trading.PositionMode,
+		ReserveBalance:    account.Trading.ReserveBalance,
+		Symbols:           account.Trading.Symbols,
+		MaxSlippage:       account.Trading.MaxSlippage,
+		ClampMaxSize:      account.Trading.ClampMaxSize,
+		NetOfFeePnL:       account.Trading.NetOfFeePnL,
+		BillsSyncInterval: account.Trading.BillsSyncInterval,
+		FundTransfer:      account.Trading.FundTransfer,
+		SpotTgtCcy:        account.Trading.SpotTgtCcy,
+		LongPosition:      account.Trading.LongPosition,
+		ShortPosition:     account.Trading.ShortPosition,
+		Grid:              account.Trading.Grid,
+		RSI:               account.Trading.RSI,
+	}
+
+	engine, err := trading.NewEngine(apiClient, db.ForAccount(account.Name), tradingConfig)
+	if err != nil {
+		return nil, stop, fmt.Errorf("创建交易引擎失败: %v", err)
+	}
+
+	// 回放时所有数据来自记录的REST响应，不连接WebSocket
+	if !replay {
+		// 创建WebSocket行情推送，替代REST轮询；相同地址和代理的账户共用一个连接
+		publicURL, privateURL, businessURL := api.WSURLsFor(account.API.WSURL, account.API.Mode)
+		feedKey := publicURL + "|" + businessURL + "|" + account.API.Proxy
+		feed, ok := feeds[feedKey]
+		if !ok {
+			feed = api.NewMarketFeed(publicURL, businessURL)
+			feed.SetProxy(proxy)
+			feed.Start()
+			feeds[feedKey] = feed
+		}
+		engine.SetMarketFeed(feed)
+
+		// 创建私有频道推送，实时同步订单、持仓和余额
+		accountFeed := api.NewAccountFeed(privateURL, apiClient)
+		accountFeed.SetProxy(proxy)
+		if err := accountFeed.Start(); err != nil {
+			return nil, stop, fmt.Errorf("启动私有频道推送失败: %v", err)
+		}
+		stops = append(stops, accountFeed.Stop)
+		engine.SetAccountFeed(accountFeed)
+	}
+
+	// 启动交易引擎
+	if err := engine.Start(); err != nil {
+		return nil, stop, fmt.Errorf("启动交易引擎失败: %v", err)
+	}
+	stops = append(stops, engine.Stop)
+
+	return engine, stop, nil
 }